@@ -0,0 +1,340 @@
+// Package api provides an http.Handler to drive a packet session remotely.
+//
+// The handler exposes the following JSON endpoints:
+//
+//	GET    /hosts                  list hosts
+//	GET    /macs                   list mac entries
+//	POST   /macs/{mac}/capture     capture the mac
+//	POST   /macs/{mac}/release     release the mac
+//	GET    /dns                    list dns entries (requires Config.DNS)
+//	GET    /leases                 list dhcp4 leases (requires Config.DHCP4)
+//	GET    /hunts                  list hunted addresses (requires Config.ARP)
+//	POST   /hunts                  start hunting {"mac": "...", "ip": "..."}
+//	DELETE /hunts/{mac}            stop hunting the mac
+//	GET    /events                 stream events as server-sent events or websocket messages
+//
+// Endpoints for handlers that are not configured return 404.
+//
+// POST and DELETE requests require the configured bearer token and a json content type so a
+// web page cannot forge them from the operator's browser; all POST and DELETE requests are
+// rejected if no token is configured. Event streams are rejected for browser origins that are
+// not in Config.Origins.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/fastlog"
+	"github.com/deeGraYve/packet/handlers/dhcp4_spoofer"
+)
+
+const module = "api"
+
+// Logger is the package logger
+var Logger = fastlog.New(module)
+
+// ARPHunter is the arp handler interface used by the api; arp_spoofer.Handler implements it.
+type ARPHunter interface {
+	HuntList() []packet.Addr
+	StartHunt(packet.Addr) (packet.HuntStage, error)
+	StopHunt(packet.Addr) (packet.HuntStage, error)
+}
+
+// DHCP4Table is the dhcp4 handler interface used by the api; dhcp4_spoofer.Handler implements it.
+type DHCP4Table interface {
+	Leases() []dhcp4_spoofer.Lease
+}
+
+// DNSTable is the dns handler interface used by the api; dns_naming.DNSHandler implements it.
+type DNSTable interface {
+	DNSEntries() []packet.DNSEntry
+}
+
+// Config holds the session and the optional handlers exposed by the api.
+type Config struct {
+	Session *packet.Session
+	ARP     ARPHunter  // optional: enables /hunts
+	DHCP4   DHCP4Table // optional: enables /leases
+	DNS     DNSTable   // optional: enables /dns
+	Token   string     // bearer token required for POST and DELETE requests
+	Origins []string   // browser origins allowed to stream events, i.e. http://localhost:8080
+}
+
+// Handler is the api http.Handler.
+type Handler struct {
+	session *packet.Session
+	arp     ARPHunter
+	dhcp4   DHCP4Table
+	dns     DNSTable
+	token   string
+	origins []string
+	mux     *http.ServeMux
+}
+
+// New returns an http.Handler for the session. Mount it under a prefix with http.StripPrefix.
+func New(config Config) (*Handler, error) {
+	if config.Session == nil {
+		return nil, fmt.Errorf("nil session: %w", packet.ErrInvalidParam)
+	}
+	h := &Handler{session: config.Session, arp: config.ARP, dhcp4: config.DHCP4, dns: config.DNS, token: config.Token,
+		origins: config.Origins, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/hosts", h.handleHosts)
+	h.mux.HandleFunc("/macs", h.handleMACs)
+	h.mux.HandleFunc("/macs/", h.handleMAC)
+	h.mux.HandleFunc("/dns", h.handleDNS)
+	h.mux.HandleFunc("/leases", h.handleLeases)
+	h.mux.HandleFunc("/hunts", h.handleHunts)
+	h.mux.HandleFunc("/hunts/", h.handleHunt)
+	h.mux.HandleFunc("/events", h.handleEvents)
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+		if status, err := h.authorize(r); err != nil {
+			writeError(w, status, err)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorize checks the bearer token and content type of a request that changes the session.
+// It returns the http status code and an error if the request is not authorized.
+func (h *Handler) authorize(r *http.Request) (int, error) {
+	if h.token == "" {
+		return http.StatusForbidden, fmt.Errorf("api token not configured")
+	}
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+		return http.StatusUnauthorized, fmt.Errorf("invalid bearer token")
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/json")
+	}
+	return http.StatusOK, nil
+}
+
+// allowOrigin returns true if the request has no Origin header or the origin is in the allowed list.
+// Browsers always send the Origin header on cross-origin and websocket requests.
+func (h *Handler) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, v := range h.origins {
+		if strings.EqualFold(v, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) handleHosts(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	hosts := h.session.GetHosts()
+	list := make([]Host, 0, len(hosts))
+	for _, v := range hosts {
+		list = append(list, newHost(v))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) handleMACs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	inventory := h.session.Inventory()
+	list := make([]MACEntry, 0, len(inventory))
+	for _, v := range inventory {
+		list = append(list, newMACEntry(v))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleMAC handles POST /macs/{mac}/capture and POST /macs/{mac}/release
+func (h *Handler) handleMAC(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/macs/"), "/")
+	if len(path) != 2 || (path[1] != "capture" && path[1] != "release") {
+		writeError(w, http.StatusNotFound, packet.ErrNotFound)
+		return
+	}
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	mac, err := net.ParseMAC(path[0])
+	if err != nil || len(mac) != packet.EthAddrLen {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("mac=%s: %w", path[0], packet.ErrInvalidMAC))
+		return
+	}
+	if path[1] == "capture" {
+		err = h.session.Capture(mac)
+	} else {
+		err = h.session.Release(mac)
+	}
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleDNS(w http.ResponseWriter, r *http.Request) {
+	if h.dns == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	entries := h.dns.DNSEntries()
+	list := make([]DNSEntry, 0, len(entries))
+	for _, v := range entries {
+		list = append(list, newDNSEntry(v))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) handleLeases(w http.ResponseWriter, r *http.Request) {
+	if h.dhcp4 == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	leases := h.dhcp4.Leases()
+	list := make([]Lease, 0, len(leases))
+	for _, v := range leases {
+		list = append(list, newLease(v))
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleHunts handles GET /hunts and POST /hunts
+func (h *Handler) handleHunts(w http.ResponseWriter, r *http.Request) {
+	if h.arp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		hunts := h.arp.HuntList()
+		list := make([]Addr, 0, len(hunts))
+		for _, v := range hunts {
+			list = append(list, newAddr(v))
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		var req Addr
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", packet.ErrInvalidParam))
+			return
+		}
+		addr, err := req.addr()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if _, err := h.arp.StartHunt(addr); err != nil {
+			writeError(w, statusCode(err), err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newAddr(addr))
+
+	default:
+		allowMethod(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleHunt handles DELETE /hunts/{mac}
+func (h *Handler) handleHunt(w http.ResponseWriter, r *http.Request) {
+	if h.arp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethod(w, r, http.MethodDelete) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/hunts/")
+	mac, err := net.ParseMAC(name)
+	if err != nil || len(mac) != packet.EthAddrLen {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("mac=%s: %w", name, packet.ErrInvalidMAC))
+		return
+	}
+	for _, v := range h.arp.HuntList() {
+		if v.MAC.String() == mac.String() {
+			if _, err := h.arp.StopHunt(v); err != nil {
+				writeError(w, statusCode(err), err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("mac=%s: %w", mac, packet.ErrNotFound))
+}
+
+// allowMethod returns true if the request method is one of methods; otherwise it writes a 405 response.
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// statusCode maps session errors to http status codes.
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, packet.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, packet.ErrIsRouter), errors.Is(err, packet.ErrInvalidIP), errors.Is(err, packet.ErrInvalidMAC),
+		errors.Is(err, packet.ErrInvalidParam):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		Logger.Msg("failed to write response").Error(err).Write()
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{Error: err.Error()})
+}
+
+// parseAddr returns the packet.Addr for mac and ip strings.
+func parseAddr(mac string, ip string) (packet.Addr, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != packet.EthAddrLen {
+		return packet.Addr{}, fmt.Errorf("mac=%s: %w", mac, packet.ErrInvalidMAC)
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return packet.Addr{}, fmt.Errorf("ip=%s: %w", ip, packet.ErrInvalidIP)
+	}
+	return packet.Addr{MAC: hw, IP: addr}, nil
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/handlers/arp_spoofer"
+)
+
+var (
+	hostMAC   = net.HardwareAddr{0x00, 0x55, 0x55, 0x55, 0x55, 0x55}
+	hostIP4   = netip.MustParseAddr("192.168.0.129")
+	routerMAC = net.HardwareAddr{0x00, 0x66, 0x66, 0x66, 0x66, 0x66}
+	routerIP4 = netip.MustParseAddr("192.168.0.11")
+	mac1      = net.HardwareAddr{0x00, 0x02, 0x03, 0x04, 0x05, 0x01}
+	ip1       = netip.MustParseAddr("192.168.0.1")
+)
+
+const testToken = "secret"
+
+func setupTestServer(t *testing.T) (*packet.Session, *httptest.Server) {
+	conn, outConn := packet.TestNewBufferedConn()
+	go packet.TestReadAndDiscardLoop(outConn)
+	nicInfo := &packet.NICInfo{
+		HomeLAN4:    netip.PrefixFrom(hostIP4, 24),
+		HostAddr4:   packet.Addr{MAC: hostMAC, IP: hostIP4},
+		RouterAddr4: packet.Addr{MAC: routerMAC, IP: routerIP4},
+	}
+	session, err := packet.Config{Conn: conn, NICInfo: nicInfo}.NewSession("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arp, err := arp_spoofer.New(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := New(Config{Session: session, ARP: arp, Token: testToken, Origins: []string{"http://localhost:8080"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(h)
+	t.Cleanup(func() {
+		server.Close()
+		arp.Close()
+		session.Close()
+	})
+
+	// announce mac1 so the session has a host
+	ether := packet.EncodeEther(make([]byte, packet.EthMaxSize), syscall.ETH_P_ARP, mac1, packet.EthernetBroadcast)
+	ether, _ = ether.AppendPayload(packet.EncodeARP(make([]byte, packet.ARPLen), packet.ARPOperationRequest,
+		packet.Addr{MAC: mac1, IP: ip1}, packet.Addr{MAC: packet.EthernetZero, IP: ip1}))
+	if _, err := session.Parse(ether); err != nil {
+		t.Fatal(err)
+	}
+	return session, server
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("expected error for nil session")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	session, server := setupTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header map[string]string // replaces the default json content type and bearer token
+		status int
+		want   string // substring of the response body
+	}{
+		{name: "capture no token", method: http.MethodPost, path: "/macs/00:02:03:04:05:01/capture",
+			header: map[string]string{"Content-Type": "application/json"}, status: http.StatusUnauthorized},
+		{name: "capture invalid token", method: http.MethodPost, path: "/macs/00:02:03:04:05:01/capture",
+			header: map[string]string{"Content-Type": "application/json", "Authorization": "Bearer invalid"}, status: http.StatusUnauthorized},
+		{name: "capture text plain", method: http.MethodPost, path: "/macs/00:02:03:04:05:01/capture",
+			header: map[string]string{"Content-Type": "text/plain", "Authorization": "Bearer " + testToken}, status: http.StatusUnsupportedMediaType},
+		{name: "stop hunt no token", method: http.MethodDelete, path: "/hunts/00:02:03:04:05:01", status: http.StatusUnauthorized,
+			header: map[string]string{"Content-Type": "application/json"}},
+		{name: "hosts", method: http.MethodGet, path: "/hosts", status: http.StatusOK, want: `"mac":"00:02:03:04:05:01","ip":"192.168.0.1","online":true`},
+		{name: "hosts method", method: http.MethodPost, path: "/hosts", status: http.StatusMethodNotAllowed},
+		{name: "macs", method: http.MethodGet, path: "/macs", status: http.StatusOK, want: `"mac":"00:02:03:04:05:01","captured":false`},
+		{name: "capture", method: http.MethodPost, path: "/macs/00:02:03:04:05:01/capture", status: http.StatusNoContent},
+		{name: "captured", method: http.MethodGet, path: "/hosts", status: http.StatusOK, want: `"captured":true`},
+		{name: "release", method: http.MethodPost, path: "/macs/00:02:03:04:05:01/release", status: http.StatusNoContent},
+		{name: "capture router", method: http.MethodPost, path: "/macs/00:66:66:66:66:66/capture", status: http.StatusBadRequest, want: "host is router"},
+		{name: "invalid mac", method: http.MethodPost, path: "/macs/invalid/capture", status: http.StatusBadRequest},
+		{name: "invalid action", method: http.MethodPost, path: "/macs/00:02:03:04:05:01/block", status: http.StatusNotFound},
+		{name: "dns not configured", method: http.MethodGet, path: "/dns", status: http.StatusNotFound},
+		{name: "leases not configured", method: http.MethodGet, path: "/leases", status: http.StatusNotFound},
+		{name: "start hunt", method: http.MethodPost, path: "/hunts", body: `{"mac":"00:02:03:04:05:01","ip":"192.168.0.1"}`, status: http.StatusCreated},
+		{name: "hunts", method: http.MethodGet, path: "/hunts", status: http.StatusOK, want: `[{"mac":"00:02:03:04:05:01","ip":"192.168.0.1"}]`},
+		{name: "start hunt invalid", method: http.MethodPost, path: "/hunts", body: `{"mac":"00:02:03:04:05:01"}`, status: http.StatusBadRequest},
+		{name: "stop hunt", method: http.MethodDelete, path: "/hunts/00:02:03:04:05:01", status: http.StatusNoContent},
+		{name: "stop hunt again", method: http.MethodDelete, path: "/hunts/00:02:03:04:05:01", status: http.StatusNotFound},
+		{name: "hunts empty", method: http.MethodGet, path: "/hunts", status: http.StatusOK, want: `[]`},
+		{name: "events invalid type", method: http.MethodGet, path: "/events?type=invalid", status: http.StatusBadRequest},
+		{name: "events invalid origin", method: http.MethodGet, path: "/events", header: map[string]string{"Origin": "http://example.com"},
+			status: http.StatusForbidden},
+		{name: "websocket invalid origin", method: http.MethodGet, path: "/events", status: http.StatusForbidden,
+			header: map[string]string{"Origin": "http://example.com", "Upgrade": "websocket", "Connection": "Upgrade",
+				"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			if tt.header != nil {
+				req.Header = http.Header{}
+				for k, v := range tt.header {
+					req.Header.Set(k, v)
+				}
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body strings.Builder
+			bufio.NewReader(resp.Body).WriteTo(&body)
+			if resp.StatusCode != tt.status {
+				t.Errorf("invalid status want=%d got=%d body=%s", tt.status, resp.StatusCode, body.String())
+			}
+			if !strings.Contains(body.String(), tt.want) {
+				t.Errorf("invalid body want=%s got=%s", tt.want, body.String())
+			}
+		})
+	}
+	if session.IsCaptured(mac1) {
+		t.Error("mac not released")
+	}
+}
+
+func TestHandler_noToken(t *testing.T) {
+	session, _ := setupTestServer(t)
+	h, _ := New(Config{Session: session})
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/macs/00:02:03:04:05:01/capture", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer ")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || session.IsCaptured(mac1) {
+		t.Errorf("invalid status want=%d got=%d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestHandler_events(t *testing.T) {
+	session, server := setupTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?type=captured,released", nil)
+	req.Header.Set("Origin", "http://localhost:8080")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("invalid content type %s", ct)
+	}
+
+	session.Capture(mac1)
+	session.Release(mac1)
+
+	reader := bufio.NewReader(resp.Body)
+	for _, want := range []string{"captured", "released"} {
+		var event Event
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal("failed to read event", err)
+			}
+			if strings.HasPrefix(line, "data: ") {
+				if err := json.Unmarshal([]byte(line[len("data: "):]), &event); err != nil {
+					t.Fatal("invalid event", err)
+				}
+				break
+			}
+		}
+		if event.Type != want || event.MAC != mac1.String() {
+			t.Errorf("invalid event want=%s got=%+v", want, event)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/deeGraYve/packet"
+	"golang.org/x/net/websocket"
+)
+
+// handleEvents streams session events to the client until the client disconnects or the
+// session is closed. Clients that send a websocket upgrade request receive one json message
+// per event; all other clients receive server-sent events.
+//
+// The optional query parameter "type" is a comma separated list of event types to receive,
+// for example /events?type=host_online,host_offline.
+//
+// Requests from a browser origin that is not allowed are rejected.
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	filter, err := parseEventFilter(r.URL.Query().Get("type"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		server := websocket.Server{
+			Handshake: func(config *websocket.Config, r *http.Request) error {
+				if !h.allowOrigin(r) {
+					return fmt.Errorf("origin=%s not allowed", r.Header.Get("Origin"))
+				}
+				return nil
+			},
+			Handler: func(ws *websocket.Conn) { h.streamWebSocket(ws, filter) },
+		}
+		server.ServeHTTP(w, r)
+		return
+	}
+	if !h.allowOrigin(r) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("origin=%s not allowed", r.Header.Get("Origin")))
+		return
+	}
+	h.streamSSE(w, r, filter)
+}
+
+func (h *Handler) streamSSE(w http.ResponseWriter, r *http.Request, filter packet.EventFilter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+	sub := h.session.Subscribe(0, filter)
+	defer h.session.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(newEvent(e))
+			if err != nil {
+				Logger.Msg("failed to marshal event").Error(err).Write()
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *Handler) streamWebSocket(ws *websocket.Conn, filter packet.EventFilter) {
+	sub := h.session.Subscribe(0, filter)
+	defer h.session.Unsubscribe(sub)
+
+	// the client does not send messages; a read returns when the client disconnects
+	done := make(chan struct{})
+	go func() {
+		var msg []byte
+		for websocket.Message.Receive(ws, &msg) == nil {
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(ws, newEvent(e)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseEventFilter returns a filter for the comma separated list of event type names
+// or nil if the list is empty.
+func parseEventFilter(names string) (packet.EventFilter, error) {
+	if names == "" {
+		return nil, nil
+	}
+	var types []packet.EventType
+	for _, name := range strings.Split(names, ",") {
+		t, found := eventType(strings.TrimSpace(name))
+		if !found {
+			return nil, fmt.Errorf("event type=%s: %w", name, packet.ErrInvalidParam)
+		}
+		types = append(types, t)
+	}
+	return packet.EventTypes(types...), nil
+}
+
+// eventType returns the event type for name.
+func eventType(name string) (packet.EventType, bool) {
+	for t := packet.EventType(1); !strings.HasPrefix(t.String(), "event("); t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/handlers/dhcp4_spoofer"
+)
+
+// Addr is the json representation of a packet.Addr.
+type Addr struct {
+	MAC string     `json:"mac"`
+	IP  netip.Addr `json:"ip"`
+}
+
+func newAddr(addr packet.Addr) Addr {
+	return Addr{MAC: addr.MAC.String(), IP: addr.IP}
+}
+
+func (a Addr) addr() (packet.Addr, error) {
+	return parseAddr(a.MAC, a.IP.String())
+}
+
+// Host is the json representation of a packet.Host.
+type Host struct {
+	MAC          string            `json:"mac"`
+	IP           netip.Addr        `json:"ip"`
+	Online       bool              `json:"online"`
+	Captured     bool              `json:"captured"`
+	HuntStage    string            `json:"huntStage"`
+	Manufacturer string            `json:"manufacturer,omitempty"`
+	Names        map[string]string `json:"names,omitempty"`
+	LastSeen     time.Time         `json:"lastSeen"`
+}
+
+func newHost(host *packet.Host) Host {
+	host.MACEntry.Row.RLock()
+	defer host.MACEntry.Row.RUnlock()
+	return Host{
+		MAC: host.Addr.MAC.String(), IP: host.Addr.IP, Online: host.Online, Captured: host.MACEntry.Captured,
+		HuntStage: host.HuntStage.String(), Manufacturer: host.Manufacturer, LastSeen: host.LastSeen,
+		Names: names(host.DHCP4Name, host.MDNSName, host.SSDPName, host.LLMNRName, host.NBNSName, host.WSDName, host.DeviceName),
+	}
+}
+
+// MACEntry is the json representation of a packet.MACEntry.
+type MACEntry struct {
+	MAC          string            `json:"mac"`
+	Captured     bool              `json:"captured"`
+	IP4          netip.Addr        `json:"ip4"`
+	IP6GUA       netip.Addr        `json:"ip6gua"`
+	IP6LLA       netip.Addr        `json:"ip6lla"`
+	Manufacturer string            `json:"manufacturer,omitempty"`
+	Names        map[string]string `json:"names,omitempty"`
+	FirstSeen    time.Time         `json:"firstSeen"`
+	LastSeen     time.Time         `json:"lastSeen"`
+}
+
+func newMACEntry(e packet.InventoryEntry) MACEntry {
+	return MACEntry{
+		MAC: e.MAC.String(), Captured: e.Captured, IP4: e.IP4, IP6GUA: e.IP6GUA, IP6LLA: e.IP6LLA,
+		Manufacturer: e.Manufacturer, FirstSeen: e.FirstSeen, LastSeen: e.LastSeen,
+		Names: names(e.DHCP4Name, e.MDNSName, e.SSDPName, e.LLMNRName, e.NBNSName, e.WSDName, e.DeviceName),
+	}
+}
+
+// names returns a map of name type to name for the non empty entries.
+func names(list ...packet.NameEntry) map[string]string {
+	var m map[string]string
+	for _, v := range list {
+		if v.Name == "" {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[v.Type] = v.Name
+	}
+	return m
+}
+
+// DNSEntry is the json representation of a packet.DNSEntry.
+type DNSEntry struct {
+	Name  string       `json:"name"`
+	IP4   []netip.Addr `json:"ip4,omitempty"`
+	IP6   []netip.Addr `json:"ip6,omitempty"`
+	CName []string     `json:"cname,omitempty"`
+	PTR   []string     `json:"ptr,omitempty"`
+}
+
+func newDNSEntry(e packet.DNSEntry) DNSEntry {
+	entry := DNSEntry{Name: e.Name}
+	for k := range e.IP4Records {
+		entry.IP4 = append(entry.IP4, k)
+	}
+	for k := range e.IP6Records {
+		entry.IP6 = append(entry.IP6, k)
+	}
+	for _, v := range e.CNameRecords {
+		entry.CName = append(entry.CName, v.CName)
+	}
+	for k := range e.PTRRecords {
+		entry.PTR = append(entry.PTR, k)
+	}
+	return entry
+}
+
+// Lease is the json representation of a dhcp4_spoofer.Lease.
+type Lease struct {
+	ClientID   string     `json:"clientID"`
+	State      string     `json:"state"`
+	MAC        string     `json:"mac"`
+	IP         netip.Addr `json:"ip"`
+	Name       string     `json:"name,omitempty"`
+	DHCPExpiry time.Time  `json:"dhcpExpiry"`
+}
+
+func newLease(l dhcp4_spoofer.Lease) Lease {
+	return Lease{
+		ClientID: hex.EncodeToString(l.ClientID), State: l.State.String(), MAC: l.Addr.MAC.String(), IP: l.Addr.IP,
+		Name: l.Name, DHCPExpiry: l.DHCPExpiry,
+	}
+}
+
+// Event is the json representation of a packet.Event.
+type Event struct {
+	Type string     `json:"type"`
+	Time time.Time  `json:"time"`
+	MAC  string     `json:"mac,omitempty"`
+	IP   netip.Addr `json:"ip"`
+	Data string     `json:"data,omitempty"` // event detail in log format
+}
+
+func newEvent(e packet.Event) Event {
+	event := Event{Type: e.Type.String(), Time: e.Time, IP: e.Addr.IP}
+	if e.Addr.MAC != nil {
+		event.MAC = e.Addr.MAC.String()
+	}
+	if e.Data != nil {
+		// skip the fixed width module name at the start of the line
+		event.Data = strings.TrimSpace(Logger.Msg("").Struct(e.Data).ToString()[7:])
+	}
+	return event
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/api"
+	"github.com/deeGraYve/packet/fastlog"
+	arp "github.com/deeGraYve/packet/handlers/arp_spoofer"
+	dhcp4 "github.com/deeGraYve/packet/handlers/dhcp4_spoofer"
+	dns "github.com/deeGraYve/packet/handlers/dns_naming"
+	icmp "github.com/deeGraYve/packet/handlers/icmp_spoofer"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config is the daemon configuration file.
+type Config struct {
+	NIC     string            `yaml:"nic"`
+	User    string            `yaml:"user,omitempty"` // drop privileges to user after opening the raw socket; empty to keep running as root
+	Session SessionConfig     `yaml:"session"`
+	DHCP4   DHCP4Config       `yaml:"dhcp4"`
+	ARP     ARPConfig         `yaml:"arp"`
+	RADVS   RADVSConfig       `yaml:"radvs"`
+	DNS     DNSConfig         `yaml:"dns"`
+	API     APIConfig         `yaml:"api"`
+	Log     map[string]string `yaml:"log,omitempty"` // module to level: error, info or debug
+}
+
+// SessionConfig holds the packet.Config settings.
+type SessionConfig struct {
+	ProbeDeadline     time.Duration `yaml:"probeDeadline,omitempty"`
+	OfflineDeadline   time.Duration `yaml:"offlineDeadline,omitempty"`
+	PurgeDeadline     time.Duration `yaml:"purgeDeadline,omitempty"`
+	TrustedDHCP4      []netip.Addr  `yaml:"trustedDHCP4,omitempty"`
+	Inventory         string        `yaml:"inventory,omitempty"` // inventory yaml file; empty to disable
+	InventoryDeadline time.Duration `yaml:"inventoryDeadline,omitempty"`
+	StoreInterval     time.Duration `yaml:"storeInterval,omitempty"`
+}
+
+// DHCP4Config holds the dhcp4_spoofer.Config settings.
+type DHCP4Config struct {
+	Enable    bool         `yaml:"enable"`
+	Mode      string       `yaml:"mode,omitempty"`      // primary, secondary or nice; default to secondary
+	Netfilter netip.Prefix `yaml:"netfilter,omitempty"` // netfilter subnet; default to the host ip and home lan prefix length
+	DNS       netip.Addr   `yaml:"dns,omitempty"`       // dns server offered to clients; default to the router
+	LeaseFile string       `yaml:"leaseFile,omitempty"`
+}
+
+// ARPConfig holds the arp_spoofer.Config settings.
+type ARPConfig struct {
+	Enable        bool          `yaml:"enable"`
+	ProbeInterval time.Duration `yaml:"probeInterval,omitempty"`
+}
+
+// RADVSConfig holds the router advertisement server settings.
+type RADVSConfig struct {
+	Enable   bool           `yaml:"enable"`
+	Managed  bool           `yaml:"managed,omitempty"`
+	Other    bool           `yaml:"other,omitempty"`
+	Prefixes []netip.Prefix `yaml:"prefixes,omitempty"`
+	RDNSS    []netip.Addr   `yaml:"rdnss,omitempty"`
+	Lifetime time.Duration  `yaml:"lifetime,omitempty"` // prefix and rdnss lifetime; default to DefaultRALifetime
+}
+
+// DNSConfig holds the dns_naming settings.
+type DNSConfig struct {
+	Enable bool `yaml:"enable"`
+}
+
+// APIConfig holds the http api settings.
+type APIConfig struct {
+	Listen  string   `yaml:"listen,omitempty"`  // listen address; empty to disable
+	Token   string   `yaml:"token,omitempty"`   // bearer token required for POST and DELETE requests
+	Origins []string `yaml:"origins,omitempty"` // browser origins allowed to stream events
+}
+
+// DefaultRALifetime is the default lifetime of advertised prefixes and dns servers.
+const DefaultRALifetime = time.Hour * 4
+
+// loggers maps the log config module names to the package loggers.
+var loggers = map[string][]*fastlog.Logger{
+	"packet": {packet.Logger},
+	"arp":    {arp.Logger},
+	"dhcp4":  {dhcp4.Logger},
+	"icmp":   {icmp.Logger4, icmp.Logger6},
+	"dns":    {dns.Logger},
+	"api":    {api.Logger},
+	"daemon": {Logger},
+}
+
+var dhcp4Modes = map[string]dhcp4.Mode{
+	"primary":   dhcp4.ModePrimaryServer,
+	"secondary": dhcp4.ModeSecondaryServer,
+	"nice":      dhcp4.ModeSecondaryServerNice,
+}
+
+// LoadConfig reads and validates the configuration file.
+func LoadConfig(filename string) (config Config, err error) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := yaml.UnmarshalStrict(source, &config); err != nil {
+		return Config{}, fmt.Errorf("invalid config file=%s: %w", filename, err)
+	}
+	if err := config.validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config file=%s: %w", filename, err)
+	}
+	return config, nil
+}
+
+func (c Config) validate() error {
+	if c.NIC == "" {
+		return fmt.Errorf("missing nic: %w", packet.ErrInvalidParam)
+	}
+	if _, found := dhcp4Modes[c.DHCP4.Mode]; c.DHCP4.Mode != "" && !found {
+		return fmt.Errorf("invalid dhcp4 mode=%s: %w", c.DHCP4.Mode, packet.ErrInvalidParam)
+	}
+	for module, level := range c.Log {
+		if _, found := loggers[module]; !found {
+			return fmt.Errorf("invalid log module=%s: %w", module, packet.ErrInvalidParam)
+		}
+		if fastlog.Str2LogLevel(level) == fastlog.LevelInvalid {
+			return fmt.Errorf("invalid log level=%s for module=%s: %w", level, module, packet.ErrInvalidParam)
+		}
+	}
+	for _, p := range c.RADVS.Prefixes {
+		if !p.Addr().Is6() {
+			return fmt.Errorf("invalid radvs prefix=%s: %w", p, packet.ErrInvalidIP)
+		}
+	}
+	return nil
+}
+
+// setLogLevels applies the log levels; modules not in the config are unchanged.
+func (c Config) setLogLevels() {
+	for module, level := range c.Log {
+		for _, l := range loggers[module] {
+			l.SetLevel(fastlog.Str2LogLevel(level))
+		}
+	}
+}
+
+func (c Config) sessionConfig() packet.Config {
+	config := packet.Config{
+		ProbeDeadline: c.Session.ProbeDeadline, OfflineDeadline: c.Session.OfflineDeadline, PurgeDeadline: c.Session.PurgeDeadline,
+		InventoryDeadline: c.Session.InventoryDeadline, TrustedDHCP4: c.Session.TrustedDHCP4, StoreInterval: c.Session.StoreInterval,
+	}
+	if c.Session.Inventory != "" {
+		config.Store = packet.NewYAMLStore(c.Session.Inventory)
+	}
+	return config
+}
+
+// handlerConfig returns the dhcp4 handler config with defaults from the session nic.
+func (c DHCP4Config) handlerConfig(session *packet.Session) dhcp4.Config {
+	config := dhcp4.Config{Mode: dhcp4Modes[c.Mode], NetfilterIP: c.Netfilter, DNSServer: c.DNS, LeaseFilename: c.LeaseFile}
+	if c.Mode == "" {
+		config.Mode = dhcp4.ModeSecondaryServer
+	}
+	if !config.NetfilterIP.IsValid() {
+		config.NetfilterIP = netip.PrefixFrom(session.NICInfo().HostAddr4.IP, session.NICInfo().HomeLAN4.Bits())
+	}
+	if config.LeaseFilename == "" {
+		config.LeaseFilename = dhcp4.LeaseFilename
+	}
+	return config
+}
+
+// options returns the prefix information and rdnss options to advertise.
+func (c RADVSConfig) options() ([]packet.PrefixInformation, *packet.RecursiveDNSServer) {
+	lifetime := c.Lifetime
+	if lifetime <= 0 {
+		lifetime = DefaultRALifetime
+	}
+	prefixes := make([]packet.PrefixInformation, 0, len(c.Prefixes))
+	for _, p := range c.Prefixes {
+		prefixes = append(prefixes, packet.PrefixInformation{
+			PrefixLength: uint8(p.Bits()), OnLink: true, AutonomousAddressConfiguration: true,
+			ValidLifetime: lifetime, PreferredLifetime: lifetime, Prefix: net.IP(p.Masked().Addr().AsSlice()),
+		})
+	}
+	if len(c.RDNSS) == 0 {
+		return prefixes, nil
+	}
+	rdnss := &packet.RecursiveDNSServer{Lifetime: lifetime}
+	for _, ip := range c.RDNSS {
+		rdnss.Servers = append(rdnss.Servers, net.IP(ip.AsSlice()))
+	}
+	return prefixes, rdnss
+}
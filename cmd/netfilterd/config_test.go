@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+
+	dhcp4 "github.com/deeGraYve/packet/handlers/dhcp4_spoofer"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("netfilterd.yaml")
+	if err != nil {
+		t.Fatal("cannot load sample config", err)
+	}
+	if config.NIC != "eth0" || config.Session.OfflineDeadline != time.Minute*5 || config.ARP.ProbeInterval != time.Minute*5 {
+		t.Errorf("invalid config %+v", config)
+	}
+	if config.DHCP4.Netfilter != netip.MustParsePrefix("192.168.0.129/25") || config.Session.TrustedDHCP4[0] != netip.MustParseAddr("192.168.0.1") {
+		t.Errorf("invalid dhcp4 config %+v", config.DHCP4)
+	}
+	if config.sessionConfig().Store == nil {
+		t.Error("missing inventory store")
+	}
+
+	prefixes, rdnss := config.RADVS.options()
+	if len(prefixes) != 1 || prefixes[0].PrefixLength != 64 || prefixes[0].Prefix.String() != "2001:db8:1::" ||
+		prefixes[0].ValidLifetime != time.Hour*4 || rdnss == nil || rdnss.Servers[0].String() != "2001:db8:1::1" {
+		t.Errorf("invalid radvs options %+v %+v", prefixes, rdnss)
+	}
+}
+
+func TestConfig_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "minimal", config: "nic: eth0\n"},
+		{name: "missing nic", config: "dns:\n  enable: true\n", wantErr: true},
+		{name: "unknown field", config: "nic: eth0\ninvalid: true\n", wantErr: true},
+		{name: "invalid mode", config: "nic: eth0\ndhcp4:\n  mode: tertiary\n", wantErr: true},
+		{name: "invalid module", config: "nic: eth0\nlog:\n  invalid: info\n", wantErr: true},
+		{name: "invalid level", config: "nic: eth0\nlog:\n  packet: verbose\n", wantErr: true},
+		{name: "invalid radvs prefix", config: "nic: eth0\nradvs:\n  prefixes: [192.168.0.0/24]\n", wantErr: true},
+		{name: "invalid duration", config: "nic: eth0\narp:\n  probeInterval: often\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := t.TempDir() + "/config.yaml"
+			os.WriteFile(filename, []byte(tt.config), 0o644)
+			if _, err := LoadConfig(filename); (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDHCP4Config_mode(t *testing.T) {
+	for mode, want := range map[string]dhcp4.Mode{"": dhcp4.ModeSecondaryServer, "primary": dhcp4.ModePrimaryServer, "nice": dhcp4.ModeSecondaryServerNice} {
+		config := DHCP4Config{Mode: mode, Netfilter: netip.MustParsePrefix("192.168.0.129/25"), LeaseFile: "leases.yaml"}
+		if got := config.handlerConfig(nil).Mode; got != want {
+			t.Errorf("invalid mode=%s want=%v got=%v", mode, want, got)
+		}
+	}
+}
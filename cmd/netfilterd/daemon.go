@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/api"
+	arp "github.com/deeGraYve/packet/handlers/arp_spoofer"
+	dhcp4 "github.com/deeGraYve/packet/handlers/dhcp4_spoofer"
+	dns "github.com/deeGraYve/packet/handlers/dns_naming"
+	icmp "github.com/deeGraYve/packet/handlers/icmp_spoofer"
+)
+
+// daemon owns the session and the handlers. Handlers are registered with the session and
+// replaced on reload; the hunt loop and the api hold a read lock while using them.
+type daemon struct {
+	session *packet.Session
+	config  Config
+	events  *packet.Subscription
+	server  *http.Server
+	mutex   sync.RWMutex
+	arp     *arp.Handler
+	dhcp4   *dhcp4.Handler
+	icmp6   *icmp.Handler6
+	dns     *dns.DNSHandler
+	radvs   *icmp.RADVS
+	api     http.Handler
+	wg      sync.WaitGroup
+}
+
+// newDaemon opens the nic and starts all configured handlers. The session is closed when ctx is cancelled.
+func newDaemon(ctx context.Context, config Config) (d *daemon, err error) {
+	d = &daemon{config: config}
+	config.setLogLevels()
+	if d.session, err = config.sessionConfig().NewSessionContext(ctx, config.NIC); err != nil {
+		return nil, err
+	}
+	// Must enable IPv4 forwarding to be able to forward IP packets sent to this host as the default gw.
+	if config.ARP.Enable || config.DHCP4.Enable {
+		if err := d.session.EnableIP4Forwarding(); err != nil {
+			d.session.Close()
+			return nil, err
+		}
+	}
+	if err := d.startHandlers(); err != nil {
+		d.session.Close()
+		return nil, err
+	}
+	d.events = d.session.Subscribe(0, packet.EventTypes(packet.EventHostOnline, packet.EventHostOffline, packet.EventCaptured, packet.EventReleased))
+	return d, nil
+}
+
+// startHandlers creates the handlers in d.config. The caller must hold the lock or be the only user of d.
+func (d *daemon) startHandlers() (err error) {
+	defer func() {
+		if err != nil {
+			d.stopHandlers()
+		}
+	}()
+	if d.icmp6, err = icmp.New6(d.session); err != nil {
+		return err
+	}
+	d.session.Register(d.icmp6, packet.HandlerFilter{PayloadIDs: []packet.PayloadID{packet.PayloadICMP6}})
+	if d.config.ARP.Enable {
+		if d.arp, err = (arp.Config{ProbeInterval: d.config.ARP.ProbeInterval}).New(d.session); err != nil {
+			return err
+		}
+		d.session.Register(d.arp, packet.HandlerFilter{PayloadIDs: []packet.PayloadID{packet.PayloadARP}})
+	}
+	if d.config.DHCP4.Enable {
+		if d.dhcp4, err = d.config.DHCP4.handlerConfig(d.session).New(d.session); err != nil {
+			return err
+		}
+		d.session.Register(d.dhcp4, packet.HandlerFilter{PayloadIDs: []packet.PayloadID{packet.PayloadDHCP4}})
+	}
+	if d.config.DNS.Enable {
+		if d.dns, err = dns.New(d.session); err != nil {
+			return err
+		}
+		d.session.Register(d.dns, packet.HandlerFilter{PayloadIDs: []packet.PayloadID{packet.PayloadDNS, packet.PayloadMDNS,
+			packet.PayloadLLMNR, packet.PayloadNBNS, packet.PayloadSSDP, packet.PayloadWSDP}})
+	}
+	if d.config.RADVS.Enable {
+		prefixes, rdnss := d.config.RADVS.options()
+		if d.radvs, err = d.icmp6.StartRADVS(d.config.RADVS.Managed, d.config.RADVS.Other, prefixes, rdnss); err != nil {
+			return err
+		}
+	}
+
+	// the api interfaces must be nil when the handler is disabled
+	apiConfig := api.Config{Session: d.session, Token: d.config.API.Token, Origins: d.config.API.Origins}
+	if d.arp != nil {
+		apiConfig.ARP = d.arp
+	}
+	if d.dhcp4 != nil {
+		apiConfig.DHCP4 = d.dhcp4
+	}
+	if d.dns != nil {
+		apiConfig.DNS = d.dns
+	}
+	if d.api, err = api.New(apiConfig); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stopHandlers unregisters and closes all handlers. The caller must hold the lock or be the only user of d.
+func (d *daemon) stopHandlers() {
+	if d.radvs != nil {
+		d.radvs.Stop()
+		d.radvs = nil
+	}
+	if d.dns != nil {
+		d.session.Unregister(d.dns)
+		d.dns.Close()
+		d.dns = nil
+	}
+	if d.dhcp4 != nil {
+		d.session.Unregister(d.dhcp4)
+		d.dhcp4.Close()
+		d.dhcp4 = nil
+	}
+	if d.arp != nil {
+		d.session.Unregister(d.arp)
+		d.arp.Close()
+		d.arp = nil
+	}
+	if d.icmp6 != nil {
+		d.session.Unregister(d.icmp6)
+		d.icmp6.Close()
+		d.icmp6 = nil
+	}
+}
+
+// reload applies a new configuration. Log levels and handler settings are applied immediately;
+// changes to the nic, user, session and api settings require a restart.
+func (d *daemon) reload(config Config) error {
+	config.setLogLevels()
+	if config.NIC != d.config.NIC || config.User != d.config.User || !reflect.DeepEqual(config.API, d.config.API) ||
+		!reflect.DeepEqual(config.Session, d.config.Session) {
+		Logger.Msg("nic, user, session and api changes require a restart").Write()
+	}
+	if config.DHCP4 == d.config.DHCP4 && config.ARP == d.config.ARP && config.DNS == d.config.DNS &&
+		reflect.DeepEqual(config.RADVS, d.config.RADVS) {
+		return nil
+	}
+	Logger.Msg("restarting handlers").Write()
+	return d.restartHandlers(config)
+}
+
+// restartHandlers recreates the handlers with the handler settings in config and hunts the
+// captured hosts again. Handlers read the session nic on creation so they also restart when
+// the nic addresses or the router change.
+func (d *daemon) restartHandlers(config Config) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.stopHandlers()
+	previous := d.config
+	d.config.DHCP4, d.config.ARP, d.config.DNS, d.config.RADVS = config.DHCP4, config.ARP, config.DNS, config.RADVS
+	err := d.startHandlers()
+	if err != nil {
+		Logger.Msg("failed to start handlers - restoring previous config").Error(err).Write()
+		d.config = previous
+		if err := d.startHandlers(); err != nil {
+			Logger.Msg("failed to restore handlers").Error(err).Write()
+			return err
+		}
+	}
+
+	// new handlers have empty hunt lists
+	for _, host := range d.session.GetHosts() {
+		host.MACEntry.Row.RLock()
+		addr, hunt := packet.Addr{MAC: host.Addr.MAC, IP: host.Addr.IP}, host.Online && host.MACEntry.Captured
+		host.MACEntry.Row.RUnlock()
+		if hunt {
+			d.hunt(addr, true)
+		}
+	}
+	return err
+}
+
+// serveAPI binds the api listener and serves the http api until Close is called. The api handler
+// is replaced when handlers restart. It must be called before dropping privileges so the api can
+// listen on a privileged port.
+func (d *daemon) serveAPI() error {
+	if d.config.API.Listen == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", d.config.API.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on api address=%s: %w", d.config.API.Listen, err)
+	}
+	d.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mutex.RLock()
+		h := d.api
+		d.mutex.RUnlock()
+		h.ServeHTTP(w, r)
+	})}
+	go func() {
+		if err := d.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Logger.Msg("api server failed").Error(err).Write()
+		}
+	}()
+	return nil
+}
+
+// run starts the session Run loop and the hunt loop. Close waits for both to end.
+func (d *daemon) run(ctx context.Context) {
+	d.wg.Add(2)
+	go func() {
+		defer d.wg.Done()
+		if err := d.session.Run(ctx); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, packet.ErrHandlerClosed) {
+			Logger.Msg("session run failed").Error(err).Write()
+		}
+	}()
+	go func() {
+		defer d.wg.Done()
+		d.huntLoop()
+	}()
+}
+
+// huntLoop starts and stops hunting captured hosts as they come online and go offline.
+func (d *daemon) huntLoop() {
+	for e := range d.events.C {
+		for _, addr := range d.hostAddrs(e.Addr) {
+			hunt := d.session.IsCaptured(addr.MAC) && (e.Type == packet.EventCaptured || e.Type == packet.EventHostOnline)
+			d.mutex.RLock()
+			d.hunt(addr, hunt)
+			d.mutex.RUnlock()
+		}
+	}
+}
+
+// hostAddrs returns the addresses of the hosts with the event mac. Offline events carry the
+// host address as the host may no longer be in the table.
+func (d *daemon) hostAddrs(addr packet.Addr) (list []packet.Addr) {
+	if addr.IP.IsValid() {
+		return []packet.Addr{addr}
+	}
+	for _, host := range d.session.GetHosts() {
+		host.MACEntry.Row.RLock()
+		if bytes.Equal(host.Addr.MAC, addr.MAC) {
+			list = append(list, packet.Addr{MAC: host.Addr.MAC, IP: host.Addr.IP})
+		}
+		host.MACEntry.Row.RUnlock()
+	}
+	return list
+}
+
+// hunt starts or stops hunting addr. The caller must hold the read lock.
+func (d *daemon) hunt(addr packet.Addr, start bool) {
+	var err error
+	switch {
+	case addr.IP.Is4() && start:
+		if d.arp != nil {
+			_, err = d.arp.StartHunt(addr)
+		}
+		if d.dhcp4 != nil && err == nil {
+			_, err = d.dhcp4.StartHunt(addr)
+		}
+	case addr.IP.Is4():
+		if d.arp != nil {
+			_, err = d.arp.StopHunt(addr)
+		}
+		if d.dhcp4 != nil && err == nil {
+			_, err = d.dhcp4.StopHunt(addr)
+		}
+	case d.icmp6 == nil:
+	case start:
+		_, err = d.icmp6.StartHunt(addr)
+	default:
+		_, err = d.icmp6.StopHunt(addr)
+	}
+	if err != nil {
+		Logger.Msg("hunt failed").Struct(addr).Bool("start", start).Error(err).Write()
+	}
+}
+
+// Close stops the api server, the handlers and the session.
+func (d *daemon) Close() {
+	if d.server != nil {
+		d.server.Close()
+	}
+	d.mutex.Lock()
+	d.stopHandlers()
+	d.mutex.Unlock()
+	d.session.Close() // ends Run and closes the hunt loop subscription
+	d.wg.Wait()
+}
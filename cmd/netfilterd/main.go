@@ -0,0 +1,99 @@
+package main
+
+// Command netfilterd runs the session and all handlers configured in a yaml file.
+//
+// Usage:
+//
+//	netfilterd -c /etc/netfilterd.yaml
+//
+// Send SIGHUP to reload the configuration file and SIGINT or SIGTERM to exit.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+var configFile = flag.String("c", "/etc/netfilterd.yaml", "configuration file")
+
+// Logger is the daemon logger
+var Logger = fastlog.New("daemon")
+
+func main() {
+	flag.Parse()
+
+	config, err := LoadConfig(*configFile)
+	if err != nil {
+		fmt.Println("error loading config:", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d, err := newDaemon(ctx, config)
+	if err != nil {
+		fmt.Println("error starting daemon:", err)
+		os.Exit(1)
+	}
+	defer d.Close()
+	if err := d.serveAPI(); err != nil {
+		Logger.Msg("failed to start api").Error(err).Write()
+		return
+	}
+
+	// the raw socket, the handler sockets, the api listener and ip forwarding are set; root is no
+	// longer needed but the network capabilities are kept to restart handlers
+	if config.User != "" {
+		if err := dropPrivileges(config.User); err != nil {
+			Logger.Msg("failed to drop privileges").Error(err).Write()
+			return
+		}
+		Logger.Msg("dropped privileges").String("user", config.User).Write()
+	}
+
+	nic := d.session.Subscribe(0, packet.EventTypes(packet.EventNICStopped, packet.EventNICChanged))
+	d.run(ctx)
+
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for {
+		select {
+		case e, ok := <-nic.C:
+			switch {
+			case !ok:
+				return
+			case e.Type == packet.EventNICChanged:
+				if err := d.restartHandlers(d.config); err != nil {
+					Logger.Msg("failed to restart handlers").Error(err).Write()
+				}
+			default:
+				// a nic that stops receiving ip packets is likely on a disabled switch port; exit and
+				// let the service manager restart the daemon
+				Logger.Msg("exiting").Struct(e).Write()
+				return
+			}
+		case sig := <-c:
+			switch sig {
+			case syscall.SIGHUP:
+				Logger.Msg("reloading config").String("file", *configFile).Write()
+				config, err := LoadConfig(*configFile)
+				if err != nil {
+					Logger.Msg("failed to reload config - keeping current config").Error(err).Write()
+					continue
+				}
+				if err := d.reload(config); err != nil {
+					Logger.Msg("failed to apply config").Error(err).Write()
+				}
+
+			case syscall.SIGINT, syscall.SIGTERM:
+				return // will cause defered Close() functions to run
+			}
+		}
+	}
+}
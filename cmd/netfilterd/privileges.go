@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"runtime"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// keepCapabilities are the capabilities kept after dropping privileges. Handlers open raw
+// and netlink sockets, set ip forwarding and bind the dhcp server port when they restart on
+// reload or nic changes.
+var keepCapabilities = []uintptr{unix.CAP_NET_RAW, unix.CAP_NET_ADMIN, unix.CAP_NET_BIND_SERVICE}
+
+// dropPrivileges changes the process user and group to name and keeps the network
+// capabilities in keepCapabilities as ambient capabilities. The raw socket and the sockets
+// opened by the handlers remain usable; files opened later, including the inventory and
+// the dhcp lease file, are subject to the new user permissions.
+//
+// Capabilities are per thread so they are changed in all threads; this is not supported
+// when the binary is built with cgo.
+func dropPrivileges(name string) error {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return fmt.Errorf("failed to lookup user=%s: %w", name, err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return fmt.Errorf("invalid uid=%s: %w", u.Uid, err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return fmt.Errorf("invalid gid=%s: %w", u.Gid, err)
+	}
+
+	// keep the permitted capabilities when the uid changes
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, unix.PR_SET_KEEPCAPS, 1, 0); errno != 0 {
+		if errno == syscall.ENOTSUP {
+			return fmt.Errorf("failed to keep capabilities - build with CGO_ENABLED=0: %w", errno)
+		}
+		return fmt.Errorf("failed to keep capabilities: %w", errno)
+	}
+
+	// the group must change first; we cannot change the group once we are no longer root
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("failed to set gid=%d: %w", gid, err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("failed to set uid=%d: %w", uid, err)
+	}
+
+	// the effective set is cleared by setuid; restore the network capabilities and drop all others.
+	// Ambient capabilities must be in the permitted and inheritable sets.
+	var mask uint32
+	for _, c := range keepCapabilities {
+		mask |= 1 << c
+	}
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{{Effective: mask, Permitted: mask, Inheritable: mask}}
+	_, _, errno := syscall.AllThreadsSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0)
+	runtime.KeepAlive(&header)
+	runtime.KeepAlive(&data)
+	if errno != 0 {
+		return fmt.Errorf("failed to set capabilities: %w", errno)
+	}
+	for _, c := range keepCapabilities {
+		if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_RAISE, c); errno != 0 {
+			return fmt.Errorf("failed to raise ambient capability=%d: %w", c, errno)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package packet
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// ConflictKind identifies the packet that revealed an address conflict.
+type ConflictKind uint8
+
+// Address conflict kinds
+const (
+	ConflictProbe        ConflictKind = 1 // arp probe for an address in use or being probed by another mac
+	ConflictAnnouncement ConflictKind = 2 // gratuitous arp for an address in use by another mac
+	ConflictARP          ConflictKind = 3 // arp request or reply with a sender address in use by another mac
+	ConflictDAD          ConflictKind = 4 // ipv6 duplicate address detection for an address in use or being probed by another mac
+)
+
+func (k ConflictKind) String() string {
+	switch k {
+	case ConflictProbe:
+		return "probe"
+	case ConflictAnnouncement:
+		return "announcement"
+	case ConflictARP:
+		return "arp"
+	case ConflictDAD:
+		return "dad"
+	}
+	return "invalid"
+}
+
+// IPConflictEvent is the Data of EventIPConflict events.
+type IPConflictEvent struct {
+	Kind        ConflictKind
+	IP          netip.Addr
+	MAC         net.HardwareAddr // mac currently using or probing the address
+	ConflictMAC net.HardwareAddr // mac claiming the address
+}
+
+func (e IPConflictEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.String("kind", e.Kind.String())
+	l.IP("ip", e.IP)
+	l.MAC("mac", e.MAC)
+	l.MAC("conflictMAC", e.ConflictMAC)
+	return l
+}
+
+// DefaultConflictWindow is the period a host must have been seen, or a probe sent, for
+// another mac claiming the same address to be considered a conflict.
+// RFC 5227 probing completes in less than 10 seconds; we allow longer for slow hosts.
+const DefaultConflictWindow = time.Second * 30
+
+type conflictProbe struct {
+	mac  net.HardwareAddr
+	time time.Time
+}
+
+type conflictState struct {
+	sync.Mutex
+	probes map[netip.Addr]conflictProbe // recent arp probes and dad solicitations
+	events eventLimiter[netip.Addr]
+}
+
+// processARPConflict checks an arp packet for address conflicts.
+//
+// RFC 5227 probes have a zero sender address and announcements have the sender address equal to the
+// target address. A conflict is raised when a mac probes, announces or uses an address that another
+// mac used or probed within DefaultConflictWindow.
+// see https://www.rfc-editor.org/rfc/rfc5227
+func (h *Session) processARPConflict(p ARP, now time.Time) {
+	switch {
+	case p.Operation() == ARPOperationRequest && p.SrcIP().IsUnspecified():
+		if Logger.IsDebug() {
+			Logger.Msg("arp probe").MAC("mac", p.SrcMAC()).IP("ip", p.DstIP()).Write()
+		}
+		h.checkConflict(ConflictProbe, p.DstIP(), p.SrcMAC(), true, now)
+	case p.SrcIP() == p.DstIP():
+		if Logger.IsDebug() {
+			Logger.Msg("arp announcement").MAC("mac", p.SrcMAC()).IP("ip", p.SrcIP()).Write()
+		}
+		h.checkConflict(ConflictAnnouncement, p.SrcIP(), p.SrcMAC(), false, now)
+	default:
+		h.checkConflict(ConflictARP, p.SrcIP(), p.SrcMAC(), false, now)
+	}
+}
+
+// processDADConflict checks an ipv6 duplicate address detection neighbour solicitation,
+// sent with an unspecified source address, for address conflicts.
+// see https://www.rfc-editor.org/rfc/rfc4862#section-5.4
+func (h *Session) processDADConflict(mac net.HardwareAddr, p ICMP6NeighborSolicitation, now time.Time) {
+	if Logger.IsDebug() {
+		Logger.Msg("ipv6 dad").MAC("mac", mac).IP("ip", p.TargetAddress()).Write()
+	}
+	h.checkConflict(ConflictDAD, p.TargetAddress(), mac, true, now)
+}
+
+// checkConflict sends an EventIPConflict if ip is in use by, or was recently probed by, a mac other than mac.
+// Events are sent at most once per minute for each ip.
+func (h *Session) checkConflict(kind ConflictKind, ip netip.Addr, mac net.HardwareAddr, probe bool, now time.Time) {
+	if !ip.IsValid() || ip.IsUnspecified() || bytes.Equal(mac, h.NICInfo().HostAddr4.MAC) {
+		return
+	}
+
+	var owner net.HardwareAddr
+	h.mutex.RLock()
+	if host := h.findIP(ip); host != nil {
+		host.MACEntry.Row.RLock()
+		if host.Online && now.Sub(host.LastSeen) < DefaultConflictWindow && !bytes.Equal(host.MACEntry.MAC, mac) {
+			owner = CopyMAC(host.MACEntry.MAC)
+		}
+		host.MACEntry.Row.RUnlock()
+	}
+	h.mutex.RUnlock()
+
+	h.conflicts.Lock()
+	if h.conflicts.probes == nil {
+		h.conflicts.probes = make(map[netip.Addr]conflictProbe)
+	}
+	if v, found := h.conflicts.probes[ip]; owner == nil && found && now.Sub(v.time) < DefaultConflictWindow && !bytes.Equal(v.mac, mac) {
+		owner = v.mac
+	}
+	if probe {
+		h.conflicts.probes[ip] = conflictProbe{mac: CopyMAC(mac), time: now}
+	}
+	if owner == nil || !h.conflicts.events.allow(ip, now) {
+		h.conflicts.Unlock()
+		return
+	}
+	h.conflicts.Unlock()
+
+	h.sendEvent(Event{Type: EventIPConflict, Time: now, Addr: Addr{MAC: CopyMAC(mac), IP: ip},
+		Data: IPConflictEvent{Kind: kind, IP: ip, MAC: owner, ConflictMAC: CopyMAC(mac)}})
+}
+
+// purgeConflicts deletes expired probes and event times.
+func (h *Session) purgeConflicts(now time.Time) {
+	h.conflicts.Lock()
+	defer h.conflicts.Unlock()
+	for k, v := range h.conflicts.probes {
+		if now.Sub(v.time) >= DefaultConflictWindow {
+			delete(h.conflicts.probes, k)
+		}
+	}
+	h.conflicts.events.purge(now)
+}
@@ -0,0 +1,81 @@
+package packet
+
+import (
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newARPFrame(op uint16, srcAddr Addr, dstAddr Addr) Ether {
+	ether := newEtherPacket(syscall.ETH_P_ARP, srcAddr.MAC, EthernetBroadcast)
+	ether, _ = ether.SetPayload(EncodeARP(ether.Payload(), op, srcAddr, dstAddr))
+	return ether
+}
+
+func newDADFrame(mac net.HardwareAddr, target netip.Addr) Ether {
+	ether := newEtherPacket(syscall.ETH_P_IPV6, mac, IPv6SolicitedNode(target).MAC)
+	ip6 := EncodeIP6(ether.Payload(), 255, IPv6zero, IPv6SolicitedNode(target).IP)
+	ns, _ := ICMP6NeighborSolicitationMarshal(target, nil)
+	ip6, _ = ip6.AppendPayload(ns[:24], syscall.IPPROTO_ICMPV6) // no source lla option in dad
+	ether, _ = ether.SetPayload(ip6)
+	return ether
+}
+
+func TestSession_IPConflict(t *testing.T) {
+	session, _ := testSession()
+	ip3 := netip.MustParseAddr("192.168.0.3")
+	ip4 := netip.MustParseAddr("192.168.0.4")
+	lla := netip.MustParseAddr("fe80::1")
+	mac3 := net.HardwareAddr{0x00, 0x02, 0x03, 0x04, 0x05, 0x03}
+
+	tests := []struct {
+		name      string
+		frame     Ether
+		wantKind  ConflictKind // zero if no event is expected
+		wantMAC   net.HardwareAddr
+		wantClaim net.HardwareAddr
+	}{
+		{name: "first probe", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: IPv4zero}, Addr{MAC: EthernetZero, IP: ip3})},
+		{name: "same mac probe", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: IPv4zero}, Addr{MAC: EthernetZero, IP: ip3})},
+		{name: "concurrent probe", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac2, IP: IPv4zero}, Addr{MAC: EthernetZero, IP: ip3}),
+			wantKind: ConflictProbe, wantMAC: mac1, wantClaim: mac2},
+		{name: "announcement", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: ip4}, Addr{MAC: EthernetBroadcast, IP: ip4})},
+		{name: "probe in use", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac2, IP: IPv4zero}, Addr{MAC: EthernetZero, IP: ip4}),
+			wantKind: ConflictProbe, wantMAC: mac1, wantClaim: mac2},
+		{name: "rate limited", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac3, IP: IPv4zero}, Addr{MAC: EthernetZero, IP: ip4})},
+		{name: "request", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: ip1}, Addr{MAC: EthernetZero, IP: routerIP4})},
+		{name: "reply in use", frame: newARPFrame(ARPOperationReply, Addr{MAC: mac3, IP: ip1}, Addr{MAC: mac1, IP: ip4}),
+			wantKind: ConflictARP, wantMAC: mac1, wantClaim: mac3},
+		{name: "our mac", frame: newARPFrame(ARPOperationReply, Addr{MAC: hostMAC, IP: routerIP4}, Addr{MAC: mac1, IP: ip4})},
+		{name: "first dad", frame: newDADFrame(mac1, lla)},
+		{name: "concurrent dad", frame: newDADFrame(mac2, lla), wantKind: ConflictDAD, wantMAC: mac1, wantClaim: mac2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := session.Parse(tt.frame); err != nil {
+				t.Fatal("unexpected parse error", err)
+			}
+			if tt.wantKind == 0 {
+				if len(session.Events) != 0 {
+					t.Fatalf("unexpected event %s", <-session.Events)
+				}
+				return
+			}
+			if len(session.Events) != 1 {
+				t.Fatalf("expected %s conflict event", tt.wantKind)
+			}
+			e := <-session.Events
+			data, ok := e.Data.(IPConflictEvent)
+			if e.Type != EventIPConflict || !ok || data.Kind != tt.wantKind || data.MAC.String() != tt.wantMAC.String() || data.ConflictMAC.String() != tt.wantClaim.String() {
+				t.Errorf("invalid event %s", e)
+			}
+		})
+	}
+
+	session.purgeConflicts(time.Now().Add(time.Minute))
+	if n, m := len(session.conflicts.probes), session.conflicts.events.len(); n != 0 || m != 0 {
+		t.Errorf("entries not purged probes=%d events=%d", n, m)
+	}
+}
@@ -0,0 +1,391 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// Device links the mac addresses used by the same physical device.
+//
+// Phones and laptops rotate locally administered mac addresses so a single device
+// appears as many MACEntries. The session correlates mac addresses using stable signals
+// seen on the wire: dhcp client identifier, hostname and fingerprint, mdns name and the
+// interface identifier of the ipv6 link local address.
+type Device struct {
+	ID          int         // session unique device id
+	MACs        []DeviceMAC // mac history; oldest first and the last entry is the current mac
+	ClientID    []byte      // dhcp client identifier option
+	Hostname    string      // dhcp hostname option
+	Fingerprint string      // dhcp parameter request list and vendor class
+	MDNSName    string
+	LLA         netip.Addr // ipv6 link local address with an opaque interface identifier
+	FirstSeen   time.Time
+	LastSeen    time.Time
+}
+
+// DeviceMAC is an entry in the device mac history.
+type DeviceMAC struct {
+	MAC       net.HardwareAddr
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// MAC returns the current mac address for the device.
+func (d Device) MAC() net.HardwareAddr {
+	if len(d.MACs) == 0 {
+		return nil
+	}
+	return d.MACs[len(d.MACs)-1].MAC
+}
+
+func (d Device) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Int("id", d.ID)
+	l.MAC("mac", d.MAC())
+	l.Int("macs", len(d.MACs))
+	if d.Hostname != "" {
+		l.String("hostname", d.Hostname)
+	}
+	if d.MDNSName != "" {
+		l.String("mdnsname", d.MDNSName)
+	}
+	if d.Fingerprint != "" {
+		l.String("fingerprint", d.Fingerprint)
+	}
+	if d.LLA.IsValid() {
+		l.IP("lla", d.LLA)
+	}
+	return l
+}
+
+// DeviceEvent is the Data of EventDeviceMACChanged events.
+type DeviceEvent struct {
+	Device      Device
+	PreviousMAC net.HardwareAddr // mac the device was using before
+	Signals     []string         // signals that matched the known device
+}
+
+func (e DeviceEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Struct(e.Device)
+	l.MAC("previousMAC", e.PreviousMAC)
+	l.String("signals", strings.Join(e.Signals, ","))
+	return l
+}
+
+// Device correlation parameters.
+//
+// Client identifier, mdns name and link local address are unique enough to link a new mac on
+// their own. Hostname and fingerprint are shared by many devices of the same model, so both must
+// match to link a mac.
+const (
+	DefaultDeviceMACHistory = 32                  // maximum number of macs kept per device
+	DefaultDeviceDeadline   = time.Hour * 24 * 30 // delete devices not seen for this long
+	deviceLinkScore         = 2
+)
+
+// device signal names and weights
+const (
+	signalClientID    = "clientid"
+	signalMDNS        = "mdns"
+	signalLLA         = "lla"
+	signalHostname    = "hostname"
+	signalFingerprint = "fingerprint"
+)
+
+var signalWeight = map[string]int{signalClientID: 2, signalMDNS: 2, signalLLA: 2, signalHostname: 1, signalFingerprint: 1}
+
+// deviceSignals holds the signals seen for a mac; empty fields are ignored.
+type deviceSignals struct {
+	clientID    []byte
+	hostname    string
+	fingerprint string
+	mdnsName    string
+	lla         netip.Addr
+}
+
+// keys returns the signal table keys in the format "signal/value".
+func (s deviceSignals) keys() (keys []string) {
+	if len(s.clientID) > 0 {
+		keys = append(keys, signalClientID+"/"+hex.EncodeToString(s.clientID))
+	}
+	if s.hostname != "" {
+		keys = append(keys, signalHostname+"/"+strings.ToLower(s.hostname))
+	}
+	if s.fingerprint != "" {
+		keys = append(keys, signalFingerprint+"/"+s.fingerprint)
+	}
+	if s.mdnsName != "" {
+		keys = append(keys, signalMDNS+"/"+strings.ToLower(s.mdnsName))
+	}
+	if s.lla.IsValid() {
+		keys = append(keys, signalLLA+"/"+s.lla.String())
+	}
+	return keys
+}
+
+type deviceState struct {
+	nextID  int
+	devices []*Device
+	macs    map[string]*Device // key is mac string
+	signals map[string]*Device // key is signal/value
+}
+
+// processDeviceDHCP4 extracts the device signals from a dhcp discover or request.
+func (h *Session) processDeviceDHCP4(p DHCP4, now time.Time) {
+	if err := p.IsValid(); err != nil || p.OpCode() != DHCP4BootRequest {
+		return
+	}
+	options := p.ParseOptions()
+	mac := p.CHAddr()
+	s := deviceSignals{hostname: string(options[DHCP4OptionHostName]), fingerprint: dhcp4Fingerprint(options)}
+	// ignore client ids derived from the mac; the common case for hardware type 1
+	if id := options[DHCP4OptionClientIdentifier]; len(id) > 0 && !(len(id) == 7 && id[0] == 1 && bytes.Equal(id[1:], mac)) {
+		s.clientID = id
+	}
+	h.processDevice(mac, s, now)
+}
+
+// processDeviceLLA records the ipv6 link local address of mac if the interface identifier is opaque.
+// Interface identifiers derived from the mac (EUI-64) change with the mac so they are ignored.
+func (h *Session) processDeviceLLA(mac net.HardwareAddr, ip netip.Addr, now time.Time) {
+	if !ip.Is6() || !ip.IsLinkLocalUnicast() {
+		return
+	}
+	iid := eui64(mac)
+	if b := ip.As16(); bytes.Equal(b[8:], iid[:]) {
+		return
+	}
+	h.mutex.RLock()
+	host := h.HostTable.Table[ip]
+	known := host != nil && bytes.Equal(host.MACEntry.MAC, mac)
+	h.mutex.RUnlock()
+	if known {
+		return
+	}
+	h.processDevice(mac, deviceSignals{lla: ip}, now)
+}
+
+// processDevice links mac to a device and records the signals.
+//
+// A mac not seen before is linked to the known device with the highest signal score if the score
+// is at least deviceLinkScore; an EventDeviceMACChanged is then sent. Otherwise a new device is created.
+// A link local address does not link a mac while the previous device mac is online.
+// Macs without any signal are not tracked.
+func (h *Session) processDevice(mac net.HardwareAddr, s deviceSignals, now time.Time) {
+	keys := s.keys()
+	if len(keys) == 0 || len(mac) != EthAddrLen || bytes.Equal(mac, h.NICInfo().HostAddr4.MAC) {
+		return
+	}
+	var event *Event
+
+	h.mutex.Lock()
+	if h.devices.macs == nil {
+		h.devices.macs = make(map[string]*Device)
+		h.devices.signals = make(map[string]*Device)
+	}
+	d := h.devices.macs[string(mac)]
+	if d == nil {
+		best, matched := h.matchDevice(keys)
+		if best != nil && s.lla.IsValid() && h.isMACOnline(best.MAC()) {
+			// a second mac using the address while the device mac is online is a duplicate
+			// or a spoof, not a mac change; impersonation and conflict detection report it
+			h.mutex.Unlock()
+			return
+		}
+		if best != nil {
+			previous := CopyMAC(best.MAC())
+			d = best
+			d.MACs = append(d.MACs, DeviceMAC{MAC: CopyMAC(mac), FirstSeen: now})
+			if len(d.MACs) > DefaultDeviceMACHistory {
+				delete(h.devices.macs, string(d.MACs[0].MAC))
+				d.MACs = d.MACs[1:]
+			}
+			event = &Event{Type: EventDeviceMACChanged, Time: now, Addr: Addr{MAC: CopyMAC(mac)},
+				Data: DeviceEvent{PreviousMAC: previous, Signals: matched}}
+		} else {
+			h.devices.nextID++
+			d = &Device{ID: h.devices.nextID, MACs: []DeviceMAC{{MAC: CopyMAC(mac), FirstSeen: now}}, FirstSeen: now}
+			h.devices.devices = append(h.devices.devices, d)
+		}
+		h.devices.macs[string(CopyMAC(mac))] = d
+	}
+
+	// the device may return to a previous mac; move it to the end of the history
+	for i := range d.MACs {
+		if bytes.Equal(d.MACs[i].MAC, mac) {
+			entry := d.MACs[i]
+			entry.LastSeen = now
+			d.MACs = append(append(d.MACs[:i:i], d.MACs[i+1:]...), entry)
+			break
+		}
+	}
+	if len(s.clientID) > 0 {
+		d.ClientID = CopyBytes(s.clientID)
+	}
+	if s.hostname != "" {
+		d.Hostname = s.hostname
+	}
+	if s.fingerprint != "" {
+		d.Fingerprint = s.fingerprint
+	}
+	if s.mdnsName != "" {
+		d.MDNSName = s.mdnsName
+	}
+	if s.lla.IsValid() {
+		d.LLA = s.lla
+	}
+	d.LastSeen = now
+	for _, k := range keys {
+		h.devices.signals[k] = d // latest device wins when a signal moves
+	}
+	if event != nil {
+		data := event.Data.(DeviceEvent)
+		data.Device = d.copy()
+		event.Data = data
+	}
+	h.mutex.Unlock()
+
+	if event != nil {
+		if Logger.IsInfo() {
+			Logger.Msg("device mac changed").Struct(event.Data.(DeviceEvent)).Write()
+		}
+		h.sendEvent(*event)
+	}
+}
+
+// isMACOnline returns true if the mac entry exists and is online.
+// Session must be locked prior to calling this function.
+func (h *Session) isMACOnline(mac net.HardwareAddr) bool {
+	e, _ := h.MACTable.findMAC(mac)
+	if e == nil {
+		return false
+	}
+	e.Row.RLock()
+	defer e.Row.RUnlock()
+	return e.Online
+}
+
+// matchDevice returns the device with the highest score for keys and the matched signal names.
+// Session must be locked prior to calling this function.
+func (h *Session) matchDevice(keys []string) (best *Device, matched []string) {
+	scores := make(map[*Device]int)
+	signals := make(map[*Device][]string)
+	bestScore := 0
+	for _, k := range keys {
+		d := h.devices.signals[k]
+		if d == nil {
+			continue
+		}
+		name := k[:strings.IndexByte(k, '/')]
+		scores[d] += signalWeight[name]
+		signals[d] = append(signals[d], name)
+		if scores[d] > bestScore || (scores[d] == bestScore && best != nil && d.LastSeen.After(best.LastSeen)) {
+			best, bestScore = d, scores[d]
+		}
+	}
+	if bestScore < deviceLinkScore {
+		return nil, nil
+	}
+	return best, signals[best]
+}
+
+// processDeviceNames feeds the mdns names learned by the naming handlers.
+func (h *Session) processDeviceNames(now time.Time) {
+	type entry struct {
+		mac  net.HardwareAddr
+		name string
+	}
+	var list []entry
+	h.mutex.RLock()
+	for _, v := range h.MACTable.Table {
+		if v.MDNSName.Name != "" {
+			list = append(list, entry{mac: v.MAC, name: v.MDNSName.Name})
+		}
+	}
+	h.mutex.RUnlock()
+	for _, v := range list {
+		h.processDevice(v.mac, deviceSignals{mdnsName: v.name}, now)
+	}
+}
+
+// purgeDevices deletes devices not seen since DefaultDeviceDeadline.
+func (h *Session) purgeDevices(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	devices := h.devices.devices[:0]
+	for _, d := range h.devices.devices {
+		if now.Sub(d.LastSeen) < DefaultDeviceDeadline {
+			devices = append(devices, d)
+			continue
+		}
+		for _, v := range d.MACs {
+			delete(h.devices.macs, string(v.MAC))
+		}
+		for k, v := range h.devices.signals {
+			if v == d {
+				delete(h.devices.signals, k)
+			}
+		}
+	}
+	h.devices.devices = devices
+}
+
+// copy returns a deep copy of the device.
+func (d *Device) copy() Device {
+	c := *d
+	c.MACs = make([]DeviceMAC, len(d.MACs))
+	for i, v := range d.MACs {
+		c.MACs[i] = DeviceMAC{MAC: CopyMAC(v.MAC), FirstSeen: v.FirstSeen, LastSeen: v.LastSeen}
+	}
+	c.ClientID = CopyBytes(d.ClientID)
+	return c
+}
+
+// Devices returns a copy of the devices table.
+func (h *Session) Devices() []Device {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]Device, 0, len(h.devices.devices))
+	for _, v := range h.devices.devices {
+		list = append(list, v.copy())
+	}
+	return list
+}
+
+// FindDevice returns the device linked to mac.
+func (h *Session) FindDevice(mac net.HardwareAddr) (Device, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if d := h.devices.macs[string(mac)]; d != nil {
+		return d.copy(), true
+	}
+	return Device{}, false
+}
+
+// dhcp4Fingerprint returns the parameter request list as comma separated option codes followed by
+// the vendor class identifier. For example "1,3,6,15,119,252|android-dhcp-13".
+func dhcp4Fingerprint(options DHCP4Options) string {
+	list := options[DHCP4OptionParameterRequestList]
+	vendor := options[DHCP4OptionVendorClassIdentifier]
+	if len(list) == 0 && len(vendor) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i, v := range list {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(int(v)))
+	}
+	if len(vendor) > 0 {
+		b.WriteByte('|')
+		b.Write(vendor)
+	}
+	return b.String()
+}
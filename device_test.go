@@ -0,0 +1,114 @@
+package packet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func testDHCP4Discover(mac net.HardwareAddr, clientID []byte, hostname string, prl []byte) []byte {
+	options := DHCP4Options{DHCP4OptionParameterRequestList: prl}
+	if clientID != nil {
+		options[DHCP4OptionClientIdentifier] = clientID
+	}
+	if hostname != "" {
+		options[DHCP4OptionHostName] = []byte(hostname)
+	}
+	return EncodeDHCP4(make([]byte, 1024), DHCP4BootRequest, DHCP4Discover, mac, IPv4zero, IPv4zero, []byte{1, 2, 3, 4}, false, options, nil)
+}
+
+func TestSession_Devices(t *testing.T) {
+	session, _ := testSession()
+	random1 := net.HardwareAddr{0x02, 0xaa, 0x00, 0x00, 0x00, 0x01}
+	random2 := net.HardwareAddr{0x06, 0xaa, 0x00, 0x00, 0x00, 0x02}
+	random3 := net.HardwareAddr{0x0a, 0xaa, 0x00, 0x00, 0x00, 0x03}
+	random4 := net.HardwareAddr{0x0e, 0xaa, 0x00, 0x00, 0x00, 0x04}
+	random5 := net.HardwareAddr{0x12, 0xaa, 0x00, 0x00, 0x00, 0x05}
+	phonePRL := []byte{1, 3, 6, 15, 119, 252}
+	server := Addr{MAC: EthernetBroadcast, IP: IPv4bcast, Port: DHCP4ServerPort}
+	dhcp := func(mac net.HardwareAddr, clientID []byte, hostname string) Ether {
+		return testUDPFrame(Addr{MAC: mac, IP: IPv4zero, Port: DHCP4ClientPort}, server, testDHCP4Discover(mac, clientID, hostname, phonePRL))
+	}
+	lla := netip.MustParseAddr("fe80::1c2b:3a4d:5e6f:7081")
+
+	tests := []struct {
+		name    string
+		frame   Ether
+		want    bool // expect device mac changed event
+		signals int  // number of matched signals in event
+		devices int  // number of devices after frame
+		mac     net.HardwareAddr
+		prevMAC net.HardwareAddr
+	}{
+		{name: "phone", frame: dhcp(random1, []byte{0xff, 1, 2, 3, 4}, "phone"), devices: 1, mac: random1},
+		{name: "client id", frame: dhcp(random2, []byte{0xff, 1, 2, 3, 4}, ""), want: true, signals: 2, devices: 1, mac: random2, prevMAC: random1},
+		{name: "hostname and fingerprint", frame: dhcp(random3, nil, "Phone"), want: true, signals: 2, devices: 1, mac: random3, prevMAC: random2},
+		{name: "hostname only", frame: testUDPFrame(Addr{MAC: mac1, IP: IPv4zero, Port: DHCP4ClientPort}, server, testDHCP4Discover(mac1, nil, "phone", []byte{1, 3})), devices: 2, mac: mac1},
+		{name: "mac client id", frame: dhcp(mac2, append([]byte{1}, mac2...), ""), devices: 3},
+		{name: "lla", frame: newNAFrame(random4, Addr{MAC: random4, IP: lla}), devices: 4, mac: random4},
+		{name: "lla previous mac online", frame: newNAFrame(random5, Addr{MAC: random5, IP: lla}), devices: 4},
+		{name: "eui64 lla", frame: newNAFrame(mac2, Addr{MAC: mac2, IP: netip.MustParseAddr("fe80::2:3ff:fe04:502")}), devices: 4},
+		{name: "lla new mac", frame: newNAFrame(random1, Addr{MAC: random1, IP: lla}), devices: 4, mac: random1},
+		{name: "previous mac", frame: dhcp(random2, []byte{0xff, 1, 2, 3, 4}, ""), devices: 4, mac: random2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := session.Parse(tt.frame); err != nil {
+				t.Fatal("unexpected parse error", err)
+			}
+			var events []Event
+			for len(session.Events) > 0 {
+				if e := <-session.Events; e.Type == EventDeviceMACChanged {
+					events = append(events, e)
+				}
+			}
+			if n := len(session.Devices()); n != tt.devices {
+				t.Errorf("invalid devices len want=%d got=%d", tt.devices, n)
+			}
+			if !tt.want {
+				if len(events) != 0 {
+					t.Fatalf("unexpected event %s", events[0])
+				}
+			} else {
+				if len(events) != 1 {
+					t.Fatalf("expected one event got %d", len(events))
+				}
+				data, ok := events[0].Data.(DeviceEvent)
+				if !ok || data.Device.MAC().String() != tt.mac.String() || data.PreviousMAC.String() != tt.prevMAC.String() || len(data.Signals) != tt.signals {
+					t.Errorf("invalid event %+v", data)
+				}
+			}
+			if tt.mac != nil {
+				if d, found := session.FindDevice(tt.mac); !found || d.MAC().String() != tt.mac.String() {
+					t.Errorf("invalid device for mac=%s device=%+v", tt.mac, d)
+				}
+			}
+		})
+	}
+
+	// "lla new mac" moved the link local signal to the phone; the phone now has three macs
+	d, _ := session.FindDevice(random1)
+	if len(d.MACs) != 3 || d.LLA != lla || d.Hostname != "Phone" {
+		t.Errorf("invalid phone device %+v", d)
+	}
+}
+
+func TestSession_purgeDevices(t *testing.T) {
+	session, _ := testSession()
+	now := time.Now()
+	session.processDevice(mac1, deviceSignals{hostname: "a", fingerprint: "1,3"}, now.Add(-DefaultDeviceDeadline))
+	session.processDevice(mac2, deviceSignals{mdnsName: "b.local"}, now)
+	session.purgeDevices(now)
+	if n := len(session.Devices()); n != 1 {
+		t.Fatalf("invalid devices len want=1 got=%d", n)
+	}
+	if _, found := session.FindDevice(mac1); found {
+		t.Error("device not purged")
+	}
+	// signals of purged devices must not link new macs
+	session.processDevice(mac3, deviceSignals{hostname: "a", fingerprint: "1,3"}, now)
+	if len(session.Events) != 0 {
+		t.Errorf("unexpected event %s", <-session.Events)
+	}
+}
@@ -0,0 +1,169 @@
+package packet
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// DHCP4Server holds the options handed out by a dhcp server seen on the lan.
+type DHCP4Server struct {
+	ServerID  netip.Addr       // server identifier option
+	MAC       net.HardwareAddr // ethernet source of the server or relay
+	Routers   []netip.Addr     // router option in the last offer or ack
+	DNS       []netip.Addr     // domain name server option in the last offer or ack
+	Trusted   bool             // server is in Session.TrustedDHCP4 or is the router
+	Count     int              // number of offers and acks seen
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+func (s DHCP4Server) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.IP("serverID", s.ServerID)
+	l.MAC("mac", s.MAC)
+	for _, v := range s.Routers {
+		l.IP("router", v)
+	}
+	for _, v := range s.DNS {
+		l.IP("dns", v)
+	}
+	l.Bool("trusted", s.Trusted)
+	l.Int("count", s.Count)
+	return l
+}
+
+// DHCP4ServerEvent is the Data of EventRogueDHCP4Server and EventDHCP4OptionsChanged events.
+type DHCP4ServerEvent struct {
+	Server          DHCP4Server
+	PreviousRouters []netip.Addr // router option before the change
+	PreviousDNS     []netip.Addr // domain name server option before the change
+}
+
+func (e DHCP4ServerEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Struct(e.Server)
+	for _, v := range e.PreviousRouters {
+		l.IP("previousRouter", v)
+	}
+	for _, v := range e.PreviousDNS {
+		l.IP("previousDNS", v)
+	}
+	return l
+}
+
+type dhcp4ServerState struct {
+	table map[netip.Addr]*DHCP4Server
+}
+
+// processDHCP4Server records the server, router and dns options of dhcp offers and acks.
+//
+// It sends an EventRogueDHCP4Server the first time an untrusted server is seen, and an
+// EventDHCP4OptionsChanged when a server hands out a different router or dns list.
+// Frames sent by our host, including dhcp4_spoofer offers, must not be passed to this function.
+func (h *Session) processDHCP4Server(srcAddr Addr, p DHCP4, now time.Time) {
+	if err := p.IsValid(); err != nil || p.OpCode() != DHCP4BootReply {
+		return
+	}
+	options := p.ParseOptions()
+	if t := options[DHCP4OptionDHCPMessageType]; len(t) != 1 || (DHCP4MessageType(t[0]) != DHCP4Offer && DHCP4MessageType(t[0]) != DHCP4ACK) {
+		return
+	}
+	serverID := options.ServerID()
+	if !serverID.IsValid() {
+		serverID = srcAddr.IP
+	}
+	routers := dhcp4AddrList(options[DHCP4OptionRouter])
+	dns := dhcp4AddrList(options[DHCP4OptionDomainNameServer])
+
+	var eventType EventType
+	var previousRouters, previousDNS []netip.Addr
+	h.mutex.Lock()
+	if h.dhcp4Servers.table == nil {
+		h.dhcp4Servers.table = make(map[netip.Addr]*DHCP4Server)
+	}
+	s, found := h.dhcp4Servers.table[serverID]
+	switch {
+	case !found:
+		s = &DHCP4Server{ServerID: serverID, MAC: CopyMAC(srcAddr.MAC), Routers: routers, DNS: dns, FirstSeen: now}
+		s.Trusted = h.isTrustedDHCP4Server(serverID, srcAddr.MAC)
+		h.dhcp4Servers.table[serverID] = s
+		if !s.Trusted {
+			eventType = EventRogueDHCP4Server
+		}
+	case !equalAddrList(s.Routers, routers) || !equalAddrList(s.DNS, dns):
+		eventType = EventDHCP4OptionsChanged
+		previousRouters, previousDNS = s.Routers, s.DNS
+		s.Routers, s.DNS = routers, dns
+	}
+	s.Count++
+	s.LastSeen = now
+	server := *s
+	h.mutex.Unlock()
+
+	if Logger.IsDebug() {
+		Logger.Msg("dhcp4 server").Struct(server).Write()
+	}
+	if eventType != 0 {
+		h.sendEvent(Event{Type: eventType, Time: now, Addr: Addr{MAC: server.MAC, IP: serverID},
+			Data: DHCP4ServerEvent{Server: server, PreviousRouters: previousRouters, PreviousDNS: previousDNS}})
+	}
+}
+
+// isTrustedDHCP4Server returns true if the server is in the trusted list or, when the list is
+// empty, if the server is the router.
+func (h *Session) isTrustedDHCP4Server(serverID netip.Addr, mac net.HardwareAddr) bool {
+	if len(h.TrustedDHCP4) == 0 {
+		return serverID == h.NICInfo().RouterAddr4.IP || bytes.Equal(mac, h.NICInfo().RouterAddr4.MAC)
+	}
+	for _, v := range h.TrustedDHCP4 {
+		if v == serverID {
+			return true
+		}
+	}
+	return false
+}
+
+// purgeDHCP4Servers deletes servers not seen for PurgeDeadline. An untrusted server seen again
+// afterwards is reported as a new rogue server.
+func (h *Session) purgeDHCP4Servers(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for k, v := range h.dhcp4Servers.table {
+		if now.Sub(v.LastSeen) >= h.PurgeDeadline {
+			delete(h.dhcp4Servers.table, k)
+		}
+	}
+}
+
+// DHCP4Servers returns a copy of the dhcp servers seen on the lan.
+func (h *Session) DHCP4Servers() []DHCP4Server {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]DHCP4Server, 0, len(h.dhcp4Servers.table))
+	for _, v := range h.dhcp4Servers.table {
+		list = append(list, *v)
+	}
+	return list
+}
+
+// dhcp4AddrList returns the list of ipv4 addresses in the option
+func dhcp4AddrList(b []byte) (list []netip.Addr) {
+	for ; len(b) >= 4; b = b[4:] {
+		list = append(list, netip.AddrFrom4(*(*[4]byte)(b[:4])))
+	}
+	return list
+}
+
+func equalAddrList(a []netip.Addr, b []netip.Addr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
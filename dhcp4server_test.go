@@ -0,0 +1,101 @@
+package packet
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func testDHCP4Reply(mt DHCP4MessageType, serverID netip.Addr, router netip.Addr, dns ...netip.Addr) []byte {
+	options := DHCP4Options{
+		DHCP4OptionServerIdentifier: serverID.AsSlice(),
+		DHCP4OptionRouter:           router.AsSlice(),
+	}
+	var b []byte
+	for _, v := range dns {
+		b = append(b, v.AsSlice()...)
+	}
+	options[DHCP4OptionDomainNameServer] = b
+	return EncodeDHCP4(make([]byte, 1024), DHCP4BootReply, mt, mac1, IPv4zero, ip1, []byte{1, 2, 3, 4}, false, options, nil)
+}
+
+func TestSession_DHCP4Servers(t *testing.T) {
+	session, _ := testSession()
+	travelRouter := netip.MustParseAddr("192.168.8.1")
+	travelMAC := net.HardwareAddr{0x00, 0x02, 0x03, 0x04, 0x05, 0x08}
+	dns1 := netip.MustParseAddr("1.1.1.1")
+	dns2 := netip.MustParseAddr("8.8.8.8")
+	client := Addr{MAC: mac1, IP: IPv4bcast, Port: DHCP4ClientPort}
+	router := Addr{MAC: routerMAC, IP: routerIP4, Port: DHCP4ServerPort}
+	rogue := Addr{MAC: travelMAC, IP: travelRouter, Port: DHCP4ServerPort}
+
+	tests := []struct {
+		name    string
+		src     Addr
+		payload []byte
+		want    EventType // zero if no event is expected
+		servers int
+	}{
+		{name: "router offer", src: router, payload: testDHCP4Reply(DHCP4Offer, routerIP4, routerIP4, dns1), servers: 1},
+		{name: "router ack", src: router, payload: testDHCP4Reply(DHCP4ACK, routerIP4, routerIP4, dns1), servers: 1},
+		{name: "nak ignored", src: router, payload: testDHCP4Reply(DHCP4NAK, routerIP4, travelRouter, dns2), servers: 1},
+		{name: "dns changed", src: router, payload: testDHCP4Reply(DHCP4ACK, routerIP4, routerIP4, dns1, dns2), want: EventDHCP4OptionsChanged, servers: 1},
+		{name: "rogue offer", src: rogue, payload: testDHCP4Reply(DHCP4Offer, travelRouter, travelRouter, travelRouter), want: EventRogueDHCP4Server, servers: 2},
+		{name: "rogue again", src: rogue, payload: testDHCP4Reply(DHCP4Offer, travelRouter, travelRouter, travelRouter), servers: 2},
+		{name: "router changed", src: rogue, payload: testDHCP4Reply(DHCP4ACK, travelRouter, routerIP4, travelRouter), want: EventDHCP4OptionsChanged, servers: 2},
+		{name: "our offer", src: Addr{MAC: hostMAC, IP: hostIP4, Port: DHCP4ServerPort}, payload: testDHCP4Reply(DHCP4Offer, hostIP4, hostIP4, hostIP4), servers: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := session.Parse(testUDPFrame(tt.src, client, tt.payload)); err != nil {
+				t.Fatal("unexpected parse error", err)
+			}
+			if n := len(session.DHCP4Servers()); n != tt.servers {
+				t.Errorf("invalid servers len want=%d got=%d", tt.servers, n)
+			}
+			if tt.want == 0 {
+				if len(session.Events) != 0 {
+					t.Fatalf("unexpected event %s", <-session.Events)
+				}
+				return
+			}
+			if len(session.Events) != 1 {
+				t.Fatalf("expected %s event", tt.want)
+			}
+			e := <-session.Events
+			data, ok := e.Data.(DHCP4ServerEvent)
+			if e.Type != tt.want || !ok || data.Server.ServerID != tt.src.IP {
+				t.Errorf("invalid event %s", e)
+			}
+			if tt.want == EventDHCP4OptionsChanged && len(data.PreviousRouters) == 0 {
+				t.Errorf("missing previous options %s", e)
+			}
+		})
+	}
+
+	session.purgeDHCP4Servers(time.Now().Add(time.Minute))
+	if n := len(session.DHCP4Servers()); n != 2 {
+		t.Errorf("servers purged before the deadline len=%d", n)
+	}
+	session.purgeDHCP4Servers(time.Now().Add(session.PurgeDeadline))
+	if n := len(session.DHCP4Servers()); n != 0 {
+		t.Errorf("servers not purged len=%d", n)
+	}
+}
+
+func TestSession_TrustedDHCP4(t *testing.T) {
+	session, _ := testSession()
+	session.TrustedDHCP4 = []netip.Addr{netip.MustParseAddr("192.168.0.2")}
+	client := Addr{MAC: mac1, IP: IPv4bcast, Port: DHCP4ClientPort}
+
+	// router is not trusted when a list is set
+	session.Parse(testUDPFrame(Addr{MAC: routerMAC, IP: routerIP4, Port: DHCP4ServerPort}, client, testDHCP4Reply(DHCP4Offer, routerIP4, routerIP4)))
+	if len(session.Events) != 1 || (<-session.Events).Type != EventRogueDHCP4Server {
+		t.Error("expected rogue server event for router")
+	}
+	session.Parse(testUDPFrame(Addr{MAC: mac2, IP: ip2, Port: DHCP4ServerPort}, client, testDHCP4Reply(DHCP4Offer, ip2, routerIP4)))
+	if len(session.Events) != 0 {
+		t.Errorf("unexpected event %s", <-session.Events)
+	}
+}
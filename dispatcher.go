@@ -0,0 +1,270 @@
+package packet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Handler is the common interface of packet handlers dispatched by Session.Run.
+//
+// Handlers that do not hunt return StageNoChange from StartHunt and StopHunt; handlers
+// without periodic work return nil from MinuteTicker.
+type Handler interface {
+	ProcessPacket(Frame) error
+	MinuteTicker(time.Time) error
+	StartHunt(Addr) (HuntStage, error)
+	StopHunt(Addr) (HuntStage, error)
+	Close() error
+}
+
+// HandlerFilter selects the frames dispatched to a handler. A frame matches if its PayloadID
+// or its ethernet type is in the filter; an empty filter matches all frames.
+type HandlerFilter struct {
+	PayloadIDs []PayloadID
+	EtherTypes []uint16
+}
+
+func (f HandlerFilter) match(frame Frame) bool {
+	if len(f.PayloadIDs) == 0 && len(f.EtherTypes) == 0 {
+		return true
+	}
+	for _, id := range f.PayloadIDs {
+		if frame.PayloadID == id {
+			return true
+		}
+	}
+	if len(f.EtherTypes) > 0 {
+		etherType := frame.Ether().EtherType()
+		for _, t := range f.EtherTypes {
+			if etherType == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type registeredHandler struct {
+	handler Handler
+	filter  HandlerFilter
+	panics  uint64
+}
+
+type dispatcher struct {
+	mutex    sync.RWMutex
+	handlers []*registeredHandler
+}
+
+// list returns a copy of the registered handlers.
+func (d *dispatcher) list() []*registeredHandler {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return append([]*registeredHandler(nil), d.handlers...)
+}
+
+// Register adds a handler to the dispatcher. Session.Run calls ProcessPacket for every frame
+// matching the filter and MinuteTicker every minute.
+func (h *Session) Register(handler Handler, filter HandlerFilter) {
+	h.dispatcher.mutex.Lock()
+	defer h.dispatcher.mutex.Unlock()
+	h.dispatcher.handlers = append(h.dispatcher.handlers, &registeredHandler{handler: handler, filter: filter})
+}
+
+// Unregister removes the handler from the dispatcher. It does not close the handler.
+func (h *Session) Unregister(handler Handler) {
+	h.dispatcher.mutex.Lock()
+	defer h.dispatcher.mutex.Unlock()
+	for i, v := range h.dispatcher.handlers {
+		if v.handler == handler {
+			h.dispatcher.handlers = append(h.dispatcher.handlers[:i:i], h.dispatcher.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
+// StartHunt calls StartHunt on all registered handlers and returns the first error.
+func (h *Session) StartHunt(addr Addr) (err error) {
+	for _, v := range h.dispatcher.list() {
+		v.call("StartHunt", func() error {
+			_, e := v.handler.StartHunt(addr)
+			return e
+		}, &err)
+	}
+	return err
+}
+
+// StopHunt calls StopHunt on all registered handlers and returns the first error.
+func (h *Session) StopHunt(addr Addr) (err error) {
+	for _, v := range h.dispatcher.list() {
+		v.call("StopHunt", func() error {
+			_, e := v.handler.StopHunt(addr)
+			return e
+		}, &err)
+	}
+	return err
+}
+
+// call runs f and recovers from a panic so a faulty handler does not stop the dispatcher.
+// The error is stored in *first if no error was stored before.
+func (r *registeredHandler) call(op string, f func() error, first *error) {
+	defer func() {
+		if p := recover(); p != nil {
+			n := atomic.AddUint64(&r.panics, 1)
+			Logger.Msg("handler panic").String("handler", fmt.Sprintf("%T", r.handler)).String("op", op).
+				Int("count", int(n)).Sprintf("panic", p).String("stack", string(debug.Stack())).Write()
+			if *first == nil {
+				*first = fmt.Errorf("handler %T panic in %s: %v", r.handler, op, p)
+			}
+		}
+	}()
+	if err := f(); err != nil {
+		if Logger.IsDebug() {
+			Logger.Msg("handler error").String("handler", fmt.Sprintf("%T", r.handler)).String("op", op).Error(err).Write()
+		}
+		if *first == nil {
+			*first = err
+		}
+	}
+}
+
+// dispatch calls the matching handlers and then Notify.
+func (h *Session) dispatch(frame Frame) {
+	var err error
+	for _, v := range h.dispatcher.list() {
+		if v.filter.match(frame) {
+			v.call("ProcessPacket", func() error { return v.handler.ProcessPacket(frame) }, &err)
+		}
+	}
+	h.Notify(frame)
+}
+
+// Run reads frames from the session, dispatches them to the registered handlers and calls
+// Notify, replacing the read loop every program used to write. Run also calls MinuteTicker
+// on the registered handlers every minute.
+//
+// Frames from the same source mac are always processed in order. If Config.Workers is greater
+// than zero, frames are processed by that many goroutines; otherwise frames are processed in
+// the read goroutine. Packets sent by us are ignored.
+//
+// Run returns ctx.Err() when ctx is cancelled or ErrHandlerClosed when the session is closed.
+func (h *Session) Run(ctx context.Context) error {
+	// unblock ReadFrom when the context is cancelled; reset the deadline when we return
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			h.Conn.SetReadDeadline(time.Now())
+		case <-h.ctx.Done():
+			h.Conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+		h.Conn.SetReadDeadline(time.Time{})
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	tickerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.tickerLoop(tickerCtx)
+	}()
+
+	var queues []chan Frame
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+	}()
+	for i := 0; i < h.workers; i++ {
+		q := make(chan Frame, 64)
+		queues = append(queues, q)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.worker(q)
+		}()
+	}
+
+	buffer := make([]byte, EthMaxSize)
+	for {
+		n, _, err := h.Conn.ReadFrom(buffer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if h.ctx.Err() != nil {
+				return ErrHandlerClosed
+			}
+			if err, ok := err.(net.Error); ok && err.Temporary() {
+				continue
+			}
+			return err
+		}
+
+		// Ignore packets sent by us
+		src := SrcMAC(buffer[:n])
+		if src == nil || bytes.Equal(src, h.NICInfo().HostAddr4.MAC) {
+			continue
+		}
+
+		// Parse is not goroutine safe; parse in the read goroutine and dispatch in the workers
+		frame, err := h.Parse(buffer[:n])
+		if err != nil {
+			if Logger.IsDebug() {
+				Logger.Msg("parse error").Error(err).Write()
+			}
+			continue
+		}
+		if len(queues) == 0 {
+			h.dispatch(frame)
+			continue
+		}
+		select {
+		case queues[int(src[len(src)-1])%len(queues)] <- frame:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		buffer = framePool.Get().([]byte) // the frame references the buffer until a worker processes it
+	}
+}
+
+var framePool = sync.Pool{New: func() interface{} { return make([]byte, EthMaxSize) }}
+
+// worker dispatches the frames in q and returns the frame buffers to the pool.
+func (h *Session) worker(q chan Frame) {
+	for frame := range q {
+		h.dispatch(frame)
+		framePool.Put([]byte(frame.ether[:cap(frame.ether)]))
+	}
+}
+
+func (h *Session) tickerLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, v := range h.dispatcher.list() {
+				var err error
+				v.call("MinuteTicker", func() error { return v.handler.MinuteTicker(now) }, &err)
+			}
+		case <-ctx.Done():
+			return
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
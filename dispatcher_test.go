@@ -0,0 +1,109 @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type testHandler struct {
+	mutex  sync.Mutex
+	frames map[PayloadID]int
+	hunts  int
+	panic  PayloadID // panic when processing this payload
+}
+
+func (h *testHandler) ProcessPacket(frame Frame) error {
+	h.mutex.Lock()
+	if h.frames == nil {
+		h.frames = make(map[PayloadID]int)
+	}
+	h.frames[frame.PayloadID]++
+	h.mutex.Unlock()
+	if frame.PayloadID == h.panic {
+		panic("test panic")
+	}
+	return nil
+}
+
+func (h *testHandler) count(id PayloadID) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.frames[id]
+}
+
+func (h *testHandler) MinuteTicker(now time.Time) error { return nil }
+func (h *testHandler) Close() error                     { return nil }
+
+func (h *testHandler) StartHunt(addr Addr) (HuntStage, error) {
+	h.hunts++
+	return StageHunt, nil
+}
+
+func (h *testHandler) StopHunt(addr Addr) (HuntStage, error) {
+	h.hunts--
+	return StageNormal, errors.New("stop error")
+}
+
+func TestSession_Run(t *testing.T) {
+	for _, workers := range []int{0, 3} {
+		conn, outConn := TestNewBufferedConn()
+		go TestReadAndDiscardLoop(outConn)
+		nicInfo := &NICInfo{HomeLAN4: netip.PrefixFrom(ip1, 24), HostAddr4: Addr{MAC: hostMAC, IP: hostIP4}, RouterAddr4: Addr{MAC: routerMAC, IP: routerIP4}}
+		session, err := Config{Conn: conn, NICInfo: nicInfo, Workers: workers}.NewSession("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		arp := &testHandler{}
+		ip := &testHandler{}
+		all := &testHandler{panic: PayloadARP}
+		session.Register(arp, HandlerFilter{PayloadIDs: []PayloadID{PayloadARP}})
+		session.Register(ip, HandlerFilter{EtherTypes: []uint16{syscall.ETH_P_IP}})
+		session.Register(all, HandlerFilter{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan error, 1)
+		go func() { result <- session.Run(ctx) }()
+
+		// frames written to outConn are read by the session
+		client := outConn
+		for i := 0; i < 10; i++ {
+			client.WriteTo(newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: ip1}, Addr{MAC: EthernetZero, IP: ip2}), nil)
+			client.WriteTo(testUDPFrame(Addr{MAC: mac2, IP: ip2, Port: 1000}, Addr{MAC: mac3, IP: ip3, Port: 2000}, []byte{1, 2, 3}), nil)
+		}
+		client.WriteTo(newARPFrame(ARPOperationRequest, Addr{MAC: hostMAC, IP: hostIP4}, Addr{MAC: EthernetZero, IP: ip2}), nil) // ignored
+
+		for deadline := time.Now().Add(time.Second * 2); time.Now().Before(deadline) && all.count(PayloadUDP) < 10; {
+			time.Sleep(time.Millisecond * 10)
+		}
+		cancel()
+		if err := <-result; !errors.Is(err, context.Canceled) {
+			t.Errorf("workers=%d invalid run result %v", workers, err)
+		}
+		if arp.count(PayloadARP) != 10 || arp.count(PayloadUDP) != 0 {
+			t.Errorf("workers=%d invalid arp handler frames %v", workers, arp.frames)
+		}
+		if ip.count(PayloadUDP) != 10 || ip.count(PayloadARP) != 0 {
+			t.Errorf("workers=%d invalid ip handler frames %v", workers, ip.frames)
+		}
+		if all.count(PayloadARP) != 10 || all.count(PayloadUDP) != 10 {
+			t.Errorf("workers=%d panic must not stop the dispatcher frames %v", workers, all.frames)
+		}
+		if session.FindMACEntry(mac1) == nil {
+			t.Errorf("workers=%d frames not notified", workers)
+		}
+
+		if err := session.StartHunt(Addr{MAC: mac1, IP: ip1}); err != nil || arp.hunts != 1 || all.hunts != 1 {
+			t.Errorf("workers=%d invalid start hunt err=%v", workers, err)
+		}
+		session.Unregister(all)
+		if err := session.StopHunt(Addr{MAC: mac1, IP: ip1}); err == nil || arp.hunts != 0 || all.hunts != 1 {
+			t.Errorf("workers=%d invalid stop hunt err=%v", workers, err)
+		}
+		session.Close()
+	}
+}
@@ -0,0 +1,164 @@
+package packet
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// DefaultSubscriptionSize is the queue length of a subscription if none is given.
+const DefaultSubscriptionSize = 256
+
+// EventFilter returns true if the subscriber wants the event.
+type EventFilter func(Event) bool
+
+// EventTypes returns a filter that accepts the given event types only.
+func EventTypes(types ...EventType) EventFilter {
+	return func(e Event) bool {
+		for _, t := range types {
+			if e.Type == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Subscription is a consumer of session events with its own queue and filter.
+//
+// A slow subscriber only loses its own events: when the queue is full the event is
+// dropped for this subscription and counted in Dropped.
+type Subscription struct {
+	C       <-chan Event // events accepted by the filter; closed on Unsubscribe or session Close
+	c       chan Event
+	filter  EventFilter
+	dropped uint64
+}
+
+// Dropped returns the number of events dropped because the queue was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+type eventBus struct {
+	mutex         sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+	subscribed    atomic.Bool // set on the first Subscribe; the deprecated channels are no longer filled
+}
+
+// Subscribe returns a new subscription to session events. Size is the queue length and
+// defaults to DefaultSubscriptionSize; a nil filter accepts all events.
+//
+// The subscription receives host events (online, offline, new device, ip change, name change,
+// captured and released, router change) and all network events sent to Session.Events.
+// Host events are generated by Notify.
+//
+// Once Subscribe is called, the deprecated Session.C and Session.Events channels are no longer filled.
+func (h *Session) Subscribe(size int, filter EventFilter) *Subscription {
+	if size <= 0 {
+		size = DefaultSubscriptionSize
+	}
+	c := make(chan Event, size)
+	s := &Subscription{C: c, c: c, filter: filter}
+	h.bus.mutex.Lock()
+	defer h.bus.mutex.Unlock()
+	if h.bus.closed {
+		close(c)
+		return s
+	}
+	if h.bus.subscriptions == nil {
+		h.bus.subscriptions = make(map[*Subscription]struct{})
+	}
+	h.bus.subscribed.Store(true)
+	h.bus.subscriptions[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe removes the subscription and closes its channel.
+func (h *Session) Unsubscribe(s *Subscription) {
+	h.bus.mutex.Lock()
+	defer h.bus.mutex.Unlock()
+	if _, found := h.bus.subscriptions[s]; found {
+		delete(h.bus.subscriptions, s)
+		close(s.c)
+	}
+}
+
+// publish sends the event to all subscriptions without blocking.
+func (b *eventBus) publish(e Event) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for s := range b.subscriptions {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			if atomic.AddUint64(&s.dropped, 1) == 1 {
+				Logger.Msg("subscription queue is full").Int("len", len(s.c)).Struct(e).Write()
+			}
+		}
+	}
+}
+
+// close closes all subscriptions; further subscriptions are closed on creation.
+func (b *eventBus) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.closed = true
+	for s := range b.subscriptions {
+		close(s.c)
+	}
+	b.subscriptions = nil
+}
+
+// publishHost sends a host event to subscribers only; host events are not sent to Session.Events.
+func (h *Session) publishHost(t EventType, addr Addr, data fastlog.FastLog) {
+	e := Event{Type: t, Time: time.Now(), Addr: addr, Data: data}
+	if Logger.IsDebug() {
+		Logger.Msg("event").Struct(e).Write()
+	}
+	h.bus.publish(e)
+}
+
+// IPChangeEvent is the Data of EventIPChanged events.
+type IPChangeEvent struct {
+	Addr     Addr // mac and new ip
+	Previous Addr // mac and previous ip
+}
+
+func (e IPChangeEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Struct(e.Addr)
+	l.IP("previous", e.Previous.IP)
+	return l
+}
+
+// CaptureEvent is the Data of EventCaptured and EventReleased events.
+type CaptureEvent struct {
+	MAC      net.HardwareAddr
+	Captured bool
+}
+
+func (e CaptureEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.MAC("mac", e.MAC)
+	l.Bool("captured", e.Captured)
+	return l
+}
+
+// RouterChangeEvent is the Data of EventRouterChanged events.
+type RouterChangeEvent struct {
+	Router   Addr
+	Previous Addr
+}
+
+func (e RouterChangeEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Struct(e.Router)
+	l.MAC("previousMAC", e.Previous.MAC)
+	l.IP("previousIP", e.Previous.IP)
+	return l
+}
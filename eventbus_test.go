@@ -0,0 +1,97 @@
+package packet
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// drainEvents returns the event types queued in the subscription.
+func drainEvents(s *Subscription) (list []EventType) {
+	for len(s.C) > 0 {
+		list = append(list, (<-s.C).Type)
+	}
+	return list
+}
+
+func TestSession_Subscribe(t *testing.T) {
+	session, _ := testSession()
+	all := session.Subscribe(0, nil)
+	online := session.Subscribe(0, EventTypes(EventHostOnline, EventHostOffline))
+	slow := session.Subscribe(1, nil)
+	ip4 := netip.MustParseAddr("192.168.0.4")
+
+	tests := []struct {
+		name   string
+		action func()
+		all    []EventType
+		online []EventType
+	}{
+		{name: "new host", action: func() { session.Notify(newTestHost(session, Addr{MAC: mac1, IP: ip1})) },
+			all: []EventType{EventNewDevice, EventHostOnline}, online: []EventType{EventHostOnline}},
+		{name: "same host", action: func() { session.Notify(newTestHost(session, Addr{MAC: mac1, IP: ip1})) }},
+		{name: "name", action: func() {
+			frame := newTestHost(session, Addr{MAC: mac1, IP: ip1})
+			frame.Host.UpdateMDNSName(NameEntry{Type: "mdns", Name: "laptop"})
+			session.Notify(frame)
+		}, all: []EventType{EventNameChanged}},
+		{name: "ip change", action: func() { session.Notify(newTestHost(session, Addr{MAC: mac1, IP: ip4})) },
+			all: []EventType{EventIPChanged, EventHostOffline, EventHostOnline}, online: []EventType{EventHostOffline, EventHostOnline}},
+		{name: "capture", action: func() { session.Capture(mac1) }, all: []EventType{EventCaptured}},
+		{name: "capture again", action: func() { session.Capture(mac1) }},
+		{name: "release", action: func() { session.Release(mac1) }, all: []EventType{EventReleased}},
+		{name: "release again", action: func() { session.Release(mac1) }},
+		{name: "router", action: func() { session.SetRouter(Addr{MAC: mac2, IP: ip2}) }, all: []EventType{EventRouterChanged}},
+		{name: "conflict", action: func() { session.sendEvent(Event{Type: EventIPConflict, Addr: Addr{MAC: mac1, IP: ip1}, Data: IPConflictEvent{}}) },
+			all: []EventType{EventIPConflict}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.action()
+			if got := drainEvents(all); !equalEventTypes(got, tt.all) {
+				t.Errorf("invalid events want=%v got=%v", tt.all, got)
+			}
+			if got := drainEvents(online); !equalEventTypes(got, tt.online) {
+				t.Errorf("invalid filtered events want=%v got=%v", tt.online, got)
+			}
+		})
+	}
+
+	// the slow subscriber lost events but the other subscribers did not
+	if len(slow.C) != 1 || slow.Dropped() == 0 {
+		t.Errorf("invalid slow subscriber len=%d dropped=%d", len(slow.C), slow.Dropped())
+	}
+	if all.Dropped() != 0 || online.Dropped() != 0 {
+		t.Errorf("unexpected dropped events all=%d online=%d", all.Dropped(), online.Dropped())
+	}
+	if len(session.Events) != 0 || len(session.C) != 0 {
+		t.Errorf("deprecated channels filled with subscribers events=%d notifications=%d", len(session.Events), len(session.C))
+	}
+	if !session.MACTable.Table[len(session.MACTable.Table)-1].IsRouter || session.NICInfo().RouterAddr4.IP != ip2 {
+		t.Error("router not changed")
+	}
+
+	session.Unsubscribe(online)
+	if _, ok := <-online.C; ok {
+		t.Error("channel not closed on unsubscribe")
+	}
+	session.Unsubscribe(online) // must not panic
+	session.Close()
+	if _, ok := <-all.C; ok {
+		t.Error("channel not closed on session close")
+	}
+	if _, ok := <-session.Subscribe(0, nil).C; ok {
+		t.Error("subscription after close must be closed")
+	}
+}
+
+func equalEventTypes(a []EventType, b []EventType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
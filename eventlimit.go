@@ -0,0 +1,38 @@
+package packet
+
+import "time"
+
+// eventLimiter sends at most one event per key each minute. Detectors use a comparable
+// struct key with the event type and the address the event is about.
+//
+// eventLimiter is not goroutine safe; the caller must hold the lock of the state that owns it.
+// The zero value is ready to use.
+type eventLimiter[K comparable] struct {
+	last map[K]time.Time
+}
+
+// allow returns true and records now if no event was allowed for key in the last minute.
+func (l *eventLimiter[K]) allow(key K, now time.Time) bool {
+	if last, found := l.last[key]; found && now.Sub(last) < time.Minute {
+		return false
+	}
+	if l.last == nil {
+		l.last = make(map[K]time.Time)
+	}
+	l.last[key] = now
+	return true
+}
+
+// purge deletes keys last allowed more than a minute ago.
+func (l *eventLimiter[K]) purge(now time.Time) {
+	for k, v := range l.last {
+		if now.Sub(v) >= time.Minute {
+			delete(l.last, k)
+		}
+	}
+}
+
+// len returns the number of keys.
+func (l *eventLimiter[K]) len() int {
+	return len(l.last)
+}
@@ -0,0 +1,44 @@
+package packet
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_eventLimiter(t *testing.T) {
+	type key struct {
+		event EventType
+		id    int
+	}
+	var l eventLimiter[key]
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		key  key
+		now  time.Time
+		want bool
+	}{
+		{name: "first", key: key{EventIPConflict, 1}, now: now, want: true},
+		{name: "same key", key: key{EventIPConflict, 1}, now: now.Add(time.Second * 59), want: false},
+		{name: "other id", key: key{EventIPConflict, 2}, now: now, want: true},
+		{name: "other event", key: key{EventMACFlapping, 1}, now: now, want: true},
+		{name: "after a minute", key: key{EventIPConflict, 1}, now: now.Add(time.Minute), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.allow(tt.key, tt.now); got != tt.want {
+				t.Errorf("allow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	l.purge(now.Add(time.Minute))
+	if n := l.len(); n != 1 {
+		t.Errorf("invalid len after purge want=1 got=%d", n)
+	}
+	l.purge(now.Add(time.Minute * 2))
+	if n := l.len(); n != 0 {
+		t.Errorf("invalid len after purge want=0 got=%d", n)
+	}
+}
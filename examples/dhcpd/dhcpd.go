@@ -67,7 +67,7 @@ func main() {
 			}
 
 			// Ignore packets sent by us
-			if bytes.Equal(packet.SrcMAC(buffer[:n]), s.NICInfo.HostAddr4.MAC) {
+			if bytes.Equal(packet.SrcMAC(buffer[:n]), s.NICInfo().HostAddr4.MAC) {
 				continue
 			}
 
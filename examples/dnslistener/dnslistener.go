@@ -79,6 +79,25 @@ func main() {
 					fmt.Println("error processing arp packet", err)
 				}
 
+			case packet.PayloadLLMNR:
+				name, err := dnshandler.ProcessLLMNR(frame.Host, frame.Ether(), frame.Payload())
+				if err != nil {
+					fmt.Println("error processing llmnr packet", err)
+					continue
+				}
+				if frame.Host != nil && name.Name != "" {
+					frame.Host.UpdateLLMNRName(name)
+				}
+
+			case packet.PayloadWSDP:
+				name, _, err := dnshandler.ProcessWSD(frame.Host, frame.Ether(), frame.Payload())
+				if err != nil {
+					fmt.Println("error processing wsd packet", err)
+					continue
+				}
+				if frame.Host != nil && (name.Name != "" || name.Model != "") {
+					frame.Host.UpdateWSDName(name)
+				}
 			}
 		}
 	}()
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/deeGraYve/packet"
 )
@@ -24,32 +28,20 @@ func main() {
 	}
 	defer s.Close()
 
+	sub := s.Subscribe(0, packet.EventTypes(packet.EventHostOnline, packet.EventHostOffline, packet.EventIPChanged, packet.EventNameChanged))
 	go func() {
-		for {
-			notification := <-s.C
-			switch notification.Online {
-			case true:
-				fmt.Printf("is online: %s\n", notification)
-			default:
-				fmt.Printf("is offline: %s\n", notification)
+		for e := range sub.C {
+			fmt.Println(e)
+			if e.Type == packet.EventHostOnline || e.Type == packet.EventHostOffline {
+				s.PrintTable()
 			}
-			s.PrintTable()
 		}
 	}()
 
-	buffer := make([]byte, packet.EthMaxSize)
-	for {
-		n, _, err := s.ReadFrom(buffer)
-		if err != nil {
-			fmt.Println("error reading packet", err)
-			return
-		}
-
-		frame, err := s.Parse(buffer[:n])
-		if err != nil {
-			fmt.Println("parse error", err)
-			continue
-		}
-		s.Notify(frame)
+	// read and parse packets until ctrl-C; Run calls Notify for every frame
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := s.Run(ctx); err != nil && err != context.Canceled {
+		fmt.Println("error reading packet", err)
 	}
 }
@@ -1,6 +1,6 @@
 module github.com/deeGraYve/packet
 
-go 1.18
+go 1.19
 
 require (
 	github.com/mdlayher/netx v0.0.0-20200512211805-669a06fde734
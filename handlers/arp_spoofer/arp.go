@@ -3,6 +3,7 @@ package arp_spoofer
 
 import (
 	"bytes"
+	"context"
 	"net"
 	"net/netip"
 	"sync"
@@ -21,6 +22,8 @@ const (
 	probe
 )
 
+var _ packet.Handler = &Handler{}
+
 // Handler stores instance variables
 type Handler struct {
 	arpMutex      sync.RWMutex
@@ -46,11 +49,11 @@ func New(session *packet.Session) (h *Handler, err error) {
 
 func (config Config) New(session *packet.Session) (h *Handler, err error) {
 	h = &Handler{session: session, huntList: make(map[string]packet.Addr, 6), closeChan: make(chan bool)}
-	if !h.session.NICInfo.HostAddr4.IP.Is4() {
+	if !h.session.NICInfo().HostAddr4.IP.Is4() {
 		return nil, packet.ErrInvalidIP
 	}
 
-	if !h.session.NICInfo.HomeLAN4.Addr().Is4() || h.session.NICInfo.HomeLAN4.Addr().IsUnspecified() {
+	if !h.session.NICInfo().HomeLAN4.Addr().Is4() || h.session.NICInfo().HomeLAN4.Addr().IsUnspecified() {
 		return nil, packet.ErrInvalidIP
 	}
 	h.probeInterval = config.ProbeInterval
@@ -68,6 +71,11 @@ func (h *Handler) Close() error {
 	return nil
 }
 
+// MinuteTicker implements packet.Handler; the arp handler has no periodic work.
+func (h *Handler) MinuteTicker(now time.Time) error {
+	return nil
+}
+
 // PrintTable print the ARP table to stdout.
 func (h *Handler) PrintTable() {
 	h.arpMutex.Lock()
@@ -84,9 +92,9 @@ func (h *Handler) RequestTo(dst net.HardwareAddr, targetIP netip.Addr) error {
 		return packet.ErrInvalidIP
 	}
 	if Logger.IsDebug() {
-		Logger.Msg("send request - who is").IP("ip", targetIP).IP("tell", h.session.NICInfo.HostAddr4.IP).MAC("dst", dst).Write()
+		Logger.Msg("send request - who is").IP("ip", targetIP).IP("tell", h.session.NICInfo().HostAddr4.IP).MAC("dst", dst).Write()
 	}
-	return h.RequestRaw(dst, h.session.NICInfo.HostAddr4, packet.Addr{MAC: packet.EthernetBroadcast, IP: targetIP})
+	return h.RequestRaw(dst, h.session.NICInfo().HostAddr4, packet.Addr{MAC: packet.EthernetBroadcast, IP: targetIP})
 }
 
 // Request send a broadcast ARP request from host to targetIP
@@ -95,9 +103,9 @@ func (h *Handler) Request(targetIP netip.Addr) error {
 		return packet.ErrInvalidIP
 	}
 	if Logger.IsDebug() {
-		Logger.Msg("send request - who is").IP("ip", targetIP).IP("tell", h.session.NICInfo.HostAddr4.IP).Write()
+		Logger.Msg("send request - who is").IP("ip", targetIP).IP("tell", h.session.NICInfo().HostAddr4.IP).Write()
 	}
-	return h.RequestRaw(packet.EthernetBroadcast, h.session.NICInfo.HostAddr4, packet.Addr{MAC: packet.EthernetBroadcast, IP: targetIP})
+	return h.RequestRaw(packet.EthernetBroadcast, h.session.NICInfo().HostAddr4, packet.Addr{MAC: packet.EthernetBroadcast, IP: targetIP})
 }
 
 // Probe send an arp probe broadcast on the local link.
@@ -110,7 +118,7 @@ func (h *Handler) Request(targetIP netip.Addr) error {
 // An ARP Probe conveys both a question ("Is anyone using this address?") and an
 // implied statement ("This is the address I hope to use.").
 func (h *Handler) Probe(ip netip.Addr) error {
-	return h.RequestRaw(packet.EthernetBroadcast, packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: packet.IPv4zero}, packet.Addr{MAC: packet.EthernetZero, IP: ip})
+	return h.RequestRaw(packet.EthernetBroadcast, packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: packet.IPv4zero}, packet.Addr{MAC: packet.EthernetZero, IP: ip})
 }
 
 // AnnounceTo send an arp announcement on the local link.
@@ -136,7 +144,7 @@ func (h *Handler) AnnounceTo(dst net.HardwareAddr, targetIP netip.Addr) (err err
 		}
 	}
 	err = h.RequestRaw(dst,
-		packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: targetIP},
+		packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: targetIP},
 		packet.Addr{MAC: packet.EthernetBroadcast, IP: targetIP})
 	return err
 }
@@ -166,7 +174,7 @@ func (h *Handler) RequestRaw(dst net.HardwareAddr, sender packet.Addr, target pa
 	ether := packet.Ether(b[0:])
 
 	// Send packet with ether src set to host but arp packet set to target
-	ether = packet.EncodeEther(ether, syscall.ETH_P_ARP, h.session.NICInfo.HostAddr4.MAC, dst)
+	ether = packet.EncodeEther(ether, syscall.ETH_P_ARP, h.session.NICInfo().HostAddr4.MAC, dst)
 	arp := packet.EncodeARP(ether.Payload(), packet.ARPOperationRequest, sender, target)
 	if ether, err = ether.SetPayload(arp); err != nil {
 		return err
@@ -196,7 +204,7 @@ func (h *Handler) reply(dst net.HardwareAddr, sender packet.Addr, target packet.
 	ether := packet.Ether(b[0:])
 
 	// Send packet with ether src set to host but arp packet set to target
-	ether = packet.EncodeEther(ether, syscall.ETH_P_ARP, h.session.NICInfo.HostAddr4.MAC, dst)
+	ether = packet.EncodeEther(ether, syscall.ETH_P_ARP, h.session.NICInfo().HostAddr4.MAC, dst)
 	arp := packet.EncodeARP(ether.Payload(), packet.ARPOperationReply, sender, target)
 	if ether, err = ether.SetPayload(arp); err != nil {
 		return err
@@ -226,38 +234,25 @@ func (h *Handler) WhoIs(ip netip.Addr) (packet.Addr, error) {
 	return packet.Addr{}, packet.ErrNotFound
 }
 
-// ScanNetwork sends 256 arp requests to identify IPs on the lan
+// Scan sends an arp request to every IP on the lan and returns when all requests are sent.
+// Responses are processed by the session as usual; use packet.Session.Scan to stream the results.
 func (h *Handler) Scan() error {
-	ip := h.session.NICInfo.HomeLAN4.Addr()
-	n := (uint32(0xffffffff) << uint32(h.session.NICInfo.HomeLAN4.Bits())) >> h.session.NICInfo.HomeLAN4.Bits()
-	for host := uint32(1); host < n; host++ {
-		ip = ip.Next()
-
-		// Don't scan router and host
-		if ip == h.session.NICInfo.RouterAddr4.IP || ip == h.session.NICInfo.HostAddr4.IP {
-			continue
-		}
-
-		if h.closed { // return if Close() is called when we are in the loop
-			return nil
-		}
-		err := h.Request(ip)
-		if err != nil {
-			if err1, ok := err.(net.Error); ok && err1.Temporary() {
-				if Logger.IsDebug() {
-					Logger.Msg("error in write socket is temporary - retry ").Error(err1).Write()
-				}
-				continue
-			}
-
-			if Logger.IsDebug() {
-				Logger.Msg("arp request error").Error(err).Write()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := h.session.Scan(ctx, packet.ScanOptions{IPv4: true, Rate: 125, Wait: time.Millisecond})
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case _, ok := <-c:
+			if !ok {
+				return nil
 			}
-			return err
+		case <-h.closeChan: // return if Close() is called when we are in the loop
+			return nil
 		}
-		time.Sleep(time.Millisecond * 8)
 	}
-	return nil
 }
 
 // ProcessPacket process an ARP packet
@@ -326,11 +321,11 @@ func (h *Handler) ProcessPacket(frame packet.Frame) error {
 		h.arpMutex.Lock()
 		_, hunting := h.huntList[string(arpFrame.SrcMAC())]
 		h.arpMutex.Unlock()
-		if hunting && arpFrame.DstIP() == h.session.NICInfo.RouterAddr4.IP {
+		if hunting && arpFrame.DstIP() == h.session.NICInfo().RouterAddr4.IP {
 			if Logger.IsDebug() {
 				Logger.Msg("router spoofing - send reply I am").IP("ip", arpFrame.DstIP()).MAC("dstmac", arpFrame.SrcMAC()).Write()
 			}
-			if err := h.Reply(arpFrame.SrcMAC(), packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: arpFrame.DstIP()}, packet.Addr{MAC: arpFrame.SrcMAC(), IP: arpFrame.SrcIP()}); err != nil {
+			if err := h.Reply(arpFrame.SrcMAC(), packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: arpFrame.DstIP()}, packet.Addr{MAC: arpFrame.SrcMAC(), IP: arpFrame.SrcIP()}); err != nil {
 				Logger.Msg("failed to send spoofing reply").MAC("mac", arpFrame.SrcMAC()).Error(err).Write()
 			}
 			return nil
@@ -354,10 +349,10 @@ func (h *Handler) ProcessPacket(frame packet.Frame) error {
 			// Note: detected one situation where android probed external DNS IP. Not sure if this occur in other clients.
 			//       to avoid issues, check DstIP is in the local subnet.
 			//       arp  : probe reject for ip=8.8.8.8 from mac=84:11:9e:03:89:c0 (android phone) - 10 March 2021
-			if h.session.NICInfo.HomeLAN4.Contains(arpFrame.DstIP()) {
+			if h.session.NICInfo().HomeLAN4.Contains(arpFrame.DstIP()) {
 				Logger.Msg("probe reject for").IP("ip", arpFrame.DstIP()).MAC("fromMAC", arpFrame.SrcMAC()).IP("offer", offer).Write()
 				// unicast reply to srcMAC
-				h.Reply(arpFrame.SrcMAC(), packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: arpFrame.DstIP()}, packet.Addr{MAC: arpFrame.SrcMAC(), IP: packet.IP4Broadcast})
+				h.Reply(arpFrame.SrcMAC(), packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: arpFrame.DstIP()}, packet.Addr{MAC: arpFrame.SrcMAC(), IP: packet.IP4Broadcast})
 			}
 		}
 		return nil
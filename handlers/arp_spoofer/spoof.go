@@ -13,6 +13,17 @@ func (h *Handler) IsHunting(ip netip.Addr) bool {
 	return b
 }
 
+// HuntList returns a copy of the addresses being hunted.
+func (h *Handler) HuntList() []packet.Addr {
+	h.arpMutex.RLock()
+	defer h.arpMutex.RUnlock()
+	list := make([]packet.Addr, 0, len(h.huntList))
+	for _, v := range h.huntList {
+		list = append(list, v)
+	}
+	return list
+}
+
 func (h *Handler) findHuntByIP(ip netip.Addr) (packet.Addr, bool) {
 	for _, v := range h.huntList {
 		if v.IP == ip {
@@ -89,7 +100,7 @@ func (h *Handler) spoofLoop(addr packet.Addr) {
 			// When hunt terminate normally, clear the arp table with announcement to real router mac.
 			if !h.closed {
 				// request will fix the ether src mac to host to prevent ethernet port disabling
-				if err := h.RequestRaw(addr.MAC, h.session.NICInfo.RouterAddr4, h.session.NICInfo.RouterAddr4); err != nil {
+				if err := h.RequestRaw(addr.MAC, h.session.NICInfo().RouterAddr4, h.session.NICInfo().RouterAddr4); err != nil {
 					Logger.Msg("error send request packet").Struct(addr).Error(err).Write()
 				}
 			}
@@ -100,7 +111,7 @@ func (h *Handler) spoofLoop(addr packet.Addr) {
 		//
 		// Announce to target that we own the router IP; This will update the target arp table with our mac
 		// i.e. tell target I am 192.168.0.1
-		err := h.AnnounceTo(targetAddr.MAC, h.session.NICInfo.RouterAddr4.IP)
+		err := h.AnnounceTo(targetAddr.MAC, h.session.NICInfo().RouterAddr4.IP)
 		if err != nil {
 			Logger.Msg("error send announcement packet").Struct(targetAddr).Error(err).Write()
 			return
@@ -106,8 +106,8 @@ func (h *Handler) sendDeclineReleasePacket(msgType packet.DHCP4MessageType, clie
 	xid = mustXID(xid)
 	p := packet.EncodeDHCP4(b[0:], packet.DHCP4BootRequest, msgType, chAddr, ciAddr, packet.IPv4zero, xid, false, options, nil)
 
-	srcAddr := packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: h.session.NICInfo.HostAddr4.IP, Port: packet.DHCP4ClientPort}
-	dstAddr := packet.Addr{MAC: h.session.NICInfo.RouterAddr4.MAC, IP: h.session.NICInfo.RouterAddr4.IP, Port: packet.DHCP4ServerPort}
+	srcAddr := packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: h.session.NICInfo().HostAddr4.IP, Port: packet.DHCP4ClientPort}
+	dstAddr := packet.Addr{MAC: h.session.NICInfo().RouterAddr4.MAC, IP: h.session.NICInfo().RouterAddr4.IP, Port: packet.DHCP4ServerPort}
 	err = sendDHCP4Packet(h.session.Conn, srcAddr, dstAddr, p)
 	return err
 }
@@ -128,8 +128,8 @@ func (h *Handler) SendDiscoverPacket(chAddr net.HardwareAddr, ciAddr netip.Addr,
 		byte(packet.DHCP4OptionDomainNameServer), byte(packet.DHCP4OptionDomainName),
 	}
 
-	srcAddr := packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: h.session.NICInfo.HostAddr4.IP, Port: packet.DHCP4ClientPort}
-	dstAddr := packet.Addr{MAC: h.session.NICInfo.RouterAddr4.MAC, IP: h.session.NICInfo.RouterAddr4.IP, Port: packet.DHCP4ServerPort}
+	srcAddr := packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: h.session.NICInfo().HostAddr4.IP, Port: packet.DHCP4ClientPort}
+	dstAddr := packet.Addr{MAC: h.session.NICInfo().RouterAddr4.MAC, IP: h.session.NICInfo().RouterAddr4.IP, Port: packet.DHCP4ServerPort}
 
 	b := packet.EtherBufferPool.Get().(*[packet.EthMaxSize]byte)
 	defer packet.EtherBufferPool.Put(b)
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
-	"os"
 	"testing"
 	"time"
 
@@ -15,8 +14,7 @@ import (
 func Test_declineSimple(t *testing.T) {
 	packet.Logger.SetLevel(fastlog.LevelError)
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	srcAddr := packet.Addr{MAC: mac5, IP: packet.IPv4zero, Port: packet.DHCP4ClientPort}
@@ -41,8 +39,7 @@ func Test_declineSimple(t *testing.T) {
 }
 func Test_DeclineFromAnotherServer(t *testing.T) {
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	tc.IPOffer = netip.Addr{}
@@ -57,6 +57,8 @@ type Config struct {
 	LeaseFilename string
 }
 
+var _ packet.Handler = &Handler{}
+
 // Handler is the main dhcp4 handler
 type Handler struct {
 	session   *packet.Session   // engine handler
@@ -72,8 +74,8 @@ type Handler struct {
 
 // New returns a dhcp handler with two internal subnets.
 func New(session *packet.Session) (handler *Handler, err error) {
-	config := Config{Mode: ModeSecondaryServer, DNSServer: session.NICInfo.RouterAddr4.IP,
-		NetfilterIP: netip.PrefixFrom(session.NICInfo.HostAddr4.IP, session.NICInfo.HomeLAN4.Bits()), LeaseFilename: LeaseFilename}
+	config := Config{Mode: ModeSecondaryServer, DNSServer: session.NICInfo().RouterAddr4.IP,
+		NetfilterIP: netip.PrefixFrom(session.NICInfo().HostAddr4.IP, session.NICInfo().HomeLAN4.Bits()), LeaseFilename: LeaseFilename}
 	return config.New(session)
 }
 
@@ -92,8 +94,8 @@ func (config Config) New(session *packet.Session) (h *Handler, err error) {
 	if !config.NetfilterIP.IsValid() {
 		return nil, fmt.Errorf("netfilter prefix NetfilterIP=%s is invalid: %w", config.NetfilterIP, packet.ErrInvalidIP)
 	}
-	if !session.NICInfo.HomeLAN4.Contains(config.NetfilterIP.Addr()) {
-		return nil, fmt.Errorf("netfilter ip=%s does not exist in home net=%s: %w", config.NetfilterIP, session.NICInfo.HomeLAN4, packet.ErrInvalidIP)
+	if !session.NICInfo().HomeLAN4.Contains(config.NetfilterIP.Addr()) {
+		return nil, fmt.Errorf("netfilter ip=%s does not exist in home net=%s: %w", config.NetfilterIP, session.NICInfo().HomeLAN4, packet.ErrInvalidIP)
 	}
 
 	// validate mode - default to SecondaryServerNice
@@ -104,14 +106,14 @@ func (config Config) New(session *packet.Session) (h *Handler, err error) {
 
 	// validate dns server : set default to router IP
 	if !config.DNSServer.IsValid() {
-		config.DNSServer = session.NICInfo.RouterAddr4.IP
+		config.DNSServer = session.NICInfo().RouterAddr4.IP
 	}
 
 	// Segment network - home subnet includes the whole home LAN
 	homeSubnet := SubnetConfig{
-		LAN:        session.NICInfo.HomeLAN4,
-		DefaultGW:  session.NICInfo.RouterAddr4.IP,
-		DHCPServer: session.NICInfo.HostAddr4.IP,
+		LAN:        session.NICInfo().HomeLAN4,
+		DefaultGW:  session.NICInfo().RouterAddr4.IP,
+		DHCPServer: session.NICInfo().HostAddr4.IP,
 		DNSServer:  config.DNSServer,
 		Stage:      packet.StageNormal,
 		// FirstIP:    net.ParseIP("192.168.0.10"),
@@ -120,7 +122,7 @@ func (config Config) New(session *packet.Session) (h *Handler, err error) {
 	netfilterSubnet := SubnetConfig{
 		LAN:        config.NetfilterIP.Masked(),
 		DefaultGW:  config.NetfilterIP.Addr(),
-		DHCPServer: session.NICInfo.HostAddr4.IP,
+		DHCPServer: session.NICInfo().HostAddr4.IP,
 		DNSServer:  packet.DNSv4CloudFlareFamily1,
 		Stage:      packet.StageRedirected,
 		// FirstIP:    net.ParseIP("192.168.0.10"),
@@ -210,6 +212,17 @@ func (h *Handler) PrintTable() {
 	h.printTable()
 }
 
+// Leases returns a copy of the lease table.
+func (h *Handler) Leases() []Lease {
+	h.Lock()
+	defer h.Unlock()
+	list := make([]Lease, 0, len(h.table))
+	for _, v := range h.table {
+		list = append(list, *v)
+	}
+	return list
+}
+
 func (h *Handler) printTable() {
 	for _, v := range h.table {
 		fmt.Printf("dhcp4 : %v\n", v)
@@ -217,7 +230,7 @@ func (h *Handler) printTable() {
 }
 
 // StartHunt will start the process to capture the client DHCP negotiation
-func (h *Handler) StartHunt(addr packet.Addr) error {
+func (h *Handler) StartHunt(addr packet.Addr) (packet.HuntStage, error) {
 	if Logger.IsInfo() {
 		Logger.Msg("start hunt").Struct(addr).Write()
 	}
@@ -231,15 +244,15 @@ func (h *Handler) StartHunt(addr packet.Addr) error {
 			h.forceRelease(lease.ClientID, h.net1.DefaultGW, lease.Addr.MAC, lease.Addr.IP, nil)
 		}
 	}
-	return nil
+	return packet.StageHunt, nil
 }
 
 // StopHunt will end the capture process
-func (h *Handler) StopHunt(addr packet.Addr) error {
+func (h *Handler) StopHunt(addr packet.Addr) (packet.HuntStage, error) {
 	if Logger.IsInfo() {
 		Logger.Msg("stop hunt").Struct(addr).Write()
 	}
-	return nil
+	return packet.StageNormal, nil
 }
 
 // ProcessPacket handles a DHCP4 packet performing DHCP4 spoofing and
@@ -309,7 +322,7 @@ func (h *Handler) ProcessPacket(frame packet.Frame) error {
 		if Logger.IsDebug() {
 			Logger.Msg("send reply to").Struct(dstAddr).Struct(response).Write()
 		}
-		srcAddr := packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: h.session.NICInfo.HostAddr4.IP, Port: packet.DHCP4ServerPort}
+		srcAddr := packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: h.session.NICInfo().HostAddr4.IP, Port: packet.DHCP4ServerPort}
 		if err := sendDHCP4Packet(h.session.Conn, srcAddr, dstAddr, response); err != nil {
 			Logger.Msg("send packet failed").Error(err).Write()
 			return err
@@ -5,7 +5,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/netip"
-	"os"
 	"testing"
 	"time"
 
@@ -14,13 +13,13 @@ import (
 )
 
 func TestAttach(t *testing.T) {
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 
 	if err := tc.h.Close(); err != nil {
 		panic(err)
 	}
 
-	tc2 := setupTestHandler()
+	tc2 := setupTestHandler(t)
 	if err := tc2.h.Close(); err != nil {
 		panic(err)
 	}
@@ -228,8 +227,7 @@ func TestHandler_handleRequest(t *testing.T) {
 	}
 
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	var offerXID []byte
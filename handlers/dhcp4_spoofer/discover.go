@@ -86,8 +86,8 @@ func (h *Handler) handleDiscover(p packet.DHCP4, options packet.DHCP4Options) (d
 		}
 	}
 
-	if lease.IPOffer == h.session.NICInfo.HostAddr4.IP || lease.IPOffer == h.session.NICInfo.RouterAddr4.IP {
-		fmt.Println(module, "TRACE  ip allocation same as host ip or router ip", lease.IPOffer, h.session.NICInfo.HostAddr4.IP, h.session.NICInfo.RouterAddr4.IP)
+	if lease.IPOffer == h.session.NICInfo().HostAddr4.IP || lease.IPOffer == h.session.NICInfo().RouterAddr4.IP {
+		fmt.Println(module, "TRACE  ip allocation same as host ip or router ip", lease.IPOffer, h.session.NICInfo().HostAddr4.IP, h.session.NICInfo().RouterAddr4.IP)
 	}
 
 	// Client can send another discovery after the entry expiry
@@ -3,7 +3,6 @@ package dhcp4_spoofer
 import (
 	"net"
 	"net/netip"
-	"os"
 	"syscall"
 	"testing"
 	"time"
@@ -22,8 +21,7 @@ func TestDHCPHandler_handleDiscover(t *testing.T) {
 	options[packet.DHCP4OptionCode(packet.DHCP4OptionParameterRequestList)] = []byte{byte(packet.DHCP4OptionDomainNameServer)}
 
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	tests := []struct {
@@ -109,8 +107,7 @@ func TestDHCPHandler_handleDiscoverCaptured(t *testing.T) {
 
 	// packet.DebugIP4 = true
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	t.Run("mac1 normal", func(t *testing.T) {
@@ -153,8 +150,7 @@ func TestDHCPHandler_exhaust(t *testing.T) {
 
 	packet.Logger.SetLevel(fastlog.LevelError)
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	tests := []struct {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
-	"os"
 	"testing"
 	"time"
 
@@ -16,8 +15,7 @@ import (
 func Test_requestSimple(t *testing.T) {
 	packet.Logger.SetLevel(fastlog.LevelDebug)
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	tests := []struct {
@@ -62,8 +60,7 @@ func Test_requestSimple(t *testing.T) {
 func Test_requestCaptured(t *testing.T) {
 	packet.Logger.SetLevel(fastlog.LevelDebug)
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	t.Run("host1", func(t *testing.T) {
@@ -140,8 +137,7 @@ func Test_requestExhaust(t *testing.T) {
 
 	packet.Logger.SetLevel(fastlog.LevelError)
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	exhaustAllIPs(t, tc, mac1)
@@ -171,8 +167,7 @@ func Test_requestExhaust(t *testing.T) {
 
 func Test_requestAnotherHost(t *testing.T) {
 	Logger.SetLevel(fastlog.LevelError)
-	os.Remove(testDHCPFilename)
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	tc.IPOffer = netip.Addr{}
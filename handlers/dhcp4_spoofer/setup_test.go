@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"testing"
@@ -109,7 +110,8 @@ func readResponse(tc *testContext) error {
 	}
 }
 
-func setupTestHandler() *testContext {
+// setupTestHandler creates a handler that saves the leases in a test temporary directory.
+func setupTestHandler(t *testing.T) *testContext {
 
 	var err error
 
@@ -133,13 +135,13 @@ func setupTestHandler() *testContext {
 	tc.session, err = packet.Config{Conn: tc.inConn, NICInfo: nicInfo}.NewSession("")
 
 	if Logger.IsInfo() {
-		fmt.Println("nicinfo: ", tc.session.NICInfo)
+		fmt.Println("nicinfo: ", tc.session.NICInfo())
 	}
 
 	config := Config{
 		NetfilterIP:   netip.PrefixFrom(hostIP4, 25),
 		DNSServer:     dnsIP4,
-		LeaseFilename: testDHCPFilename,
+		LeaseFilename: filepath.Join(t.TempDir(), testDHCPFilename),
 	}
 	tc.h, err = config.New(tc.session)
 	// tc.h, err = Config{ClientConn: tc.clientInConn}.New(tc.session, net.IPNet{IP: hostIP4, Mask: net.IPv4Mask(255, 255, 255, 128)}, dnsIP4, testDHCPFilename)
@@ -7,11 +7,10 @@ import (
 	"fmt"
 	"net"
 	"net/netip"
-	"os"
 	"testing"
 )
 
-const testDHCPFilename = "./testDHCPConfig.yml"
+const testDHCPFilename = "testDHCPConfig.yml" // created in the test temporary directory
 
 var (
 	// test table: add subnet combinations here to test the model
@@ -25,10 +24,8 @@ func Test_Subnet_Save(t *testing.T) {
 	// Debug = true
 	filename := t.TempDir() + "/dhcpconfig.yaml"
 
-	// delete from previous test
-	os.Remove(testDHCPFilename)
 
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 
 	checkLeaseTable(t, tc, 0, 0, 0)
@@ -82,7 +79,7 @@ func Test_Subnet_Save(t *testing.T) {
 
 func Test_Migration(t *testing.T) {
 
-	tc := setupTestHandler()
+	tc := setupTestHandler(t)
 	defer tc.Close()
 	var err error
 
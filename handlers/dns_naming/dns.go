@@ -1,6 +1,6 @@
 // Package dns_naming maps a name from the wire to a mac address.
 // It is useful on a lan to link hosts to known names.
-// It works for mdns, dns, nbns, and ssdp names as these all use standard dns message formats when
+// It works for mdns, dns, llmnr, nbns, and ssdp names as these all use standard dns message formats when
 // replying to queries.
 package dns_naming
 
@@ -22,13 +22,15 @@ var Debug bool
 var Logger = fastlog.New(module)
 
 type DNSHandler struct {
-	session   *packet.Session
-	DNSTable  map[string]packet.DNSEntry // store dns records
-	mutex     sync.RWMutex
-	mconn4    *net.UDPConn
-	mconn6    *net.UDPConn
-	ssdpconn4 *net.UDPConn
-	mdnsCache map[string]cache
+	session    *packet.Session
+	DNSTable   map[string]packet.DNSEntry // store dns records
+	mutex      sync.RWMutex
+	mconn4     *net.UDPConn
+	mconn6     *net.UDPConn
+	ssdpconn4  *net.UDPConn
+	mdnsCache  map[string]cache
+	wsdPending map[string]bool // wsd endpoints with a metadata lookup in progress
+	wsdLookups sync.WaitGroup  // wsd metadata lookups in progress
 }
 
 func New(session *packet.Session) (h *DNSHandler, err error) {
@@ -36,6 +38,7 @@ func New(session *packet.Session) (h *DNSHandler, err error) {
 	h.session = session
 	h.DNSTable = make(map[string]packet.DNSEntry, 256)
 	h.mdnsCache = make(map[string]cache)
+	h.wsdPending = make(map[string]bool)
 
 	// Resgiter for MDNS multicast
 	if h.mconn4, err = net.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: mdnsIPv4Addr.IP.AsSlice(), Port: int(mdnsIPv4Addr.Port)}); err != nil {
@@ -53,6 +56,7 @@ func New(session *packet.Session) (h *DNSHandler, err error) {
 }
 
 func (h *DNSHandler) Close() error {
+	h.wsdLookups.Wait()
 	h.DNSTable = nil
 	h.mdnsCache = nil
 	return nil
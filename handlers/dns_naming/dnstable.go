@@ -17,6 +17,17 @@ func (h *DNSHandler) PrintDNSTable() {
 	}
 }
 
+// DNSEntries returns a copy of the dns table.
+func (h *DNSHandler) DNSEntries() []packet.DNSEntry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]packet.DNSEntry, 0, len(h.DNSTable))
+	for _, v := range h.DNSTable {
+		list = append(list, v.Copy())
+	}
+	return list
+}
+
 func (h *DNSHandler) DNSExist(ip netip.Addr) bool {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
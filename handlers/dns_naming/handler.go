@@ -0,0 +1,69 @@
+package dns_naming
+
+import (
+	"time"
+
+	"github.com/deeGraYve/packet"
+)
+
+var _ packet.Handler = &DNSHandler{}
+
+// ProcessPacket implements packet.Handler. It processes dns, mdns, llmnr, nbns, ssdp and wsd
+// frames and updates the frame host with the name found.
+//
+// SSDP locations are not fetched; call UPNPServiceDiscovery to query the device for more details.
+// WSD Hello messages without a friendly name trigger a metadata lookup in the background.
+func (h *DNSHandler) ProcessPacket(frame packet.Frame) (err error) {
+	var name packet.NameEntry
+	switch frame.PayloadID {
+	case packet.PayloadDNS:
+		_, err = h.ProcessDNS(frame)
+		return err
+	case packet.PayloadMDNS:
+		ipv4, ipv6, err := h.ProcessMDNS(frame)
+		if err != nil || frame.Host == nil {
+			return err
+		}
+		for _, v := range append(ipv4, ipv6...) {
+			if v.Addr.IP == frame.Host.Addr.IP && v.NameEntry.Name != "" {
+				frame.Host.UpdateMDNSName(v.NameEntry)
+				break
+			}
+		}
+		return nil
+	case packet.PayloadLLMNR:
+		if name, err = h.ProcessLLMNR(frame.Host, frame.Ether(), frame.Payload()); err == nil && frame.Host != nil && name.Name != "" {
+			frame.Host.UpdateLLMNRName(name)
+		}
+	case packet.PayloadNBNS:
+		if name, err = h.ProcessNBNS(frame.Host, frame.Ether(), frame.Payload()); err == nil && frame.Host != nil && name.Name != "" {
+			frame.Host.UpdateNBNSName(name)
+		}
+	case packet.PayloadSSDP:
+		if name, _, err = h.ProcessSSDP(frame.Host, frame.Ether(), frame.Payload()); err == nil && frame.Host != nil && name.Name != "" {
+			frame.Host.UpdateSSDPName(name)
+		}
+	case packet.PayloadWSDP:
+		if name, _, err = h.ProcessWSD(frame.Host, frame.Ether(), frame.Payload()); err == nil && frame.Host != nil && (name.Name != "" || name.Model != "") {
+			frame.Host.UpdateWSDName(name)
+		}
+	default:
+		return packet.ErrParseProtocol
+	}
+	return err
+}
+
+// MinuteTicker implements packet.Handler; the dns handler has no periodic work.
+func (h *DNSHandler) MinuteTicker(now time.Time) error {
+	return nil
+}
+
+// StartHunt implements packet.Handler; the dns handler does not hunt.
+func (h *DNSHandler) StartHunt(addr packet.Addr) (packet.HuntStage, error) {
+	return packet.StageNoChange, nil
+}
+
+// StopHunt implements packet.Handler; the dns handler does not hunt.
+func (h *DNSHandler) StopHunt(addr packet.Addr) (packet.HuntStage, error) {
+	return packet.StageNoChange, nil
+}
@@ -0,0 +1,185 @@
+package dns_naming
+
+import (
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/fastlog"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Link Local Multicast Name Resolution
+// https://datatracker.ietf.org/doc/html/rfc4795
+//
+// LLMNR queries are sent to and received on port 5355.  The IPv4 link-
+// scope multicast address a given responder listens to, and to which a
+// sender sends queries, is 224.0.0.252.  The IPv6 link-scope multicast
+// address a given responder listens to, and to which a sender sends all
+// queries, is FF02:0:0:0:0:0:1:3.
+//
+// Windows hosts will query a name on startup to prevent duplicates on the LAN
+// https://docs.microsoft.com/en-us/previous-versions//bb878128(v=technet.10)
+const moduleLLMNR = "llmnr"
+
+var (
+	llmnrLogger = fastlog.New(moduleLLMNR)
+
+	llmnrIPv4Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.AddrFrom4([4]byte{224, 0, 0, 252}), Port: 5355}
+	llmnrIPv6Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.MustParseAddr("FF02:0:0:0:0:0:1:3"), Port: 5355}
+)
+
+// llmnrIgnoreNames lists names queried by most hosts that do not belong to the sender.
+var llmnrIgnoreNames = []string{"wpad", "isatap", "localhost"}
+
+// SendLLMNRQuery send a multicast LLMNR query for the host name
+func (h *DNSHandler) SendLLMNRQuery(name string) (err error) {
+	if !strings.HasSuffix(name, ".") {
+		name = name + "."
+	}
+	return h.sendMDNSQuery(h.session.NICInfo().HostAddr4, llmnrIPv4Addr, dnsmessage.TypeA, name)
+}
+
+// ProcessLLMNR process a LLMNR query or response and returns the name of the sender.
+//
+// LLMNR uses the DNS message format with different flags in the header:
+//
+//	|QR|   Opcode  | C|TC| T| Z| Z| Z| Z|   RCODE   |
+//
+// The C (conflict) and T (tentative) bits are in the same position as the DNS AA and RD bits.
+//
+// A response is authoritative for the name, so the name is returned if the answer address
+// matches the sender address. Windows hosts send a query for their own name when they boot or
+// change address to verify the name is unique; because any host can query any name, a query
+// is only accepted if it is sent from the host own address and no other host claims the name.
+func (h *DNSHandler) ProcessLLMNR(host *packet.Host, ether packet.Ether, payload []byte) (name packet.NameEntry, err error) {
+	var p dnsmessage.Parser
+	dnsHeader, err := p.Start(payload)
+	if err != nil {
+		return name, err
+	}
+	if dnsHeader.OpCode != 0 {
+		return name, packet.ErrParseFrame
+	}
+	var srcIP netip.Addr
+	if ether != nil {
+		srcIP = ether.SrcIP()
+	}
+
+	if !dnsHeader.Response {
+		questions, err := p.AllQuestions()
+		if err != nil {
+			return name, err
+		}
+		// the uniqueness verification query contains a single question for the host name
+		if len(questions) != 1 {
+			return name, nil
+		}
+		q := questions[0]
+		if q.Type != dnsmessage.TypeA && q.Type != dnsmessage.TypeAAAA && q.Type != dnsmessage.TypeALL {
+			return name, nil
+		}
+		if n := llmnrName(q.Name); n != "" && h.llmnrOwnName(host, srcIP, n) {
+			name = packet.NameEntry{Type: moduleLLMNR, Name: n, Expire: time.Now().Add(defaultExpiryTime)}
+		}
+		if llmnrLogger.IsDebug() {
+			llmnrLogger.Msg("query rcvd").IP("ip", srcIP).String("qname", q.Name.String()).Struct(name).Write()
+		}
+		return name, nil
+	}
+
+	if err := p.SkipAllQuestions(); err != nil {
+		return name, err
+	}
+	for {
+		hdr, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return name, err
+		}
+		var addr netip.Addr
+		switch hdr.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return name, err
+			}
+			addr = netip.AddrFrom4(r.A)
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return name, err
+			}
+			addr = netip.AddrFrom16(r.AAAA)
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return name, err
+			}
+			continue
+		}
+		// ignore answers for other hosts
+		if name.Name != "" || (srcIP.IsValid() && addr != srcIP) {
+			continue
+		}
+		if n := llmnrName(hdr.Name); n != "" {
+			name = packet.NameEntry{Type: moduleLLMNR, Name: n, Expire: time.Now().Add(time.Duration(hdr.TTL) * time.Second)}
+			if hdr.TTL == 0 {
+				name.Expire = time.Now().Add(defaultExpiryTime)
+			}
+		}
+	}
+	if llmnrLogger.IsDebug() {
+		llmnrLogger.Msg("response rcvd").IP("ip", srcIP).Bool("conflict", dnsHeader.Authoritative).Struct(name).Write()
+	}
+	return name, nil
+}
+
+// llmnrName returns the host name without the trailing dot or an empty string
+// if the name is not a host name.
+func llmnrName(n dnsmessage.Name) string {
+	name := strings.TrimSuffix(n.String(), ".")
+	if name == "" || strings.HasSuffix(name, ".arpa") {
+		return ""
+	}
+	for _, v := range llmnrIgnoreNames {
+		if strings.EqualFold(name, v) {
+			return ""
+		}
+	}
+	return name
+}
+
+// llmnrOwnName returns true if a query for name n sent from srcIP is the host verifying its own name.
+// The query must come from the host address and no other host may be known by that name.
+func (h *DNSHandler) llmnrOwnName(host *packet.Host, srcIP netip.Addr, n string) bool {
+	if host == nil || (srcIP.IsValid() && srcIP != host.Addr.IP) {
+		return false
+	}
+	if llmnrHostHasName(host, n) {
+		return true
+	}
+	for _, v := range h.session.GetHosts() {
+		if v != host && llmnrHostHasName(v, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// llmnrHostHasName returns true if the host is known by name n.
+func llmnrHostHasName(host *packet.Host, n string) bool {
+	if host == nil {
+		return false
+	}
+	host.MACEntry.Row.RLock()
+	defer host.MACEntry.Row.RUnlock()
+	for _, v := range []string{host.DHCP4Name.Name, host.NBNSName.Name, host.MDNSName.Name, host.LLMNRName.Name} {
+		if v = strings.TrimSuffix(v, ".local"); v != "" && strings.EqualFold(v, n) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package dns_naming
+
+import (
+	"net/netip"
+	"syscall"
+	"testing"
+
+	"github.com/deeGraYve/packet"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func mustPackLLMNR(response bool, qname string, answers ...dnsmessage.Resource) []byte {
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 0x1234, Response: response},
+		Questions: []dnsmessage.Question{{Name: mustNewName(qname), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+		Answers:   answers,
+	}
+	buf, err := msg.Pack()
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+func llmnrAnswer(name string, ip [4]byte) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: mustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 30},
+		Body:   &dnsmessage.AResource{A: ip},
+	}
+}
+
+func testLLMNRFrame(srcIP netip.Addr, payload []byte) packet.Ether {
+	ether := packet.EncodeEther(make([]byte, packet.EthMaxSize), syscall.ETH_P_IP, mac1, llmnrIPv4Addr.MAC)
+	ip4 := packet.EncodeIP4(ether.Payload(), 255, srcIP, llmnrIPv4Addr.IP)
+	udp := packet.EncodeUDP(ip4.Payload(), 5355, 5355)
+	udp, _ = udp.AppendPayload(payload)
+	ip4 = ip4.SetPayload(udp, syscall.IPPROTO_UDP)
+	ether, _ = ether.SetPayload(ip4)
+	return ether
+}
+
+func TestDNSHandler_ProcessLLMNR(t *testing.T) {
+	session, _ := testSession()
+	defer session.Close()
+	dnsHandler, _ := New(session)
+
+	srcIP := netip.AddrFrom4([4]byte{192, 168, 0, 10})
+	windows := &packet.Host{Addr: packet.Addr{IP: srcIP}, MACEntry: &packet.MACEntry{}, NBNSName: packet.NameEntry{Name: "desktop-1abc"}}
+	booting := &packet.Host{Addr: packet.Addr{IP: srcIP}, MACEntry: &packet.MACEntry{}}
+	other := &packet.Host{Addr: packet.Addr{IP: netip.AddrFrom4([4]byte{192, 168, 0, 30})}, MACEntry: &packet.MACEntry{}}
+
+	// printer claims its name in the session
+	printerIP := netip.AddrFrom4([4]byte{192, 168, 0, 20})
+	frame, err := session.Parse(testLLMNRFrame(printerIP, mustPackLLMNR(false, "wpad.")))
+	if err != nil || frame.Host == nil {
+		t.Fatalf("invalid frame %+v err=%v", frame, err)
+	}
+	frame.Host.UpdateNBNSName(packet.NameEntry{Type: "nbns", Name: "printer"})
+
+	tests := []struct {
+		name     string
+		host     *packet.Host
+		payload  []byte
+		wantErr  bool
+		wantName string
+	}{
+		{name: "windows boot query", host: windows, payload: mustPackLLMNR(false, "DESKTOP-1ABC."), wantName: "DESKTOP-1ABC"},
+		{name: "windows boot query without name", host: booting, payload: mustPackLLMNR(false, "DESKTOP-2DEF."), wantName: "DESKTOP-2DEF"},
+		{name: "query name of other host", host: booting, payload: mustPackLLMNR(false, "printer.")},
+		{name: "query from other address", host: other, payload: mustPackLLMNR(false, "DESKTOP-3GHI.")},
+		{name: "query unknown host", payload: mustPackLLMNR(false, "DESKTOP-1ABC.")},
+		{name: "wpad query", host: windows, payload: mustPackLLMNR(false, "wpad.")},
+		{name: "response", payload: mustPackLLMNR(true, "DESKTOP-1ABC.", llmnrAnswer("DESKTOP-1ABC.", srcIP.As4())), wantName: "DESKTOP-1ABC"},
+		{name: "response other host", payload: mustPackLLMNR(true, "printer.", llmnrAnswer("printer.", [4]byte{192, 168, 0, 20}))},
+		{name: "invalid", payload: []byte{0x12, 0x34, 0x00}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := dnsHandler.ProcessLLMNR(tt.host, testLLMNRFrame(srcIP, tt.payload), tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DNSHandler.ProcessLLMNR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name.Name != tt.wantName {
+				t.Errorf("DNSHandler.ProcessLLMNR() invalid name = %+v, want %v", name, tt.wantName)
+			}
+			if name.Name != "" && (name.Type != moduleLLMNR || name.Expire.IsZero()) {
+				t.Errorf("DNSHandler.ProcessLLMNR() invalid entry = %+v", name)
+			}
+		})
+	}
+}
+
+func TestDNSHandler_SendLLMNRQuery(t *testing.T) {
+	session, clientConn := testSession()
+	defer session.Close()
+	dnsHandler, _ := New(session)
+
+	if err := dnsHandler.SendLLMNRQuery("DESKTOP-1ABC"); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	buf := make([]byte, packet.EthMaxSize)
+	n, _, err := clientConn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("unexpected read error", err)
+	}
+	ip4 := packet.IP4(packet.Ether(buf[:n]).Payload())
+	udp := packet.UDP(ip4.Payload())
+	if ip4.Dst() != llmnrIPv4Addr.IP || udp.DstPort() != 5355 {
+		t.Errorf("invalid destination %s:%d", ip4.Dst(), udp.DstPort())
+	}
+	var p dnsmessage.Parser
+	if _, err := p.Start(udp.Payload()); err != nil {
+		t.Fatal("invalid dns message", err)
+	}
+	q, err := p.Question()
+	if err != nil || q.Type != dnsmessage.TypeA || q.Name.String() != "DESKTOP-1ABC." {
+		t.Errorf("invalid question %+v err=%v", q, err)
+	}
+}
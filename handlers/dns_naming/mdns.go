@@ -59,22 +59,6 @@ var (
 	// mDNS IPv4 link-local multicast address 224.0.0.251 (or its IPv6 equivalent FF02::FB).
 	mdnsIPv4Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.AddrFrom4([4]byte{224, 0, 0, 251}), Port: 5353}
 	mdnsIPv6Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.MustParseAddr("ff02::fb"), Port: 5353}
-
-	// TODO: do we need LLMNR? perhaps useful when a new windows machine is pluggedin?
-
-	// Link Local Multicast Name Resolution
-	// https://datatracker.ietf.org/doc/html/rfc4795
-	//
-	// LLMNR queries are sent to and received on port 5355.  The IPv4 link-
-	// scope multicast address a given responder listens to, and to which a
-	// sender sends queries, is 224.0.0.252.  The IPv6 link-scope multicast
-	// address a given responder listens to, and to which a sender sends all
-	// queries, is FF02:0:0:0:0:0:1:3.
-	//
-	// Windows hosts will query a name on startup to prevent duplicates on the LAN
-	// https://docs.microsoft.com/en-us/previous-versions//bb878128(v=technet.10)
-	llmnrIPv4Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.AddrFrom4([4]byte{224, 0, 0, 251}), Port: 5355}
-	llmnrIPv6Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.MustParseAddr("FF02:0:0:0:0:0:1:3"), Port: 5355}
 )
 
 // SendMDNSQuery send a multicast DNS query
@@ -82,12 +66,7 @@ func (h *DNSHandler) SendMDNSQuery(name string) (err error) {
 	// When responding to queries using qtype "ANY" (255) and/or
 	// qclass "ANY" (255), a Multicast DNS responder MUST respond with *ALL*
 	// of its records that match the query.
-	return h.sendMDNSQuery(h.session.NICInfo.HostAddr4, mdnsIPv4Addr, dnsmessage.TypeALL, name)
-}
-
-// SendLLMNRQuery send a multicast LLMNR query
-func (h *DNSHandler) SendLLMNRQuery(name string) (err error) {
-	return h.sendMDNSQuery(h.session.NICInfo.HostAddr4, llmnrIPv4Addr, dnsmessage.TypePTR, name)
+	return h.sendMDNSQuery(h.session.NICInfo().HostAddr4, mdnsIPv4Addr, dnsmessage.TypeALL, name)
 }
 
 func (h *DNSHandler) sendMDNSQuery(srcAddr packet.Addr, dstAddr packet.Addr, mtype dnsmessage.Type, name string) (err error) {
@@ -103,7 +82,7 @@ func (h *DNSHandler) sendMDNSQuery(srcAddr packet.Addr, dstAddr packet.Addr, mty
 		Questions: []dnsmessage.Question{
 			{
 				Name:  mustNewName(name),
-				Type:  dnsmessage.TypeALL,
+				Type:  mtype,
 				Class: dnsmessage.ClassANY,
 			},
 		},
@@ -134,7 +113,7 @@ func (h *DNSHandler) sendMDNS(buf []byte, srcAddr packet.Addr, dstAddr packet.Ad
 
 	// IP4
 	if srcAddr.IP.Is4() {
-		ether = packet.EncodeEther(ether, syscall.ETH_P_IP, h.session.NICInfo.HostAddr4.MAC, dstAddr.MAC)
+		ether = packet.EncodeEther(ether, syscall.ETH_P_IP, h.session.NICInfo().HostAddr4.MAC, dstAddr.MAC)
 		ip4 := packet.EncodeIP4(ether.Payload(), 255, srcAddr.IP, dstAddr.IP)
 		udp := packet.EncodeUDP(ip4.Payload(), dstAddr.Port, dstAddr.Port) // same port number for src and dst
 		if udp, err = udp.AppendPayload(buf); err != nil {
@@ -151,7 +130,7 @@ func (h *DNSHandler) sendMDNS(buf []byte, srcAddr packet.Addr, dstAddr packet.Ad
 	}
 
 	// IP6
-	ether = packet.EncodeEther(ether, syscall.ETH_P_IPV6, h.session.NICInfo.HostAddr4.MAC, dstAddr.MAC)
+	ether = packet.EncodeEther(ether, syscall.ETH_P_IPV6, h.session.NICInfo().HostAddr4.MAC, dstAddr.MAC)
 	ip6 := packet.EncodeIP6(ether.Payload(), 255, srcAddr.IP, dstAddr.IP)
 	udp := packet.EncodeUDP(ip6.Payload(), dstAddr.Port, dstAddr.Port) // same port number for src and dst
 	if udp, err = udp.AppendPayload(buf); err != nil {
@@ -179,7 +158,7 @@ func (h *DNSHandler) SendSleepProxyResponse(srcAddr packet.Addr, dstAddr packet.
 	// #<SPSType>-<SPSPortability>-<SPSMarginalPower>-<SPSTotalPower>.<SPSFeatureFlags> <nicelabel>
 	name = "10-34-10-70 SleepProxyServer._sleep-proxy._udp.local."
 	var ip4 [4]byte
-	copy(ip4[:], h.session.NICInfo.HostAddr4.IP.AsSlice())
+	copy(ip4[:], h.session.NICInfo().HostAddr4.IP.AsSlice())
 	msg := dnsmessage.Message{
 		Header: dnsmessage.Header{ID: id, Response: true},
 
@@ -349,7 +328,7 @@ func (h *DNSHandler) ProcessMDNS(frame packet.Frame) (ipv4 []packet.IPNameEntry,
 					entry.NameEntry.Manufacturer = "Apple"
 					// Advertise that we are a SpeepProxy server
 					// TODO: this is not working yet - September 2021
-					// go h.SendSleepProxyResponse(h.session.NICInfo.HostAddr4, mdnsIPv4Addr, dnsHeader.ID, "sleepproxy")
+					// go h.SendSleepProxyResponse(h.session.NICInfo().HostAddr4, mdnsIPv4Addr, dnsHeader.ID, "sleepproxy")
 				}
 			}
 			if entry.NameEntry.Name != "" || entry.NameEntry.Manufacturer != "" {
@@ -135,7 +135,7 @@ func (h *DNSHandler) SendNBNSNodeStatus() (err error) {
 	const word = uint16(responseRequest | opcodeQuery | nmflagsUnicast | rcodeOK)
 	sequence++
 	p := packet.EncodeDNSQuery(sequence, word, encodeNBNSName(name), questionTypeNodeStatus)
-	return h.sendNBNS(h.session.NICInfo.HostAddr4, packet.IP4BroadcastAddr, p)
+	return h.sendNBNS(h.session.NICInfo().HostAddr4, packet.IP4BroadcastAddr, p)
 }
 
 func (h *DNSHandler) sendNBNS(srcAddr packet.Addr, dstAddr packet.Addr, p packet.DNS) (err error) {
@@ -26,9 +26,6 @@ var ssdpLogger = fastlog.New("ssdp")
 // the receiver should assume the default SSDP port number of 1900.
 var ssdpIPv4Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.AddrFrom4([4]byte{239, 255, 255, 250}), Port: 1900}
 
-// Web Discovery Protocol - WSD
-var wsd4IPv4Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.AddrFrom4([4]byte{239, 255, 255, 250}), Port: 3702}
-
 const defaultExpiryTime = time.Second * 300
 
 // processSSDPNotify process notify ssdp messages
@@ -203,8 +200,8 @@ func (h *DNSHandler) SendSSDPSearch() (err error) {
 	b := packet.EtherBufferPool.Get().(*[packet.EthMaxSize]byte)
 	defer packet.EtherBufferPool.Put(b)
 	ether := packet.Ether(b[0:])
-	ether = packet.EncodeEther(ether, syscall.ETH_P_IP, h.session.NICInfo.HostAddr4.MAC, ssdpIPv4Addr.MAC)
-	ip4 := packet.EncodeIP4(ether.Payload(), 255, h.session.NICInfo.HostAddr4.IP, ssdpIPv4Addr.IP)
+	ether = packet.EncodeEther(ether, syscall.ETH_P_IP, h.session.NICInfo().HostAddr4.MAC, ssdpIPv4Addr.MAC)
+	ip4 := packet.EncodeIP4(ether.Payload(), 255, h.session.NICInfo().HostAddr4.IP, ssdpIPv4Addr.IP)
 	udp := packet.EncodeUDP(ip4.Payload(), 1900, 1900)
 	if udp, err = udp.AppendPayload(mSearchString); err != nil {
 		return err
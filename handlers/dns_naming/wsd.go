@@ -0,0 +1,420 @@
+package dns_naming
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+const moduleWSD = "wsd"
+
+const (
+	wsdMaxLookups      = 8         // maximum number of metadata lookups in flight
+	wsdMaxMetadataSize = 64 * 1024 // maximum metadata response size
+)
+
+var wsdLogger = fastlog.New("wsd")
+
+// Web Services Dynamic Discovery - WSD
+//
+// Multicast SOAP-over-UDP messages sent to 239.255.255.250:3702 by Windows hosts,
+// printers, scanners and IP cameras.
+// see: http://docs.oasis-open.org/ws-dd/discovery/1.1/os/wsdd-discovery-1.1-spec-os.html
+var wsd4IPv4Addr = packet.Addr{MAC: packet.EthBroadcast, IP: netip.AddrFrom4([4]byte{239, 255, 255, 250}), Port: 3702}
+
+// WSD message actions
+const (
+	WSDActionHello          = "Hello"
+	WSDActionBye            = "Bye"
+	WSDActionProbe          = "Probe"
+	WSDActionProbeMatches   = "ProbeMatches"
+	WSDActionResolve        = "Resolve"
+	WSDActionResolveMatches = "ResolveMatches"
+)
+
+// WSDEndpoint holds the endpoint fields announced in Hello, Bye, ProbeMatch and ResolveMatch messages.
+type WSDEndpoint struct {
+	Address         string   // endpoint reference - usually urn:uuid:...
+	Types           []string // i.e. wsdp:Device pub:Computer
+	Scopes          []string
+	XAddrs          []string // transport addresses to retrieve metadata
+	MetadataVersion uint32
+}
+
+func (e WSDEndpoint) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.String("address", e.Address)
+	l.StringArray("types", e.Types)
+	if len(e.Scopes) > 0 {
+		l.StringArray("scopes", e.Scopes)
+	}
+	l.StringArray("xaddrs", e.XAddrs)
+	return l
+}
+
+// WSDMessage holds a decoded WSD message
+type WSDMessage struct {
+	Action    string // short action name - WSDActionHello, WSDActionBye...
+	MessageID string
+	RelatesTo string
+	Endpoints []WSDEndpoint
+}
+
+func (m WSDMessage) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.String("action", m.Action)
+	l.String("id", m.MessageID)
+	for _, v := range m.Endpoints {
+		l.Struct(v)
+	}
+	return l
+}
+
+type wsdXMLEndpoint struct {
+	Address         string `xml:"EndpointReference>Address"`
+	Types           string `xml:"Types"`
+	Scopes          string `xml:"Scopes"`
+	XAddrs          string `xml:"XAddrs"`
+	MetadataVersion uint32 `xml:"MetadataVersion"`
+}
+
+type wsdXMLEnvelope struct {
+	XMLName        xml.Name         `xml:"Envelope"`
+	Action         string           `xml:"Header>Action"`
+	MessageID      string           `xml:"Header>MessageID"`
+	RelatesTo      string           `xml:"Header>RelatesTo"`
+	Hello          *wsdXMLEndpoint  `xml:"Body>Hello"`
+	Bye            *wsdXMLEndpoint  `xml:"Body>Bye"`
+	Probe          *wsdXMLEndpoint  `xml:"Body>Probe"`
+	ProbeMatches   []wsdXMLEndpoint `xml:"Body>ProbeMatches>ProbeMatch"`
+	ResolveMatches []wsdXMLEndpoint `xml:"Body>ResolveMatches>ResolveMatch"`
+}
+
+func (e wsdXMLEndpoint) endpoint() WSDEndpoint {
+	return WSDEndpoint{Address: strings.TrimSpace(e.Address), Types: strings.Fields(e.Types), Scopes: strings.Fields(e.Scopes),
+		XAddrs: strings.Fields(e.XAddrs), MetadataVersion: e.MetadataVersion}
+}
+
+// unmarshalWSDMessage decodes a WSD SOAP envelope.
+//
+// example Hello
+//
+//	<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" ...>
+//	  <soap:Header>
+//	    <wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To>
+//	    <wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Hello</wsa:Action>
+//	    <wsa:MessageID>urn:uuid:...</wsa:MessageID>
+//	  </soap:Header>
+//	  <soap:Body>
+//	    <wsd:Hello>
+//	      <wsa:EndpointReference><wsa:Address>urn:uuid:...</wsa:Address></wsa:EndpointReference>
+//	      <wsd:Types>wsdp:Device pub:Computer</wsd:Types>
+//	      <wsd:XAddrs>http://192.168.0.10:5357/.../</wsd:XAddrs>
+//	      <wsd:MetadataVersion>2</wsd:MetadataVersion>
+//	    </wsd:Hello>
+//	  </soap:Body>
+//	</soap:Envelope>
+func unmarshalWSDMessage(b []byte) (m WSDMessage, err error) {
+	v := wsdXMLEnvelope{}
+	if err := xml.Unmarshal(b, &v); err != nil {
+		return WSDMessage{}, fmt.Errorf("wsd invalid xml: %w", err)
+	}
+	m.MessageID = strings.TrimSpace(v.MessageID)
+	m.RelatesTo = strings.TrimSpace(v.RelatesTo)
+	action := strings.TrimSpace(v.Action)
+	m.Action = action[strings.LastIndex(action, "/")+1:]
+	switch {
+	case v.Hello != nil:
+		m.Endpoints = append(m.Endpoints, v.Hello.endpoint())
+	case v.Bye != nil:
+		m.Endpoints = append(m.Endpoints, v.Bye.endpoint())
+	case v.Probe != nil:
+		m.Endpoints = append(m.Endpoints, v.Probe.endpoint())
+	}
+	for _, e := range v.ProbeMatches {
+		m.Endpoints = append(m.Endpoints, e.endpoint())
+	}
+	for _, e := range v.ResolveMatches {
+		m.Endpoints = append(m.Endpoints, e.endpoint())
+	}
+	if m.Action == "" {
+		return WSDMessage{}, packet.ErrParseFrame
+	}
+	return m, nil
+}
+
+// wsdNameFromEndpoint derives the device model and OS from the endpoint types and scopes.
+func wsdNameFromEndpoint(e WSDEndpoint) (name packet.NameEntry) {
+	name.Type = moduleWSD
+	for _, t := range e.Types {
+		// strip the namespace prefix
+		t = t[strings.LastIndex(t, ":")+1:]
+		switch t {
+		case "Computer":
+			name.Model = "Computer"
+			name.OS = "Windows"
+		case "PrintDeviceType":
+			name.Model = "Printer"
+		case "ScanDeviceType":
+			if name.Model == "" {
+				name.Model = "Scanner"
+			}
+		case "NetworkVideoTransmitter":
+			name.Model = "IP Camera"
+		}
+	}
+	// ONVIF cameras announce the name and hardware model in the scopes
+	//   onvif://www.onvif.org/name/IPC-123  onvif://www.onvif.org/hardware/DS-2CD2143
+	for _, s := range e.Scopes {
+		switch {
+		case strings.HasPrefix(s, "onvif://www.onvif.org/name/"):
+			name.Name = strings.ReplaceAll(strings.TrimPrefix(s, "onvif://www.onvif.org/name/"), "%20", " ")
+		case strings.HasPrefix(s, "onvif://www.onvif.org/hardware/"):
+			name.Model = strings.ReplaceAll(strings.TrimPrefix(s, "onvif://www.onvif.org/hardware/"), "%20", " ")
+		}
+	}
+	return name
+}
+
+// ProcessWSD process a WSD message and returns the name entry and the metadata addresses (XAddrs).
+//
+// Hello and ProbeMatches carry the device types and XAddrs; the caller can use the XAddrs
+// with WSDMetadataDiscovery to retrieve the friendly name and model.
+func (h *DNSHandler) ProcessWSD(host *packet.Host, ether packet.Ether, payload []byte) (name packet.NameEntry, xaddrs []string, err error) {
+	m, err := unmarshalWSDMessage(payload)
+	if err != nil {
+		return packet.NameEntry{}, nil, err
+	}
+	if wsdLogger.IsDebug() {
+		wsdLogger.Msg("wsd rcvd").MAC("mac", ether.Src()).IP("ip", ether.SrcIP()).Struct(m).Write()
+	}
+	switch m.Action {
+	case WSDActionHello, WSDActionProbeMatches, WSDActionResolveMatches:
+	default: // Bye, Probe and Resolve do not carry device information
+		return packet.NameEntry{}, nil, nil
+	}
+	for _, e := range m.Endpoints {
+		n := wsdNameFromEndpoint(e)
+		name, _ = name.Merge(n)
+		xaddrs = append(xaddrs, e.XAddrs...)
+	}
+	name.Expire = time.Now().Add(defaultExpiryTime)
+
+	// Windows hosts and printers announce the device type only; retrieve the friendly name
+	if m.Action == WSDActionHello && name.Name == "" && host != nil {
+		h.wsdLookupMetadata(host, ether.SrcIP(), m.Endpoints)
+	}
+	return name, xaddrs, nil
+}
+
+// wsdValidXAddr returns true if the xaddr is an http url pointing to the sender ip.
+//
+// Hello messages are unsolicited; fetching any other url would let a lan host direct
+// requests to arbitrary internal or external servers.
+func wsdValidXAddr(xaddr string, srcIP netip.Addr) bool {
+	u, err := url.Parse(xaddr)
+	if err != nil || u.Scheme != "http" {
+		return false
+	}
+	ip, err := netip.ParseAddr(u.Hostname())
+	return err == nil && ip.Unmap() == srcIP.Unmap()
+}
+
+// wsdLookupMetadata retrieves the endpoints metadata in a goroutine and updates the host WSD name.
+// Only one lookup runs per endpoint address, up to wsdMaxLookups in total; hosts that already
+// have a WSD name are skipped. Close waits for the lookups in progress.
+func (h *DNSHandler) wsdLookupMetadata(host *packet.Host, srcIP netip.Addr, endpoints []WSDEndpoint) {
+	host.MACEntry.Row.RLock()
+	found := host.WSDName.Name != ""
+	addr := host.Addr
+	host.MACEntry.Row.RUnlock()
+	if found {
+		return
+	}
+	for _, e := range endpoints {
+		var xaddrs []string
+		for _, xaddr := range e.XAddrs {
+			if wsdValidXAddr(xaddr, srcIP) {
+				xaddrs = append(xaddrs, xaddr)
+			}
+		}
+		if len(xaddrs) == 0 {
+			continue
+		}
+		h.mutex.Lock()
+		if h.wsdPending[e.Address] || len(h.wsdPending) >= wsdMaxLookups {
+			h.mutex.Unlock()
+			continue
+		}
+		h.wsdPending[e.Address] = true
+		h.wsdLookups.Add(1)
+		h.mutex.Unlock()
+
+		go func(endpoint string, xaddrs []string) {
+			defer func() {
+				h.mutex.Lock()
+				delete(h.wsdPending, endpoint)
+				h.mutex.Unlock()
+				h.wsdLookups.Done()
+			}()
+			for _, xaddr := range xaddrs {
+				name, err := h.WSDMetadataDiscovery(addr, endpoint, xaddr)
+				if err != nil {
+					wsdLogger.Msg("failed to retrieve metadata").Struct(addr).String("xaddr", xaddr).Error(err).Write()
+					continue
+				}
+				if name.Name != "" || name.Model != "" {
+					name.Expire = time.Now().Add(defaultExpiryTime)
+					host.UpdateWSDName(name)
+					return
+				}
+			}
+		}(e.Address, xaddrs)
+	}
+}
+
+// wsdUUID returns a random version 4 uuid
+func wsdUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+const wsdProbeTemplate = `<?xml version="1.0" encoding="utf-8"?>` +
+	`<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" ` +
+	`xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof">` +
+	`<soap:Header><wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To>` +
+	`<wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</wsa:Action>` +
+	`<wsa:MessageID>urn:uuid:%s</wsa:MessageID></soap:Header>` +
+	`<soap:Body><wsd:Probe><wsd:Types>wsdp:Device</wsd:Types></wsd:Probe></soap:Body></soap:Envelope>`
+
+// SendWSDProbe transmit a multicast WSD probe for all devices.
+// Devices reply with a unicast ProbeMatches message to port 3702.
+func (h *DNSHandler) SendWSDProbe() (err error) {
+	b := packet.EtherBufferPool.Get().(*[packet.EthMaxSize]byte)
+	defer packet.EtherBufferPool.Put(b)
+	ether := packet.Ether(b[0:])
+	ether = packet.EncodeEther(ether, syscall.ETH_P_IP, h.session.NICInfo().HostAddr4.MAC, wsd4IPv4Addr.MAC)
+	ip4 := packet.EncodeIP4(ether.Payload(), 255, h.session.NICInfo().HostAddr4.IP, wsd4IPv4Addr.IP)
+	udp := packet.EncodeUDP(ip4.Payload(), 3702, 3702)
+	if udp, err = udp.AppendPayload([]byte(fmt.Sprintf(wsdProbeTemplate, wsdUUID()))); err != nil {
+		return err
+	}
+	ip4 = ip4.SetPayload(udp, syscall.IPPROTO_UDP)
+	if ether, err = ether.SetPayload(ip4); err != nil {
+		return err
+	}
+	if _, err = h.session.Conn.WriteTo(ether, &wsd4IPv4Addr); err != nil {
+		wsdLogger.Msg("failed to write probe").Error(err).Write()
+		return err
+	}
+	return nil
+}
+
+const wsdGetTemplate = `<?xml version="1.0" encoding="utf-8"?>` +
+	`<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing">` +
+	`<soap:Header><wsa:To>%s</wsa:To>` +
+	`<wsa:Action>http://schemas.xmlsoap.org/ws/2004/09/transfer/Get</wsa:Action>` +
+	`<wsa:MessageID>urn:uuid:%s</wsa:MessageID>` +
+	`<wsa:ReplyTo><wsa:Address>http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</wsa:Address></wsa:ReplyTo>` +
+	`</soap:Header><soap:Body/></soap:Envelope>`
+
+// WSDMetadata holds the device metadata returned by a WS-Transfer Get.
+type WSDMetadata struct {
+	Manufacturer string `xml:"ThisModel>Manufacturer"`
+	ModelName    string `xml:"ThisModel>ModelName"`
+	FriendlyName string `xml:"ThisDevice>FriendlyName"`
+	Computer     string `xml:"Relationship>Host>Computer"` // windows only - i.e. DESKTOP-1/Workgroup:WORKGROUP
+}
+
+type wsdXMLMetadata struct {
+	XMLName  xml.Name      `xml:"Envelope"`
+	Sections []WSDMetadata `xml:"Body>Metadata>MetadataSection"`
+}
+
+// unmarshalWSDMetadata decodes the metadata sections in a WS-Transfer GetResponse.
+func unmarshalWSDMetadata(b []byte) (m WSDMetadata, err error) {
+	v := wsdXMLMetadata{}
+	if err := xml.Unmarshal(b, &v); err != nil {
+		return WSDMetadata{}, fmt.Errorf("wsd invalid metadata xml: %w", err)
+	}
+	for _, s := range v.Sections {
+		if s.Manufacturer != "" {
+			m.Manufacturer = strings.TrimSpace(s.Manufacturer)
+		}
+		if s.ModelName != "" {
+			m.ModelName = strings.TrimSpace(s.ModelName)
+		}
+		if s.FriendlyName != "" {
+			m.FriendlyName = strings.TrimSpace(s.FriendlyName)
+		}
+		if s.Computer != "" {
+			m.Computer = strings.TrimSpace(s.Computer)
+		}
+	}
+	return m, nil
+}
+
+func getWSDMetadata(endpoint string, xaddr string) ([]byte, error) {
+	client := &http.Client{
+		Timeout: time.Second * 3,
+	}
+	body := fmt.Sprintf(wsdGetTemplate, endpoint, wsdUUID())
+	req, err := http.NewRequest("POST", xaddr, bytes.NewReader([]byte(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, packet.ErrNoReader
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, wsdMaxMetadataSize))
+}
+
+// WSDMetadataDiscovery retrieves the device metadata from the xaddr and returns the name entry.
+// The endpoint is the endpoint reference address announced with the xaddr.
+func (h *DNSHandler) WSDMetadataDiscovery(addr packet.Addr, endpoint string, xaddr string) (name packet.NameEntry, err error) {
+	b, err := getWSDMetadata(endpoint, xaddr)
+	if err != nil {
+		return packet.NameEntry{}, err
+	}
+	m, err := unmarshalWSDMetadata(b)
+	if err != nil {
+		return packet.NameEntry{}, err
+	}
+	if wsdLogger.IsInfo() {
+		wsdLogger.Msg("wsd metadata").Struct(addr).String("name", m.FriendlyName).String("model", m.ModelName).
+			String("manufacturer", m.Manufacturer).String("computer", m.Computer).Write()
+	}
+	return wsdNameFromMetadata(m), nil
+}
+
+func wsdNameFromMetadata(m WSDMetadata) (name packet.NameEntry) {
+	name.Type = moduleWSD
+	name.Name = m.FriendlyName
+	if m.Computer != "" { // the windows computer name is more useful than the generic friendly name
+		name.Name, _, _ = strings.Cut(m.Computer, "/")
+		name.OS = "Windows"
+	}
+	name.Model = m.ModelName
+	name.Manufacturer = m.Manufacturer
+	name.Expire = time.Now().Add(defaultExpiryTime)
+	return name
+}
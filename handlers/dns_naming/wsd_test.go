@@ -0,0 +1,214 @@
+package dns_naming
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/deeGraYve/packet"
+)
+
+var wsdHelloWindows = []byte(`<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof" xmlns:pub="http://schemas.microsoft.com/windows/pub/2005/07"><soap:Header><wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To><wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Hello</wsa:Action><wsa:MessageID>urn:uuid:2f8b3c1e-7a0e-4c3b-9d55-1f6f8a3c2b10</wsa:MessageID><wsd:AppSequence InstanceId="12" SequenceId="urn:uuid:5d1a7d0c-4f52-4bd5-b1d3-6d8f0c2e7a11" MessageNumber="1"></wsd:AppSequence></soap:Header><soap:Body><wsd:Hello><wsa:EndpointReference><wsa:Address>urn:uuid:8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77</wsa:Address></wsa:EndpointReference><wsd:Types>wsdp:Device pub:Computer</wsd:Types><wsd:XAddrs>http://192.168.0.10:5357/8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77/</wsd:XAddrs><wsd:MetadataVersion>2</wsd:MetadataVersion></wsd:Hello></soap:Body></soap:Envelope>`)
+
+var wsdProbeMatchesPrinter = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:wprt="http://schemas.microsoft.com/windows/2006/08/wdp/print" xmlns:wscn="http://schemas.microsoft.com/windows/2006/08/wdp/scan" xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof">
+<soap:Header>
+<wsa:To>http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</wsa:To>
+<wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/ProbeMatches</wsa:Action>
+<wsa:MessageID>urn:uuid:0e7ac9a4-0f36-4b8e-8f1c-3c3c0f6d2a01</wsa:MessageID>
+<wsa:RelatesTo>urn:uuid:6c3e1f0a-9b7d-4b25-8a44-2f0e6a5c9d01</wsa:RelatesTo>
+</soap:Header>
+<soap:Body>
+<wsd:ProbeMatches>
+<wsd:ProbeMatch>
+<wsa:EndpointReference><wsa:Address>urn:uuid:16a65700-007c-1000-bb49-3c2af4a1b2c3</wsa:Address></wsa:EndpointReference>
+<wsd:Types>wsdp:Device wscn:ScanDeviceType wprt:PrintDeviceType</wsd:Types>
+<wsd:Scopes>http://schemas.xmlsoap.org/ws/2006/02/devprof</wsd:Scopes>
+<wsd:XAddrs>http://192.168.0.20:80/WebServices/Device</wsd:XAddrs>
+<wsd:MetadataVersion>1</wsd:MetadataVersion>
+</wsd:ProbeMatch>
+</wsd:ProbeMatches>
+</soap:Body>
+</soap:Envelope>`)
+
+var wsdHelloCamera = []byte(`<?xml version="1.0" encoding="UTF-8"?><SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl"><SOAP-ENV:Header><wsa:MessageID>uuid:1419d68a-1dd2-11b2-a105-000000000001</wsa:MessageID><wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To><wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Hello</wsa:Action></SOAP-ENV:Header><SOAP-ENV:Body><d:Hello><wsa:EndpointReference><wsa:Address>urn:uuid:1419d68a-1dd2-11b2-a105-F0000000A1B2</wsa:Address></wsa:EndpointReference><d:Types>dn:NetworkVideoTransmitter</d:Types><d:Scopes>onvif://www.onvif.org/type/video_encoder onvif://www.onvif.org/name/Front%20Door onvif://www.onvif.org/hardware/DS-2CD2143G0-I</d:Scopes><d:XAddrs>http://192.168.0.30/onvif/device_service</d:XAddrs><d:MetadataVersion>10</d:MetadataVersion></d:Hello></SOAP-ENV:Body></SOAP-ENV:Envelope>`)
+
+var wsdBye = []byte(`<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery"><soap:Header><wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To><wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Bye</wsa:Action><wsa:MessageID>urn:uuid:3a7c1e52-6f0b-4d1a-8e2c-5b9d0f1a2c33</wsa:MessageID></soap:Header><soap:Body><wsd:Bye><wsa:EndpointReference><wsa:Address>urn:uuid:8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77</wsa:Address></wsa:EndpointReference></wsd:Bye></soap:Body></soap:Envelope>`)
+
+var wsdMetadataWindows = []byte(`<?xml version="1.0" encoding="utf-8"?><soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope" xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing" xmlns:wsx="http://schemas.xmlsoap.org/ws/2004/09/mex" xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof" xmlns:pub="http://schemas.microsoft.com/windows/pub/2005/07"><soap:Header><wsa:Action>http://schemas.xmlsoap.org/ws/2004/09/transfer/GetResponse</wsa:Action></soap:Header><soap:Body><wsx:Metadata><wsx:MetadataSection Dialect="http://schemas.xmlsoap.org/ws/2006/02/devprof/ThisDevice"><wsdp:ThisDevice><wsdp:FriendlyName>Microsoft Publication Service Device Host</wsdp:FriendlyName><wsdp:FirmwareVersion>1.0</wsdp:FirmwareVersion></wsdp:ThisDevice></wsx:MetadataSection><wsx:MetadataSection Dialect="http://schemas.xmlsoap.org/ws/2006/02/devprof/ThisModel"><wsdp:ThisModel><wsdp:Manufacturer>Microsoft Corporation</wsdp:Manufacturer><wsdp:ModelName>Microsoft Publication Service</wsdp:ModelName></wsdp:ThisModel></wsx:MetadataSection><wsx:MetadataSection Dialect="http://schemas.xmlsoap.org/ws/2006/02/devprof/Relationship"><wsdp:Relationship Type="http://schemas.xmlsoap.org/ws/2006/02/devprof/host"><wsdp:Host><wsa:EndpointReference><wsa:Address>urn:uuid:8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77</wsa:Address></wsa:EndpointReference><wsdp:Types>pub:Computer</wsdp:Types><wsdp:ServiceId>urn:uuid:8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77</wsdp:ServiceId><pub:Computer>DESKTOP-1ABC/Workgroup:WORKGROUP</pub:Computer></wsdp:Host></wsdp:Relationship></wsx:MetadataSection></wsx:Metadata></soap:Body></soap:Envelope>`)
+
+func TestDNSHandler_ProcessWSD(t *testing.T) {
+	session, _ := testSession()
+	defer session.Close()
+	dnsHandler, _ := New(session)
+
+	tests := []struct {
+		name       string
+		payload    []byte
+		wantErr    bool
+		wantName   packet.NameEntry
+		wantXAddrs []string
+	}{
+		{name: "hello windows", payload: wsdHelloWindows, wantName: packet.NameEntry{Model: "Computer", OS: "Windows"},
+			wantXAddrs: []string{"http://192.168.0.10:5357/8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77/"}},
+		{name: "probematches printer", payload: wsdProbeMatchesPrinter, wantName: packet.NameEntry{Model: "Printer"},
+			wantXAddrs: []string{"http://192.168.0.20:80/WebServices/Device"}},
+		{name: "hello camera", payload: wsdHelloCamera, wantName: packet.NameEntry{Name: "Front Door", Model: "DS-2CD2143G0-I"},
+			wantXAddrs: []string{"http://192.168.0.30/onvif/device_service"}},
+		{name: "bye", payload: wsdBye},
+		{name: "invalid", payload: []byte("<soap:Envelope>"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ether := packet.EncodeEther(make([]byte, 1500), syscall.ETH_P_IP, mac1, mac2)
+			name, xaddrs, err := dnsHandler.ProcessWSD(nil, ether, tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DNSHandler.ProcessWSD() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name.Name != tt.wantName.Name || name.Model != tt.wantName.Model || name.OS != tt.wantName.OS {
+				t.Errorf("DNSHandler.ProcessWSD() invalid name = %+v, want %+v", name, tt.wantName)
+			}
+			if len(xaddrs) != len(tt.wantXAddrs) || (len(xaddrs) > 0 && xaddrs[0] != tt.wantXAddrs[0]) {
+				t.Errorf("DNSHandler.ProcessWSD() invalid xaddrs = %v, want %v", xaddrs, tt.wantXAddrs)
+			}
+		})
+	}
+}
+
+func Test_unmarshalWSDMessage(t *testing.T) {
+	m, err := unmarshalWSDMessage(wsdProbeMatchesPrinter)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if m.Action != WSDActionProbeMatches || m.RelatesTo != "urn:uuid:6c3e1f0a-9b7d-4b25-8a44-2f0e6a5c9d01" || len(m.Endpoints) != 1 {
+		t.Fatalf("invalid message %+v", m)
+	}
+	e := m.Endpoints[0]
+	if e.Address != "urn:uuid:16a65700-007c-1000-bb49-3c2af4a1b2c3" || len(e.Types) != 3 || len(e.Scopes) != 1 || e.MetadataVersion != 1 {
+		t.Errorf("invalid endpoint %+v", e)
+	}
+}
+
+func Test_unmarshalWSDMetadata(t *testing.T) {
+	m, err := unmarshalWSDMetadata(wsdMetadataWindows)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if m.Computer != "DESKTOP-1ABC/Workgroup:WORKGROUP" || m.Manufacturer != "Microsoft Corporation" {
+		t.Errorf("invalid metadata %+v", m)
+	}
+	name := wsdNameFromMetadata(m)
+	if name.Name != "DESKTOP-1ABC" || name.OS != "Windows" || name.Type != moduleWSD {
+		t.Errorf("invalid name %+v", name)
+	}
+}
+
+func TestDNSHandler_SendWSDProbe(t *testing.T) {
+	session, clientConn := testSession()
+	defer session.Close()
+	dnsHandler, _ := New(session)
+
+	if err := dnsHandler.SendWSDProbe(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	buf := make([]byte, packet.EthMaxSize)
+	n, _, err := clientConn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("unexpected read error", err)
+	}
+	udp := packet.UDP(packet.IP4(packet.Ether(buf[:n]).Payload()).Payload())
+	if udp.DstPort() != 3702 {
+		t.Errorf("invalid port %d", udp.DstPort())
+	}
+	m, err := unmarshalWSDMessage(udp.Payload())
+	if err != nil || m.Action != WSDActionProbe {
+		t.Errorf("invalid probe %+v err=%v", m, err)
+	}
+	if !bytes.Contains(udp.Payload(), []byte("wsdp:Device")) {
+		t.Error("probe missing type")
+	}
+}
+
+func testWSDFrame(srcIP netip.Addr, payload []byte) packet.Ether {
+	ether := packet.EncodeEther(make([]byte, packet.EthMaxSize), syscall.ETH_P_IP, mac1, wsd4IPv4Addr.MAC)
+	ip4 := packet.EncodeIP4(ether.Payload(), 255, srcIP, wsd4IPv4Addr.IP)
+	udp := packet.EncodeUDP(ip4.Payload(), 3702, 3702)
+	udp, _ = udp.AppendPayload(payload)
+	ip4 = ip4.SetPayload(udp, syscall.IPPROTO_UDP)
+	ether, _ = ether.SetPayload(ip4)
+	return ether
+}
+
+func TestDNSHandler_ProcessPacketWSD(t *testing.T) {
+	session, _ := testSession()
+	defer session.Close()
+	dnsHandler, _ := New(session)
+
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.Write(wsdMetadataWindows)
+	}))
+	defer server.Close()
+	hello := bytes.Replace(wsdHelloWindows, []byte("http://192.168.0.10:5357/8a1b6e3e-55c8-4f0b-9a0a-0c3f4e2d1b77/"), []byte(server.URL), 1)
+
+	frame, err := session.Parse(testWSDFrame(netip.MustParseAddr("192.168.0.10"), hello))
+	if err != nil || frame.Host == nil || frame.PayloadID != packet.PayloadWSDP {
+		t.Fatalf("invalid frame %+v err=%v", frame, err)
+	}
+	if err := dnsHandler.ProcessPacket(frame); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	// the xaddr does not point to the sender; it must not be retrieved
+	dnsHandler.wsdLookups.Wait()
+	if n := atomic.LoadInt32(&count); n != 0 {
+		t.Fatalf("invalid xaddr retrieved count=%d", n)
+	}
+	frame.Host.MACEntry.Row.RLock()
+	name := frame.Host.WSDName
+	frame.Host.MACEntry.Row.RUnlock()
+	if name.Model == "" || name.Name != "" {
+		t.Errorf("invalid model only entry %+v", name)
+	}
+
+	// the test server listens on the loopback; send the same hello from the server ip
+	serverIP := netip.MustParseAddr("127.0.0.1")
+	if _, _, err := dnsHandler.ProcessWSD(frame.Host, testWSDFrame(serverIP, hello), hello); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	dnsHandler.wsdLookups.Wait()
+	frame.Host.MACEntry.Row.RLock()
+	name = frame.Host.WSDName
+	frame.Host.MACEntry.Row.RUnlock()
+	if name.Name != "DESKTOP-1ABC" || atomic.LoadInt32(&count) != 1 {
+		t.Fatalf("metadata not retrieved %+v", name)
+	}
+}
+
+func Test_wsdValidXAddr(t *testing.T) {
+	srcIP := netip.MustParseAddr("192.168.0.10")
+	tests := []struct {
+		name  string
+		xaddr string
+		want  bool
+	}{
+		{name: "sender", xaddr: "http://192.168.0.10:5357/8a1b6e3e/", want: true},
+		{name: "other host", xaddr: "http://192.168.0.1/admin", want: false},
+		{name: "hostname", xaddr: "http://example.com/", want: false},
+		{name: "https", xaddr: "https://192.168.0.10/", want: false},
+		{name: "file", xaddr: "file:///etc/passwd", want: false},
+		{name: "invalid", xaddr: "http://[::1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wsdValidXAddr(tt.xaddr, srcIP); got != tt.want {
+				t.Errorf("wsdValidXAddr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package icmp_spoofer
 import (
 	"fmt"
 	"syscall"
+	"time"
 
 	"github.com/deeGraYve/packet"
 	"github.com/deeGraYve/packet/fastlog"
@@ -11,6 +12,8 @@ import (
 
 var Logger4 = fastlog.New("icmp4")
 
+var _ packet.Handler = &Handler4{}
+
 // Handler4 maintains the underlying socket connection
 type Handler4 struct {
 	session *packet.Session
@@ -27,6 +30,21 @@ func (h *Handler4) Close() error {
 	return nil
 }
 
+// MinuteTicker implements packet.Handler; the icmp4 handler has no periodic work.
+func (h *Handler4) MinuteTicker(now time.Time) error {
+	return nil
+}
+
+// StartHunt implements packet.Handler; the icmp4 handler does not hunt.
+func (h *Handler4) StartHunt(addr packet.Addr) (packet.HuntStage, error) {
+	return packet.StageNoChange, nil
+}
+
+// StopHunt implements packet.Handler; the icmp4 handler does not hunt.
+func (h *Handler4) StopHunt(addr packet.Addr) (packet.HuntStage, error) {
+	return packet.StageNoChange, nil
+}
+
 // ProcessPacket parses an ICMP4 packet and log the frame.
 //
 // This is a simple processor to common ICMP4 packets includin
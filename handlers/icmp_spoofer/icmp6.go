@@ -14,6 +14,8 @@ import (
 
 var Logger6 = fastlog.New("icmp6")
 
+var _ packet.Handler = &Handler6{}
+
 // Handler implements ICMPv6 Neighbor Discovery Protocol
 // see: https://mdlayher.com/blog/network-protocol-breakdown-ndp-and-go/
 type Handler6 struct {
@@ -73,10 +75,15 @@ func (h *Handler6) Close() error {
 	return nil
 }
 
+// MinuteTicker implements packet.Handler; the icmp6 handler has no periodic work.
+func (h *Handler6) MinuteTicker(now time.Time) error {
+	return nil
+}
+
 // PingAll sends an echo request to the IPv6 multicast address to
 // encourage hosts to reply.
 func (h *Handler6) PingAll() error {
-	if !h.session.NICInfo.HostLLA.Addr().IsValid() {
+	if !h.session.NICInfo().HostLLA.Addr().IsValid() {
 		return packet.ErrInvalidIP6LLA
 	}
 	if Logger6.IsInfo() {
@@ -85,10 +92,10 @@ func (h *Handler6) PingAll() error {
 	if err := h.session.ICMP6SendRouterSolicitation(); err != nil {
 		return err
 	}
-	// if err := packet.ExecPing(packet.IP6AllNodesMulticast.String() + "%" + h.session.NICInfo.IFI.Name); err != nil { // ping with external cmd tool
+	// if err := packet.ExecPing(packet.IP6AllNodesMulticast.String() + "%" + h.session.NICInfo().IFI.Name); err != nil { // ping with external cmd tool
 	// fmt.Printf("icmp6 : error in initial ping all nodes multicast - ignoring : %s\n", err)
 	// }
-	return h.session.ICMP6SendEchoRequest(packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: h.session.NICInfo.HostLLA.Addr()}, packet.IP6AllNodesAddr, 99, 1)
+	return h.session.ICMP6SendEchoRequest(packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: h.session.NICInfo().HostLLA.Addr()}, packet.IP6AllNodesAddr, 99, 1)
 }
 
 var repeat int = -1
@@ -163,7 +170,7 @@ func (h *Handler6) ProcessPacket(pkt packet.Frame) (err error) {
 		// If a host is looking up for a GUA on the lan, it is likely a valid IP6 GUA for a local host.
 		// So, send our own neighbour solicitation to discover the IP
 		if frame.TargetAddress().IsGlobalUnicast() {
-			srcAddr := packet.Addr{MAC: h.session.NICInfo.HostAddr4.MAC, IP: h.session.NICInfo.HostLLA.Addr()}
+			srcAddr := packet.Addr{MAC: h.session.NICInfo().HostAddr4.MAC, IP: h.session.NICInfo().HostLLA.Addr()}
 			dstAddr := packet.Addr{MAC: pkt.Ether().Dst(), IP: ip6Frame.Dst()}
 			h.session.ICMP6SendNeighbourSolicitation(srcAddr, dstAddr, frame.TargetAddress())
 		}
@@ -1,6 +1,7 @@
 package icmp_spoofer
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"net/netip"
@@ -44,6 +45,8 @@ func (r *Router) String() string {
 // findOrCreateRouter return an existing router that matches ip or create a new one if not found.
 //
 // The function will copy mac and ip if required. It is safe to call this using a frame buffer.
+// New routers become the default router only if allowed by the session RAGuard policy;
+// rogue routers are kept in LANRouters but never used in the na attack.
 func (h *Handler6) findOrCreateRouter(mac net.HardwareAddr, ip netip.Addr) (router *Router, found bool) {
 	r, found := h.LANRouters[ip]
 	if found {
@@ -51,7 +54,9 @@ func (h *Handler6) findOrCreateRouter(mac net.HardwareAddr, ip netip.Addr) (rout
 	}
 	router = &Router{Addr: packet.Addr{MAC: packet.CopyMAC(mac), IP: ip}}
 	h.LANRouters[ip] = router
-	h.Router = router // make this the default ipv6 router - used in na attack
+	if bytes.Equal(mac, h.session.NICInfo().HostAddr4.MAC) || h.session.IsAllowedRouter(mac, ip) {
+		h.Router = router // make this the default ipv6 router - used in na attack
+	}
 	fmt.Printf("icmp6 : create new ipv6 router %s\n", router)
 	return router, false
 }
@@ -78,11 +83,11 @@ func (h *Handler6) StartRADVS(managed bool, other bool, prefixes []packet.Prefix
 
 func (h *Handler6) startRADVS(managed bool, other bool, prefixes []packet.PrefixInformation, rdnss *packet.RecursiveDNSServer) (radvs *RADVS, err error) {
 	radvs = &RADVS{stopChannel: make(chan bool, 1)}
-	radvs.Router, _ = h.findOrCreateRouter(h.session.NICInfo.HostAddr4.MAC, h.session.NICInfo.HostLLA.Addr())
+	radvs.Router, _ = h.findOrCreateRouter(h.session.NICInfo().HostAddr4.MAC, h.session.NICInfo().HostLLA.Addr())
 	radvs.Router.enableRADVS = true
 	radvs.Router.ManagedFlag = managed
 	radvs.Router.OtherCondigFlag = other
-	radvs.Router.MTU = uint32(h.session.NICInfo.IFI.MTU)
+	radvs.Router.MTU = uint32(h.session.NICInfo().IFI.MTU)
 	radvs.Router.ReacheableTime = int((time.Minute * 10).Milliseconds()) // Must be no greater than 3,600,000 milliseconds (1hour)
 	radvs.Router.RetransTimer = int((time.Minute * 2).Milliseconds())
 	radvs.Router.CurHopLimit = 1
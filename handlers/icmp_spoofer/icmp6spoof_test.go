@@ -1,6 +1,7 @@
 package icmp_spoofer
 
 import (
+	"net"
 	"testing"
 	"time"
 
@@ -88,3 +89,26 @@ func TestHandler_ManyStartHunt(t *testing.T) {
 	h.Mutex.Unlock()
 
 }
+
+func TestHandler6_findOrCreateRouter(t *testing.T) {
+	tc := setupTestHandler()
+	defer tc.Close()
+
+	h, _ := New6(tc.session)
+	h.findOrCreateRouter(routerMAC, ip6LLARouter)
+	if h.Router == nil || h.Router.Addr.IP != ip6LLARouter {
+		t.Fatalf("invalid default router %+v", h.Router)
+	}
+
+	// rogue router must not replace the default router
+	h.findOrCreateRouter(mac1, ip6LLA2)
+	if h.Router.Addr.IP != ip6LLARouter || len(h.LANRouters) != 2 {
+		t.Errorf("invalid default router %+v", h.Router)
+	}
+
+	tc.session.RAGuard.RouterMACs = []net.HardwareAddr{mac2}
+	h.findOrCreateRouter(mac2, ip6LLA3)
+	if h.Router.Addr.IP != ip6LLA3 {
+		t.Errorf("invalid default router %+v", h.Router)
+	}
+}
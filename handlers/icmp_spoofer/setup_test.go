@@ -93,11 +93,11 @@ func setupTestHandler() *testContext {
 	// go packet.TestReadAndDiscardLoop(tc.ctx, tc.outConn) // MUST read the out conn to avoid blocking the sender
 
 	// fake nicinfo
-	tc.session.NICInfo = &packet.NICInfo{
+	tc.session.SetNICInfo(packet.NICInfo{
 		HomeLAN4:    homeLAN,
 		HostAddr4:   packet.Addr{MAC: hostMAC, IP: hostIP4},
 		RouterAddr4: packet.Addr{MAC: routerMAC, IP: routerIP4},
-	}
+	})
 
 	if tc.h, err = New6(tc.session); err != nil {
 		panic(err)
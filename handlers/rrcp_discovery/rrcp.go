@@ -0,0 +1,192 @@
+// Package rrcp_discovery provides a handler to discover unmanaged Realtek switches
+// using the Realtek Remote Control Protocol (RRCP).
+//
+// Many cheap unmanaged switches use Realtek chips that reply to RRCP Hello broadcasts
+// even if they have no management interface. The handler sends a Hello broadcast and
+// records every switch that replies together with its chip and vendor identity.
+package rrcp_discovery
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deeGraYve/packet"
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+const module = "rrcp"
+
+var Logger = fastlog.New(module)
+
+// Switch holds the identity of a Realtek switch that replied to a Hello.
+type Switch struct {
+	MAC          net.HardwareAddr
+	DownlinkPort uint8             // port in the switch that received the hello
+	UplinkPort   uint8             // port in the switch that connects to the uplink
+	UplinkMAC    net.HardwareAddr  // mac address of the uplink switch
+	ChipID       uint16            // chip id register
+	VendorID     uint32            // vendor id register
+	Registers    map[uint16]uint16 // registers read with GetRegister
+	LastSeen     time.Time
+}
+
+func (s Switch) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.MAC("mac", s.MAC)
+	l.Uint16Hex("chipID", s.ChipID)
+	l.Uint32("vendorID", s.VendorID)
+	l.Uint8("downlink", s.DownlinkPort)
+	l.Uint8("uplink", s.UplinkPort)
+	l.MAC("uplinkMAC", s.UplinkMAC)
+	l.Int("registers", len(s.Registers))
+	return l
+}
+
+var _ packet.Handler = &Handler{}
+
+// Handler stores instance variables
+type Handler struct {
+	mutex   sync.RWMutex
+	session *packet.Session
+	authKey uint16
+	table   map[string]*Switch
+	closed  bool
+}
+
+// Config holds the handler configuration
+type Config struct {
+	AuthKey uint16 // authentication key; zero means the factory default 0x2379
+}
+
+// New creates a RRCP handler with the default authentication key.
+func New(session *packet.Session) (h *Handler, err error) {
+	return Config{}.New(session)
+}
+
+func (config Config) New(session *packet.Session) (h *Handler, err error) {
+	h = &Handler{session: session, authKey: config.AuthKey, table: make(map[string]*Switch)}
+	if h.authKey == 0 {
+		h.authKey = packet.RRCPDefaultAuthKey
+	}
+	return h, nil
+}
+
+// Close the handler
+func (h *Handler) Close() error {
+	h.mutex.Lock()
+	h.closed = true
+	h.mutex.Unlock()
+	return nil
+}
+
+// MinuteTicker implements packet.Handler; the rrcp handler has no periodic work.
+func (h *Handler) MinuteTicker(now time.Time) error {
+	return nil
+}
+
+// StartHunt implements packet.Handler; the rrcp handler does not hunt.
+func (h *Handler) StartHunt(addr packet.Addr) (packet.HuntStage, error) {
+	return packet.StageNoChange, nil
+}
+
+// StopHunt implements packet.Handler; the rrcp handler does not hunt.
+func (h *Handler) StopHunt(addr packet.Addr) (packet.HuntStage, error) {
+	return packet.StageNoChange, nil
+}
+
+// SendHello sends a RRCP Hello broadcast. Every Realtek switch on the LAN will reply
+// with its identity.
+func (h *Handler) SendHello() error {
+	return h.send(packet.EthernetBroadcast, packet.RRCPOpCodeHello, 0, 0)
+}
+
+// GetRegister sends a get configuration request for the register to the switch.
+// The reply is stored in the switch Registers map.
+func (h *Handler) GetRegister(dst net.HardwareAddr, addr uint16) error {
+	return h.send(dst, packet.RRCPOpCodeGet, addr, 0)
+}
+
+func (h *Handler) send(dst net.HardwareAddr, opcode uint8, addr uint16, data uint16) (err error) {
+	h.mutex.RLock()
+	closed := h.closed
+	h.mutex.RUnlock()
+	if closed {
+		return packet.ErrHandlerClosed
+	}
+	b := packet.EtherBufferPool.Get().(*[packet.EthMaxSize]byte)
+	defer packet.EtherBufferPool.Put(b)
+	ether := packet.Ether(b[0:])
+	ether = packet.EncodeEther(ether, 0x8899, h.session.NICInfo().HostAddr4.MAC, dst)
+	rrcp := packet.EncodeRRCP(ether.Payload(), opcode, h.authKey, addr, data)
+	if ether, err = ether.SetPayload(rrcp); err != nil {
+		return err
+	}
+	if Logger.IsDebug() {
+		Logger.Msg("send rrcp").MAC("dst", dst).Struct(rrcp).Write()
+	}
+	_, err = h.session.Conn.WriteTo(ether, &packet.Addr{MAC: dst})
+	return err
+}
+
+// ProcessPacket processes RRCP replies and updates the switch table.
+// Requests and loop detection frames are ignored.
+func (h *Handler) ProcessPacket(frame packet.Frame) error {
+	p := packet.RRCP(frame.Payload())
+	if err := p.IsValid(); err != nil {
+		return err
+	}
+	if p.Protocol() != packet.RRCPProtocolRRCP || !p.Reply() {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	s, found := h.table[string(frame.SrcAddr.MAC)]
+	if !found {
+		if p.OpCode() != packet.RRCPOpCodeHello { // only accept get replies for known switches
+			return nil
+		}
+		s = &Switch{MAC: packet.CopyMAC(frame.SrcAddr.MAC), Registers: make(map[uint16]uint16)}
+		h.table[string(s.MAC)] = s
+	}
+	s.LastSeen = time.Now()
+	switch p.OpCode() {
+	case packet.RRCPOpCodeHello:
+		s.DownlinkPort = p.DownlinkPort()
+		s.UplinkPort = p.UplinkPort()
+		s.UplinkMAC = packet.CopyMAC(p.UplinkMAC())
+		s.ChipID = p.ChipID()
+		s.VendorID = p.VendorID()
+		if !found {
+			Logger.Msg("new switch").Struct(s).Write()
+		}
+	case packet.RRCPOpCodeGet:
+		s.Registers[p.RegisterAddr()] = p.RegisterData()
+	}
+	return nil
+}
+
+// Switches returns a copy of the switch table sorted by mac address.
+func (h *Handler) Switches() []Switch {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]Switch, 0, len(h.table))
+	for _, v := range h.table {
+		s := *v
+		s.Registers = make(map[uint16]uint16, len(v.Registers))
+		for k, d := range v.Registers {
+			s.Registers[k] = d
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return string(list[i].MAC) < string(list[j].MAC) })
+	return list
+}
+
+// PrintTable print the switch table to stdout.
+func (h *Handler) PrintTable() {
+	for _, v := range h.Switches() {
+		Logger.Msg("switch").Struct(v).Write()
+	}
+}
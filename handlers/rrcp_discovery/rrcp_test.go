@@ -0,0 +1,107 @@
+package rrcp_discovery
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/deeGraYve/packet"
+)
+
+var (
+	hostMAC   = net.HardwareAddr{0x00, 0xff, 0x03, 0x04, 0x05, 0x01}
+	switchMAC = net.HardwareAddr{0x00, 0xe0, 0x4c, 0x00, 0x00, 0x01}
+	uplinkMAC = net.HardwareAddr{0x00, 0xe0, 0x4c, 0x00, 0x00, 0x02}
+)
+
+func testSession() (*packet.Session, net.PacketConn) {
+	nicInfo := &packet.NICInfo{
+		HomeLAN4:    netip.MustParsePrefix("192.168.0.0/24"),
+		HostAddr4:   packet.Addr{MAC: hostMAC, IP: netip.MustParseAddr("192.168.0.129")},
+		RouterAddr4: packet.Addr{MAC: uplinkMAC, IP: netip.MustParseAddr("192.168.0.11")},
+	}
+	serverConn, clientConn := packet.TestNewBufferedConn()
+	session, _ := packet.Config{Conn: serverConn, NICInfo: nicInfo}.NewSession("")
+	return session, clientConn
+}
+
+// newReply returns a rrcp reply frame from the switch
+func newReply(opcode uint8, payload []byte) []byte {
+	ether := packet.EncodeEther(make([]byte, packet.EthMaxSize), 0x8899, switchMAC, hostMAC)
+	rrcp := packet.EncodeRRCP(ether.Payload(), opcode, packet.RRCPDefaultAuthKey, 0, 0)
+	rrcp[1] = rrcp[1] | 0x80 // reply
+	copy(rrcp[4:], payload)
+	ether, _ = ether.SetPayload(rrcp)
+	return ether
+}
+
+func TestHandler_SendHello(t *testing.T) {
+	session, clientConn := testSession()
+	defer session.Close()
+	h, _ := New(session)
+
+	if err := h.SendHello(); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	buf := make([]byte, packet.EthMaxSize)
+	n, _, err := clientConn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("unexpected read error", err)
+	}
+	ether := packet.Ether(buf[:n])
+	rrcp := packet.RRCP(ether.Payload())
+	if ether.EtherType() != 0x8899 || !bytes.Equal(ether.Dst(), packet.EthernetBroadcast) {
+		t.Errorf("invalid ether %s", ether)
+	}
+	if rrcp.Protocol() != packet.RRCPProtocolRRCP || rrcp.OpCode() != packet.RRCPOpCodeHello || rrcp.Reply() || rrcp.AuthKey() != packet.RRCPDefaultAuthKey {
+		t.Errorf("invalid hello %s", rrcp)
+	}
+
+	h.Close()
+	if err := h.SendHello(); err != packet.ErrHandlerClosed {
+		t.Errorf("expected closed error got %v", err)
+	}
+}
+
+func TestHandler_ProcessPacket(t *testing.T) {
+	session, _ := testSession()
+	defer session.Close()
+	h, _ := New(session)
+
+	// get reply from unknown switch is ignored
+	frame, err := session.Parse(newReply(packet.RRCPOpCodeGet, []byte{0x00, 0x02, 0x34, 0x12}))
+	if err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if err := h.ProcessPacket(frame); err != nil || len(h.Switches()) != 0 {
+		t.Fatalf("unexpected switch err=%v table=%v", err, h.Switches())
+	}
+
+	// hello reply: downlink port 3, uplink port 8, uplink mac, chip id 0x8316, vendor id 0x10ec
+	hello := append([]byte{0x03, 0x08}, uplinkMAC...)
+	hello = append(hello, 0x83, 0x16, 0x00, 0x00, 0x10, 0xec)
+	frame, _ = session.Parse(newReply(packet.RRCPOpCodeHello, hello))
+	if err := h.ProcessPacket(frame); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	frame, _ = session.Parse(newReply(packet.RRCPOpCodeGet, []byte{0x00, 0x02, 0x34, 0x12}))
+	if err := h.ProcessPacket(frame); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	list := h.Switches()
+	if len(list) != 1 {
+		t.Fatalf("invalid table %v", list)
+	}
+	s := list[0]
+	if !bytes.Equal(s.MAC, switchMAC) || !bytes.Equal(s.UplinkMAC, uplinkMAC) || s.DownlinkPort != 3 || s.UplinkPort != 8 {
+		t.Errorf("invalid switch ports %+v", s)
+	}
+	if s.ChipID != 0x8316 || s.VendorID != 0x10ec {
+		t.Errorf("invalid switch identity %+v", s)
+	}
+	if s.Registers[0x0200] != 0x1234 {
+		t.Errorf("invalid registers %v", s.Registers)
+	}
+}
@@ -23,20 +23,36 @@ type HostTable struct {
 // Host has a RWMutex used to sync access to the record. This must be read locked to access fields or write locked for updating
 // When locking the engine, you must lock the engine first then row lock to avoid deadlocks
 type Host struct {
-	Addr         Addr      // MAC and IP
-	MACEntry     *MACEntry // pointer to mac entry
-	Online       bool      // host online / offline state
-	HuntStage    HuntStage // host huntStage
-	LastSeen     time.Time // last packet time
-	Manufacturer string    // Mac address manufacturer
-	DHCP4Name    NameEntry
-	MDNSName     NameEntry
-	SSDPName     NameEntry
-	LLMNRName    NameEntry
-	NBNSName     NameEntry
-	dirty        bool
+	Addr            Addr      // MAC and IP
+	MACEntry        *MACEntry // pointer to mac entry
+	Online          bool      // host online / offline state
+	HuntStage       HuntStage // host huntStage
+	LastSeen        time.Time // last packet time
+	Manufacturer    string    // Mac address manufacturer
+	DHCP4Name       NameEntry
+	MDNSName        NameEntry
+	SSDPName        NameEntry
+	LLMNRName       NameEntry
+	NBNSName        NameEntry
+	WSDName         NameEntry
+	DeviceName      NameEntry        // name from vendor discovery protocols - ubiquiti, plex and sonos
+	MulticastGroups []MulticastGroup // multicast groups joined via IGMP or MLD
+	Latency         []LatencySample  // recent latency monitor results; oldest first
+	latencyDegraded bool
+	dirty           bool
 }
 
+// MulticastGroup holds a multicast group joined by the host and the time the membership expires.
+type MulticastGroup struct {
+	Group  netip.Addr
+	Expire time.Time
+}
+
+// DefaultMulticastGroupExpiry is the group membership interval; a membership is removed if the host
+// does not report it again within this period.
+// see https://tools.ietf.org/html/rfc3376#section-8.4
+const DefaultMulticastGroupExpiry = time.Second * 260
+
 func (e *Host) String() string {
 	return Logger.Msg("").Struct(e).ToString()
 }
@@ -53,6 +69,14 @@ func (e Host) FastLog(l *fastlog.Line) *fastlog.Line {
 	l.Struct(e.SSDPName)
 	l.Struct(e.LLMNRName)
 	l.Struct(e.NBNSName)
+	l.Struct(e.WSDName)
+	l.Struct(e.DeviceName)
+	if len(e.MulticastGroups) > 0 {
+		l.Int("groups", len(e.MulticastGroups))
+	}
+	if n := len(e.Latency); n > 0 {
+		l.Struct(e.Latency[n-1])
+	}
 	l.String("lastSeen", time.Since(e.LastSeen).String())
 	return l
 }
@@ -136,6 +160,10 @@ func (h *Session) findOrCreateHostWithLock(addr Addr) (host *Host, found bool) {
 
 	// this is new IP,
 	// create a new host and link to mac entry
+	newMAC := false
+	if e, _ := h.MACTable.findMAC(addr.MAC); e == nil {
+		newMAC = true
+	}
 	macEntry := h.MACTable.findOrCreate(addr.MAC)
 	host = &Host{Addr: Addr{IP: addr.IP, MAC: macEntry.MAC}, MACEntry: macEntry, Online: false} // set to false to trigger Online transition
 	host.dirty = true
@@ -150,6 +178,9 @@ func (h *Session) findOrCreateHostWithLock(addr Addr) (host *Host, found bool) {
 
 	// link host to macEntry
 	macEntry.HostList = append(macEntry.HostList, host)
+	if newMAC {
+		h.publishHost(EventNewDevice, host.Addr, toNotification(host))
+	}
 	return host, false
 }
 
@@ -267,3 +298,108 @@ func (host *Host) UpdateNBNSName(name NameEntry) {
 		host.MACEntry.NBNSName, _ = host.MACEntry.NBNSName.Merge(host.NBNSName)
 	}
 }
+
+func (host *Host) UpdateWSDName(name NameEntry) {
+	host.MACEntry.Row.Lock()
+	defer host.MACEntry.Row.Unlock()
+	var notify bool
+	host.WSDName, notify = host.WSDName.Merge(name)
+	if notify {
+		host.dirty = true
+		Logger.Msg("updated wsd name").Struct(host.Addr).Struct(host.WSDName).Write()
+		host.MACEntry.WSDName, _ = host.MACEntry.WSDName.Merge(host.WSDName)
+	}
+}
+
+func (host *Host) UpdateDeviceName(name NameEntry) {
+	host.MACEntry.Row.Lock()
+	defer host.MACEntry.Row.Unlock()
+	var notify bool
+	host.DeviceName, notify = host.DeviceName.Merge(name)
+	if notify {
+		host.dirty = true
+		Logger.Msg("updated device name").Struct(host.Addr).Struct(host.DeviceName).Write()
+		host.MACEntry.DeviceName, _ = host.MACEntry.DeviceName.Merge(host.DeviceName)
+	}
+}
+
+// updateMACDeviceName updates the device name for all hosts using the mac. It is used
+// for layer 2 frames that are not linked to a host.
+func (h *Session) updateMACDeviceName(mac net.HardwareAddr, name NameEntry) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	e, _ := h.MACTable.findMAC(mac)
+	if e == nil {
+		return
+	}
+	e.Row.Lock()
+	defer e.Row.Unlock()
+	var notify bool
+	if e.DeviceName, notify = e.DeviceName.Merge(name); !notify {
+		return
+	}
+	Logger.Msg("updated device name").MAC("mac", mac).Struct(e.DeviceName).Write()
+	for _, host := range e.HostList {
+		host.DeviceName, _ = host.DeviceName.Merge(name)
+		host.dirty = true
+	}
+}
+
+// updateMulticastGroups adds or removes group memberships reported by the host.
+func (host *Host) updateMulticastGroups(list []MulticastMembership, now time.Time) {
+	host.MACEntry.Row.Lock()
+	defer host.MACEntry.Row.Unlock()
+	for _, m := range list {
+		pos := -1
+		for i := range host.MulticastGroups {
+			if host.MulticastGroups[i].Group == m.Group {
+				pos = i
+				break
+			}
+		}
+		switch {
+		case m.Join && pos == -1:
+			if Logger.IsInfo() {
+				Logger.Msg("multicast group join").Struct(host.Addr).IP("group", m.Group).Write()
+			}
+			host.MulticastGroups = append(host.MulticastGroups, MulticastGroup{Group: m.Group, Expire: now.Add(DefaultMulticastGroupExpiry)})
+		case m.Join:
+			host.MulticastGroups[pos].Expire = now.Add(DefaultMulticastGroupExpiry)
+		case pos != -1:
+			if Logger.IsInfo() {
+				Logger.Msg("multicast group leave").Struct(host.Addr).IP("group", m.Group).Write()
+			}
+			host.MulticastGroups = append(host.MulticastGroups[:pos], host.MulticastGroups[pos+1:]...)
+		}
+	}
+}
+
+// expireMulticastGroups removes memberships that were not refreshed before now.
+// Caller must hold the row lock.
+func (host *Host) expireMulticastGroups(now time.Time) {
+	n := 0
+	for _, v := range host.MulticastGroups {
+		if v.Expire.After(now) {
+			host.MulticastGroups[n] = v
+			n++
+		}
+	}
+	host.MulticastGroups = host.MulticastGroups[:n]
+}
+
+// FindMulticastGroup returns the list of hosts that joined the multicast group.
+func (h *Session) FindMulticastGroup(group netip.Addr) (list []Addr) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for _, host := range h.HostTable.Table {
+		host.MACEntry.Row.RLock()
+		for _, v := range host.MulticastGroups {
+			if v.Group == group {
+				list = append(list, host.Addr)
+				break
+			}
+		}
+		host.MACEntry.Row.RUnlock()
+	}
+	return list
+}
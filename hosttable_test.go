@@ -243,3 +243,23 @@ func TestHost_UpdateNBNSName(t *testing.T) {
 		t.Error("unexpected name", notification.NBNSName)
 	}
 }
+
+func TestHost_UpdateWSDName(t *testing.T) {
+	session, _ := testSession()
+	host1, _ := session.findOrCreateHostWithLock(Addr{MAC: mac1, IP: ip1})
+	session.notify(Frame{Host: host1}) // first notification
+	name := NameEntry{Type: "wsd", Name: "DESKTOP-1", Model: "Computer", OS: "Windows"}
+	host1.UpdateWSDName(name)
+	session.notify(Frame{Host: host1}) // change of name notification
+	var notification Notification
+	for i := 0; i < 2; i++ { // must get have 2 notifications - online and change of name
+		select {
+		case notification = <-session.C:
+		case <-time.After(time.Second):
+			t.Fatal("did not receive notification number", i)
+		}
+	}
+	if notification.WSDName.Name != name.Name || notification.WSDName.Model != name.Model {
+		t.Error("unexpected name", notification.WSDName)
+	}
+}
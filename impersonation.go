@@ -0,0 +1,134 @@
+package packet
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// SpoofEvent is the Data of EventGatewayImpersonation and EventMACFlapping events.
+type SpoofEvent struct {
+	IP       netip.Addr
+	MAC      net.HardwareAddr // expected mac; the router mac or the previous mac for the ip
+	SpoofMAC net.HardwareAddr // mac claiming the ip
+	Count    int              // number of mac changes for flapping
+	Evidence []Ether          // copy of the frames that triggered the event; oldest first
+}
+
+func (e SpoofEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.IP("ip", e.IP)
+	l.MAC("mac", e.MAC)
+	l.MAC("spoofMAC", e.SpoofMAC)
+	if e.Count > 0 {
+		l.Int("count", e.Count)
+	}
+	l.Int("evidence", len(e.Evidence))
+	return l
+}
+
+// A mac flapping event is sent when the mac for an ip changes DefaultFlapCount times within DefaultFlapWindow.
+const (
+	DefaultFlapCount  = 3
+	DefaultFlapWindow = time.Minute
+)
+
+type flapEntry struct {
+	mac      net.HardwareAddr
+	changes  []time.Time
+	evidence []Ether
+}
+
+// spoofEventKey is the event type and ip.
+type spoofEventKey struct {
+	event EventType
+	ip    netip.Addr
+}
+
+type spoofState struct {
+	sync.Mutex
+	flaps  map[netip.Addr]*flapEntry
+	events eventLimiter[spoofEventKey]
+}
+
+// processARPSpoof checks an arp packet for gateway impersonation and mac flapping.
+// Frames sent by our host, including arp_spoofer hunts, must not be passed to this function.
+func (h *Session) processARPSpoof(ether Ether, p ARP, now time.Time) {
+	if p.SrcIP().IsUnspecified() { // probe
+		return
+	}
+	h.checkSpoof(ether, p.SrcIP(), p.SrcMAC(), h.NICInfo().RouterAddr4.IP, now)
+}
+
+// processNASpoof checks a neighbour advertisement for router impersonation and mac flapping.
+// The target link layer option is used if present, otherwise the ethernet source.
+// We assume the router uses the same mac for IPv4 and IPv6.
+func (h *Session) processNASpoof(ether Ether, p ICMP6NeighborAdvertisement, now time.Time) {
+	mac := p.TargetLLA()
+	if len(mac) != 6 {
+		mac = ether.Src()
+	}
+	h.checkSpoof(ether, p.TargetAddress(), mac, h.NICInfo().RouterLLA.Addr(), now)
+}
+
+// checkSpoof sends an EventGatewayImpersonation if mac claims the router ip and an EventMACFlapping
+// if the mac for ip changes too often. Events are sent at most once per minute for each ip.
+func (h *Session) checkSpoof(ether Ether, ip netip.Addr, mac net.HardwareAddr, routerIP netip.Addr, now time.Time) {
+	if !ip.IsValid() || bytes.Equal(mac, h.NICInfo().HostAddr4.MAC) || bytes.Equal(ether.Src(), h.NICInfo().HostAddr4.MAC) {
+		return
+	}
+	var events []Event
+
+	h.spoof.Lock()
+	if h.spoof.flaps == nil {
+		h.spoof.flaps = make(map[netip.Addr]*flapEntry)
+	}
+
+	if routerIP.IsValid() && ip == routerIP && len(h.NICInfo().RouterAddr4.MAC) == 6 && !bytes.Equal(mac, h.NICInfo().RouterAddr4.MAC) {
+		if h.spoof.events.allow(spoofEventKey{event: EventGatewayImpersonation, ip: ip}, now) {
+			events = append(events, Event{Type: EventGatewayImpersonation, Time: now, Addr: Addr{MAC: CopyMAC(mac), IP: ip},
+				Data: SpoofEvent{IP: ip, MAC: CopyMAC(h.NICInfo().RouterAddr4.MAC), SpoofMAC: CopyMAC(mac), Evidence: []Ether{Ether(CopyBytes(ether))}}})
+		}
+	}
+
+	entry := h.spoof.flaps[ip]
+	switch {
+	case entry == nil:
+		h.spoof.flaps[ip] = &flapEntry{mac: CopyMAC(mac)}
+	case !bytes.Equal(entry.mac, mac):
+		previous := entry.mac
+		entry.mac = CopyMAC(mac)
+		entry.changes = append(entry.changes, now)
+		entry.evidence = append(entry.evidence, Ether(CopyBytes(ether)))
+		for len(entry.changes) > 0 && now.Sub(entry.changes[0]) >= DefaultFlapWindow {
+			entry.changes = entry.changes[1:]
+			entry.evidence = entry.evidence[1:]
+		}
+		if len(entry.changes) >= DefaultFlapCount && h.spoof.events.allow(spoofEventKey{event: EventMACFlapping, ip: ip}, now) {
+			events = append(events, Event{Type: EventMACFlapping, Time: now, Addr: Addr{MAC: CopyMAC(mac), IP: ip},
+				Data: SpoofEvent{IP: ip, MAC: previous, SpoofMAC: CopyMAC(mac), Count: len(entry.changes), Evidence: entry.evidence}})
+			entry.changes = nil
+			entry.evidence = nil
+		}
+	}
+	h.spoof.Unlock()
+
+	for _, e := range events {
+		h.sendEvent(e)
+	}
+}
+
+// purgeImpersonation removes flap entries without recent changes and event times older than a minute.
+func (h *Session) purgeImpersonation(now time.Time) {
+	h.spoof.Lock()
+	defer h.spoof.Unlock()
+	for k, v := range h.spoof.flaps {
+		if n := len(v.changes); n == 0 || now.Sub(v.changes[n-1]) >= DefaultFlapWindow {
+			delete(h.spoof.flaps, k) // the entry is created again on the next packet
+		}
+	}
+	h.spoof.events.purge(now)
+}
@@ -0,0 +1,75 @@
+package packet
+
+import (
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newNAFrame(srcMAC net.HardwareAddr, target Addr) Ether {
+	ether := newEtherPacket(syscall.ETH_P_IPV6, srcMAC, EthernetBroadcast)
+	ip6 := EncodeIP6(ether.Payload(), 255, target.IP, IP6AllNodesMulticast)
+	ip6, _ = ip6.AppendPayload(ICMP6NeighborAdvertisementMarshal(true, false, true, target), syscall.IPPROTO_ICMPV6)
+	ether, _ = ether.SetPayload(ip6)
+	return ether
+}
+
+func TestSession_Impersonation(t *testing.T) {
+	session, _ := testSession()
+	routerLLA := netip.MustParseAddr("fe80::1")
+	updateTestNICInfo(session, func(info *NICInfo) { info.RouterLLA = netip.PrefixFrom(routerLLA, 64) })
+
+	tests := []struct {
+		name  string
+		frame Ether
+		want  EventType // zero if no spoof event is expected
+		mac   net.HardwareAddr
+	}{
+		{name: "router", frame: newARPFrame(ARPOperationReply, Addr{MAC: routerMAC, IP: routerIP4}, Addr{MAC: mac1, IP: ip1})},
+		{name: "our hunt", frame: newARPFrame(ARPOperationReply, Addr{MAC: hostMAC, IP: routerIP4}, Addr{MAC: mac1, IP: ip1})},
+		{name: "gateway arp", frame: newARPFrame(ARPOperationReply, Addr{MAC: mac1, IP: routerIP4}, Addr{MAC: mac2, IP: ip2}), want: EventGatewayImpersonation, mac: mac1},
+		{name: "rate limited", frame: newARPFrame(ARPOperationReply, Addr{MAC: mac1, IP: routerIP4}, Addr{MAC: mac2, IP: ip2})},
+		{name: "router lla", frame: newNAFrame(routerMAC, Addr{MAC: routerMAC, IP: routerLLA})},
+		{name: "gateway na", frame: newNAFrame(mac2, Addr{MAC: mac2, IP: routerLLA}), want: EventGatewayImpersonation, mac: mac2},
+		{name: "flap first", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: ip3}, Addr{MAC: EthernetZero, IP: ip2})},
+		{name: "flap 1", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac2, IP: ip3}, Addr{MAC: EthernetZero, IP: ip2})},
+		{name: "flap 2", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac1, IP: ip3}, Addr{MAC: EthernetZero, IP: ip2})},
+		{name: "flap 3", frame: newARPFrame(ARPOperationRequest, Addr{MAC: mac2, IP: ip3}, Addr{MAC: EthernetZero, IP: ip2}), want: EventMACFlapping, mac: mac2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := session.Parse(tt.frame); err != nil {
+				t.Fatal("unexpected parse error", err)
+			}
+			var events []Event
+			for len(session.Events) > 0 {
+				if e := <-session.Events; e.Type != EventIPConflict { // tested separately
+					events = append(events, e)
+				}
+			}
+			if tt.want == 0 {
+				if len(events) != 0 {
+					t.Fatalf("unexpected event %s", events[0])
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("expected one %s event got %d", tt.want, len(events))
+			}
+			data, ok := events[0].Data.(SpoofEvent)
+			if events[0].Type != tt.want || !ok || data.SpoofMAC.String() != tt.mac.String() || len(data.Evidence) == 0 {
+				t.Errorf("invalid event %s", events[0])
+			}
+			if tt.want == EventMACFlapping && (data.Count != DefaultFlapCount || len(data.Evidence) != DefaultFlapCount) {
+				t.Errorf("invalid flapping evidence %+v", data)
+			}
+		})
+	}
+
+	session.purgeImpersonation(time.Now().Add(time.Minute))
+	if n, m := len(session.spoof.flaps), session.spoof.events.len(); n != 0 || m != 0 {
+		t.Errorf("entries not purged flaps=%d events=%d", n, m)
+	}
+}
@@ -0,0 +1,200 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// InventoryStore persists the mac inventory so names, capture state and first seen times
+// survive a restart. The session loads the inventory in NewSession and saves a snapshot
+// every Config.StoreInterval and on Close.
+type InventoryStore interface {
+	Load() ([]InventoryEntry, error)
+	Save([]InventoryEntry) error
+}
+
+// InventoryEntry is the persistent part of a MACEntry.
+type InventoryEntry struct {
+	MAC          net.HardwareAddr `yaml:"-"`
+	Captured     bool
+	IP4          netip.Addr
+	IP6GUA       netip.Addr
+	IP6LLA       netip.Addr
+	Manufacturer string    `yaml:",omitempty"`
+	DHCP4Name    NameEntry `yaml:",omitempty"`
+	MDNSName     NameEntry `yaml:",omitempty"`
+	SSDPName     NameEntry `yaml:",omitempty"`
+	LLMNRName    NameEntry `yaml:",omitempty"`
+	NBNSName     NameEntry `yaml:",omitempty"`
+	WSDName      NameEntry `yaml:",omitempty"`
+	DeviceName   NameEntry `yaml:",omitempty"`
+	FirstSeen    time.Time
+	LastSeen     time.Time
+}
+
+// DefaultStoreInterval is the default interval between inventory snapshots.
+const DefaultStoreInterval = time.Minute * 5
+
+// Inventory returns a snapshot of the mac table.
+func (h *Session) Inventory() []InventoryEntry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]InventoryEntry, 0, len(h.MACTable.Table))
+	for _, e := range h.MACTable.Table {
+		e.Row.RLock()
+		list = append(list, InventoryEntry{
+			MAC: CopyMAC(e.MAC), Captured: e.Captured, IP4: e.IP4, IP6GUA: e.IP6GUA, IP6LLA: e.IP6LLA, Manufacturer: e.Manufacturer,
+			DHCP4Name: e.DHCP4Name, MDNSName: e.MDNSName, SSDPName: e.SSDPName, LLMNRName: e.LLMNRName,
+			NBNSName: e.NBNSName, WSDName: e.WSDName, DeviceName: e.DeviceName,
+			FirstSeen: e.FirstSeen, LastSeen: e.LastSeen,
+		})
+		e.Row.RUnlock()
+	}
+	return list
+}
+
+// restoreInventory recreates the mac entries in list. Entries are restored offline and without
+// hosts; hosts are created when traffic is seen and inherit the names and capture state.
+// The router is never restored as captured.
+func (h *Session) restoreInventory(list []InventoryEntry) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, v := range list {
+		if len(v.MAC) != EthAddrLen {
+			continue
+		}
+		e := h.MACTable.findOrCreate(v.MAC)
+		e.Captured = v.Captured && !e.IsRouter
+		if !e.IP4.IsValid() || e.IP4.IsUnspecified() {
+			e.IP4 = v.IP4
+		}
+		if !e.IP6GUA.IsValid() || e.IP6GUA.IsUnspecified() {
+			e.IP6GUA = v.IP6GUA
+		}
+		if !e.IP6LLA.IsValid() || e.IP6LLA.IsUnspecified() {
+			e.IP6LLA = v.IP6LLA
+		}
+		if e.Manufacturer == "" {
+			e.Manufacturer = v.Manufacturer
+		}
+		e.DHCP4Name, e.MDNSName, e.SSDPName, e.LLMNRName = v.DHCP4Name, v.MDNSName, v.SSDPName, v.LLMNRName
+		e.NBNSName, e.WSDName, e.DeviceName = v.NBNSName, v.WSDName, v.DeviceName
+		if !v.FirstSeen.IsZero() && v.FirstSeen.Before(e.FirstSeen) {
+			e.FirstSeen = v.FirstSeen
+		}
+		if v.LastSeen.After(e.LastSeen) {
+			e.LastSeen = v.LastSeen
+		}
+	}
+}
+
+// purgeMACEntries deletes mac entries without hosts that were not seen since cutoff. These are
+// typically entries restored from the inventory for devices that never came back; the caller
+// uses InventoryDeadline so names survive a long downtime. Router and captured entries are kept.
+func (h *Session) purgeMACEntries(cutoff time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	table := h.MACTable.Table[:0]
+	for _, e := range h.MACTable.Table {
+		e.Row.RLock()
+		lastSeen := e.LastSeen
+		if lastSeen.IsZero() {
+			lastSeen = e.FirstSeen
+		}
+		keep := len(e.HostList) > 0 || e.IsRouter || e.Captured || !lastSeen.Before(cutoff)
+		e.Row.RUnlock()
+		if keep {
+			table = append(table, e)
+			continue
+		}
+		if Logger.IsDebug() {
+			Logger.Msg("delete mac entry").MAC("mac", e.MAC).Write()
+		}
+	}
+	for i := len(table); i < len(h.MACTable.Table); i++ {
+		h.MACTable.Table[i] = nil // release deleted entries
+	}
+	h.MACTable.Table = table
+}
+
+// saveInventory saves a snapshot of the mac table to the session store if one is configured.
+func (h *Session) saveInventory() error {
+	if h.store == nil {
+		return nil
+	}
+	if err := h.store.Save(h.Inventory()); err != nil {
+		Logger.Msg("failed to save inventory").Error(err).Write()
+		return err
+	}
+	return nil
+}
+
+// YAMLStore is an InventoryStore that keeps the inventory in a yaml file.
+type YAMLStore struct {
+	Filename string
+	mutex    sync.Mutex
+}
+
+// NewYAMLStore returns an InventoryStore for filename. The file is created on the first save.
+func NewYAMLStore(filename string) *YAMLStore {
+	return &YAMLStore{Filename: filename}
+}
+
+// yamlInventoryEntry stores the mac in readable form
+type yamlInventoryEntry struct {
+	MAC            string
+	InventoryEntry `yaml:",inline"`
+}
+
+// Load returns the inventory in the file or an empty inventory if the file does not exist.
+func (s *YAMLStore) Load() ([]InventoryEntry, error) {
+	source, err := os.ReadFile(s.Filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var table []yamlInventoryEntry
+	if err := yaml.Unmarshal(source, &table); err != nil {
+		return nil, fmt.Errorf("invalid inventory file=%s: %w", s.Filename, err)
+	}
+	list := make([]InventoryEntry, 0, len(table))
+	for _, v := range table {
+		mac, err := net.ParseMAC(v.MAC)
+		if err != nil {
+			Logger.Msg("invalid inventory mac").String("mac", v.MAC).Error(err).Write()
+			continue
+		}
+		v.InventoryEntry.MAC = mac
+		list = append(list, v.InventoryEntry)
+	}
+	return list, nil
+}
+
+// Save writes the inventory to a temporary file and renames it so a crash never leaves a partial file.
+func (s *YAMLStore) Save(list []InventoryEntry) error {
+	table := make([]yamlInventoryEntry, 0, len(list))
+	for _, v := range list {
+		table = append(table, yamlInventoryEntry{MAC: v.MAC.String(), InventoryEntry: v})
+	}
+	stream, err := yaml.Marshal(table)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tmp := s.Filename + ".tmp"
+	if err := os.WriteFile(tmp, stream, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Filename)
+}
@@ -0,0 +1,101 @@
+package packet
+
+import (
+	"net/netip"
+	"os"
+	"testing"
+	"time"
+)
+
+func testInventorySession(t *testing.T, store InventoryStore) *Session {
+	conn, outConn := TestNewBufferedConn()
+	go TestReadAndDiscardLoop(outConn)
+	nicInfo := &NICInfo{
+		HomeLAN4:    netip.PrefixFrom(ip1, 24),
+		HostAddr4:   Addr{MAC: hostMAC, IP: hostIP4},
+		RouterAddr4: Addr{MAC: routerMAC, IP: routerIP4},
+	}
+	session, err := Config{Conn: conn, NICInfo: nicInfo, Store: store}.NewSession("")
+	if err != nil {
+		t.Fatal("cannot create session", err)
+	}
+	return session
+}
+
+func TestSession_Inventory(t *testing.T) {
+	store := NewYAMLStore(t.TempDir() + "/inventory.yaml")
+	firstSeen := time.Now().Add(-time.Hour * 24).Round(time.Second)
+
+	session := testInventorySession(t, store)
+	newTestHost(session, Addr{MAC: mac1, IP: ip1})
+	newTestHost(session, Addr{MAC: mac2, IP: ip2})
+	session.Capture(mac1)
+	session.DHCPv4Update(mac1, ip1, NameEntry{Type: "dhcp4", Name: "laptop"})
+	session.mutex.Lock()
+	entry, _ := session.MACTable.findMAC(mac1)
+	entry.FirstSeen = firstSeen
+	session.mutex.Unlock()
+	session.Close() // save on close
+
+	session = testInventorySession(t, store)
+	defer session.Close()
+	if n := len(session.MACTable.Table); n != 4 {
+		t.Fatalf("invalid mac table len want=4 got=%d", n)
+	}
+	if len(session.HostTable.Table) != 2 {
+		t.Errorf("hosts must not be restored len=%d", len(session.HostTable.Table))
+	}
+	if !session.IsCaptured(mac1) || session.IsCaptured(mac2) {
+		t.Error("invalid captured state")
+	}
+	entry, _ = session.MACTable.findMAC(mac1)
+	if entry.DHCP4Name.Name != "laptop" || entry.IP4 != ip1 || !entry.FirstSeen.Equal(firstSeen) || entry.Online {
+		t.Errorf("invalid restored entry %s firstSeen=%v", entry, entry.FirstSeen)
+	}
+
+	// hosts link to the restored entry
+	frame := newTestHost(session, Addr{MAC: mac1, IP: ip1})
+	if frame.Host.MACEntry != entry || !frame.Host.MACEntry.Captured {
+		t.Errorf("host not linked to restored entry %s", frame.Host)
+	}
+
+	// restored entries without hosts are kept after the purge deadline
+	session.purge(time.Now().Add(session.PurgeDeadline + time.Minute))
+	if e := session.FindMACEntry(mac2); e == nil {
+		t.Error("entry purged before the inventory deadline")
+	}
+
+	// and deleted after the inventory deadline
+	session.purgeMACEntries(time.Now().Add(time.Second))
+	if e := session.FindMACEntry(mac2); e != nil {
+		t.Errorf("entry not purged %s", e)
+	}
+	if session.FindMACEntry(mac1) == nil || session.FindMACEntry(routerMAC) == nil || session.FindMACEntry(hostMAC) == nil {
+		t.Error("entries with hosts must not be purged")
+	}
+}
+
+func TestYAMLStore_Load(t *testing.T) {
+	filename := t.TempDir() + "/inventory.yaml"
+	store := NewYAMLStore(filename)
+
+	// missing file is an empty inventory
+	if list, err := store.Load(); err != nil || len(list) != 0 {
+		t.Fatalf("unexpected load list=%v err=%v", list, err)
+	}
+
+	os.WriteFile(filename, []byte("- mac: 00:02:03:04:05:01\n  captured: true\n- mac: invalid\n"), 0o644)
+	list, err := store.Load()
+	if err != nil || len(list) != 1 || !list[0].Captured || list[0].MAC.String() != mac1.String() {
+		t.Fatalf("unexpected load list=%+v err=%v", list, err)
+	}
+
+	os.WriteFile(filename, []byte("{invalid"), 0o644)
+	if _, err := store.Load(); err == nil {
+		t.Error("expected error for invalid file")
+	}
+	conn, _ := TestNewBufferedConn()
+	if _, err := (Config{Conn: conn, NICInfo: &NICInfo{}, Store: store}).NewSession(""); err == nil {
+		t.Error("expected session error for invalid inventory")
+	}
+}
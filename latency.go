@@ -0,0 +1,206 @@
+package packet
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// LatencyConfig configures the latency monitor.
+//
+// The monitor pings every online host once per Interval and keeps the last History
+// results in Host.Latency. A host is degraded when the round trip time is DegradedFactor
+// times above its baseline, or when the packet loss reaches LossThreshold after a baseline exists.
+type LatencyConfig struct {
+	Interval       time.Duration // time between rounds; default 1 minute
+	Count          int           // echo requests per host in each round; default 3
+	Timeout        time.Duration // wait time for each reply; default 1 second
+	History        int           // number of samples kept per host; default 30
+	DegradedFactor float64       // rtt increase over baseline considered degraded; default 3
+	MinRTT         time.Duration // rtt below this value is never degraded; default 50 milliseconds
+	LossThreshold  float64       // packet loss considered degraded between 0 and 1; default 0.5
+	Concurrency    int           // number of hosts pinged in parallel; default 8
+}
+
+// LatencySample holds the result of a monitor round for a host.
+type LatencySample struct {
+	Time     time.Time
+	RTT      time.Duration // average round trip time; zero if all requests were lost
+	Jitter   time.Duration
+	Loss     float64 // fraction of lost echo requests between 0 and 1
+	degraded bool    // sample taken while degraded; excluded from the baseline
+}
+
+func (s LatencySample) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Duration("rtt", s.RTT)
+	l.Duration("jitter", s.Jitter)
+	l.Sprintf("loss", s.Loss)
+	return l
+}
+
+// LatencyEvent is the Data of EventLatencyDegraded and EventLatencyRecovered events.
+type LatencyEvent struct {
+	RTT      time.Duration // average round trip time in the last round
+	Baseline time.Duration // median round trip time of previous healthy rounds
+	Loss     float64       // packet loss in the last round
+	Reason   string        // "rtt" or "loss" for degraded events
+}
+
+func (e LatencyEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Duration("rtt", e.RTT)
+	l.Duration("baseline", e.Baseline)
+	l.Sprintf("loss", e.Loss)
+	if e.Reason != "" {
+		l.String("reason", e.Reason)
+	}
+	return l
+}
+
+// minimum number of healthy samples required to calculate a baseline
+const latencyBaselineSamples = 3
+
+func (c LatencyConfig) withDefaults() LatencyConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+	}
+	if c.Count <= 0 {
+		c.Count = 3
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = time.Second
+	}
+	if c.History <= 0 {
+		c.History = 30
+	}
+	if c.DegradedFactor <= 1 {
+		c.DegradedFactor = 3
+	}
+	if c.MinRTT <= 0 {
+		c.MinRTT = time.Millisecond * 50
+	}
+	if c.LossThreshold <= 0 || c.LossThreshold > 1 {
+		c.LossThreshold = 0.5
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 8
+	}
+	return c
+}
+
+// MonitorLatency pings all online hosts periodically and records the results in Host.Latency.
+// It sends EventLatencyDegraded when a host latency degrades or it starts dropping
+// echo requests, and EventLatencyRecovered when the host is healthy again.
+//
+// MonitorLatency blocks until ctx is done or the session is closed; call it in a goroutine.
+func (h *Session) MonitorLatency(ctx context.Context, config LatencyConfig) error {
+	config = config.withDefaults()
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+	for {
+		h.latencyRound(ctx, config)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-h.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// latencyRound pings every online host once and waits for all results.
+func (h *Session) latencyRound(ctx context.Context, config LatencyConfig) {
+	type target struct {
+		host *Host
+		addr Addr
+	}
+	var targets []target
+	for _, host := range h.GetHosts() {
+		host.MACEntry.Row.RLock()
+		if host.Online && host.Addr.IP != h.NICInfo().HostAddr4.IP {
+			targets = append(targets, target{host: host, addr: host.Addr})
+		}
+		host.MACEntry.Row.RUnlock()
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.Concurrency)
+	for _, t := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(t target) {
+			defer func() { <-sem; wg.Done() }()
+			stats, err := h.Pinger.Ping(ctx, t.addr, PingOptions{Count: config.Count, Interval: time.Millisecond * 200, Timeout: config.Timeout})
+			if err != nil && err != ErrTimeout {
+				if ctx.Err() == nil {
+					Logger.Msg("latency ping error").Struct(t.addr).Error(err).Write()
+				}
+				return
+			}
+			h.updateLatency(t.host, stats, config, time.Now())
+		}(t)
+	}
+	wg.Wait()
+}
+
+// updateLatency records the ping result for host and sends an event if the host
+// changed between healthy and degraded.
+func (h *Session) updateLatency(host *Host, stats PingStats, config LatencyConfig, now time.Time) {
+	sample := LatencySample{Time: now, Loss: stats.Loss, Jitter: stats.Jitter}
+	if stats.Received > 0 {
+		sample.RTT = stats.Avg
+	}
+
+	host.MACEntry.Row.Lock()
+	baseline := latencyBaseline(host.Latency)
+	var reason string
+	switch {
+	case (baseline > 0 || host.latencyDegraded) && sample.Loss >= config.LossThreshold: // a host that never answered is not degraded
+		reason = "loss"
+	case baseline > 0 && sample.RTT >= config.MinRTT && float64(sample.RTT) > float64(baseline)*config.DegradedFactor:
+		reason = "rtt"
+	}
+	sample.degraded = reason != ""
+	host.Latency = append(host.Latency, sample)
+	if n := len(host.Latency) - config.History; n > 0 {
+		host.Latency = append(host.Latency[:0], host.Latency[n:]...)
+	}
+	changed := sample.degraded != host.latencyDegraded
+	host.latencyDegraded = sample.degraded
+	addr := host.Addr
+	host.MACEntry.Row.Unlock()
+
+	if !changed {
+		return
+	}
+	e := Event{Type: EventLatencyRecovered, Time: now, Addr: addr,
+		Data: LatencyEvent{RTT: sample.RTT, Baseline: baseline, Loss: sample.Loss, Reason: reason}}
+	if sample.degraded {
+		e.Type = EventLatencyDegraded
+	}
+	h.sendEvent(e)
+}
+
+// latencyBaseline returns the median rtt of healthy samples or zero if there
+// are not enough samples.
+func latencyBaseline(samples []LatencySample) time.Duration {
+	rtt := make([]time.Duration, 0, len(samples))
+	for _, s := range samples {
+		if !s.degraded && s.RTT > 0 {
+			rtt = append(rtt, s.RTT)
+		}
+	}
+	if len(rtt) < latencyBaselineSamples {
+		return 0
+	}
+	sort.Slice(rtt, func(i, j int) bool { return rtt[i] < rtt[j] })
+	return rtt[len(rtt)/2]
+}
@@ -0,0 +1,81 @@
+package packet
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSession_updateLatency(t *testing.T) {
+	session, _ := testSession()
+	frame := newTestHost(session, Addr{MAC: mac1, IP: ip1})
+	config := LatencyConfig{History: 5}.withDefaults()
+	ms := time.Millisecond
+
+	tests := []struct {
+		name      string
+		stats     PingStats
+		wantEvent EventType // zero if no event is expected
+		reason    string
+	}{
+		{name: "lost without baseline", stats: PingStats{Loss: 1}},
+		{name: "first", stats: PingStats{Received: 3, Avg: 10 * ms}},
+		{name: "no baseline", stats: PingStats{Received: 3, Avg: 200 * ms}},
+		{name: "third", stats: PingStats{Received: 3, Avg: 20 * ms}},
+		{name: "within factor", stats: PingStats{Received: 3, Avg: 45 * ms}},
+		{name: "rtt degraded", stats: PingStats{Received: 3, Avg: 200 * ms}, wantEvent: EventLatencyDegraded, reason: "rtt"},
+		{name: "still degraded", stats: PingStats{Received: 3, Avg: 250 * ms}},
+		{name: "recovered", stats: PingStats{Received: 3, Avg: 20 * ms}, wantEvent: EventLatencyRecovered},
+		{name: "loss", stats: PingStats{Received: 1, Avg: 20 * ms, Loss: 2.0 / 3}, wantEvent: EventLatencyDegraded, reason: "loss"},
+		{name: "all lost", stats: PingStats{Loss: 1}},
+		{name: "loss recovered", stats: PingStats{Received: 3, Avg: 15 * ms}, wantEvent: EventLatencyRecovered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session.updateLatency(frame.Host, tt.stats, config, time.Now())
+			if tt.wantEvent == 0 {
+				if len(session.Events) != 0 {
+					t.Fatalf("unexpected event %s", <-session.Events)
+				}
+				return
+			}
+			if len(session.Events) != 1 {
+				t.Fatalf("expected event %s", tt.wantEvent)
+			}
+			e := <-session.Events
+			data, ok := e.Data.(LatencyEvent)
+			if e.Type != tt.wantEvent || !ok || data.Reason != tt.reason || e.Addr.IP != ip1 {
+				t.Errorf("invalid event %s", e)
+			}
+		})
+	}
+	if n := len(frame.Host.Latency); n != config.History {
+		t.Errorf("invalid history len %d", n)
+	}
+}
+
+func TestSession_MonitorLatency(t *testing.T) {
+	session, client := testSession()
+	go echoResponder(session, client)
+	frame := newTestHost(session, Addr{MAC: mac1, IP: ip1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- session.MonitorLatency(ctx, LatencyConfig{Interval: time.Millisecond * 20, Count: 1, Timeout: time.Millisecond * 50})
+	}()
+	time.Sleep(time.Millisecond * 100)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected canceled error got %v", err)
+	}
+
+	frame.Host.MACEntry.Row.RLock()
+	defer frame.Host.MACEntry.Row.RUnlock()
+	if len(frame.Host.Latency) < 2 {
+		t.Fatalf("expected latency samples got %d", len(frame.Host.Latency))
+	}
+	if s := frame.Host.Latency[0]; s.RTT <= 0 || s.Loss != 0 {
+		t.Errorf("invalid sample %+v", s)
+	}
+}
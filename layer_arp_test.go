@@ -145,7 +145,7 @@ func Test_Handler_ARPRequests(t *testing.T) {
 			}
 
 			// TODO: this test should be moved to Parse
-			// if bytes.Equal(SrcMAC(ether), session.NICInfo.HostAddr4.MAC) {
+			// if bytes.Equal(SrcMAC(ether), session.NICInfo().HostAddr4.MAC) {
 			// return
 			// }
 
@@ -0,0 +1,203 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// CDP TLV types
+const (
+	CDPTypeDeviceID        = 0x0001
+	CDPTypeAddresses       = 0x0002
+	CDPTypePortID          = 0x0003
+	CDPTypeCapabilities    = 0x0004
+	CDPTypeSoftwareVersion = 0x0005
+	CDPTypePlatform        = 0x0006
+	CDPTypeNativeVLAN      = 0x000a
+	CDPTypeDuplex          = 0x000b
+	CDPTypeMgmtAddresses   = 0x0016
+)
+
+// CDP capabilities bits
+const (
+	CDPCapabilityRouter    = 0x0001
+	CDPCapabilityTBBridge  = 0x0002
+	CDPCapabilitySRBridge  = 0x0004
+	CDPCapabilitySwitch    = 0x0008
+	CDPCapabilityHost      = 0x0010
+	CDPCapabilityIGMP      = 0x0020
+	CDPCapabilityRepeater  = 0x0040
+	CDPCapabilityTelephone = 0x0080
+)
+
+// Cisco SNAP organisation id and CDP protocol id
+var (
+	cdpOrganisationID = []byte{0x00, 0x00, 0x0c}
+	cdpProtocolID     = uint16(0x2000)
+)
+
+// CDP provides access to Cisco Discovery Protocol frames. CDP is sent in 802.3 LLC SNAP
+// frames to 01:00:0c:cc:cc:cc with organisation id 00:00:0c and protocol id 0x2000.
+//
+//	+--------+--------+--------+--------+
+//	| version| ttl    | checksum        |
+//	+--------+--------+--------+--------+
+//	| type (2)        | len (2)         | value ... (len includes the 4 byte header)
+//	+--------+--------+--------+--------+
+//
+// see https://en.wikipedia.org/wiki/Cisco_Discovery_Protocol
+type CDP []byte
+
+func (p CDP) IsValid() error {
+	if len(p) < 4 {
+		return fmt.Errorf("cdp len=%d: %w", len(p), ErrFrameLen)
+	}
+	pos := 4
+	for pos+4 <= len(p) {
+		l := int(binary.BigEndian.Uint16(p[pos+2 : pos+4]))
+		if l < 4 || pos+l > len(p) {
+			return fmt.Errorf("cdp tlv len=%d pos=%d: %w", l, pos, ErrParseFrame)
+		}
+		pos = pos + l
+	}
+	return nil
+}
+
+func (p CDP) Version() uint8     { return p[0] }
+func (p CDP) TTL() time.Duration { return time.Duration(p[1]) * time.Second }
+func (p CDP) Checksum() uint16   { return binary.BigEndian.Uint16(p[2:4]) }
+
+// GetTLV returns the value of the first TLV matching t or nil if not present.
+// The packet must be valid.
+func (p CDP) GetTLV(t uint16) []byte {
+	pos := 4
+	for pos+4 <= len(p) {
+		l := int(binary.BigEndian.Uint16(p[pos+2 : pos+4]))
+		if binary.BigEndian.Uint16(p[pos:pos+2]) == t {
+			return p[pos+4 : pos+l]
+		}
+		pos = pos + l
+	}
+	return nil
+}
+
+func (p CDP) DeviceID() string        { return string(p.GetTLV(CDPTypeDeviceID)) }
+func (p CDP) PortID() string          { return string(p.GetTLV(CDPTypePortID)) }
+func (p CDP) SoftwareVersion() string { return string(p.GetTLV(CDPTypeSoftwareVersion)) }
+func (p CDP) Platform() string        { return string(p.GetTLV(CDPTypePlatform)) }
+
+func (p CDP) Capabilities() uint32 {
+	if v := p.GetTLV(CDPTypeCapabilities); len(v) >= 4 {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (p CDP) NativeVLAN() uint16 {
+	if v := p.GetTLV(CDPTypeNativeVLAN); len(v) >= 2 {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+// Addresses returns the IPv4 and IPv6 addresses in the addresses TLV.
+func (p CDP) Addresses() []netip.Addr {
+	return cdpAddresses(p.GetTLV(CDPTypeAddresses))
+}
+
+// cdpAddresses decodes the address list format used in the address and management address TLVs
+//
+//	number of addresses (4) | protocol type (1) | protocol len (1) | protocol | address len (2) | address ...
+func cdpAddresses(v []byte) (list []netip.Addr) {
+	if len(v) < 4 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint32(v[0:4]))
+	pos := 4
+	for i := 0; i < n && pos+2 <= len(v); i++ {
+		pl := int(v[pos+1])
+		if pos+2+pl+2 > len(v) {
+			return list
+		}
+		al := int(binary.BigEndian.Uint16(v[pos+2+pl : pos+2+pl+2]))
+		if pos+2+pl+2+al > len(v) {
+			return list
+		}
+		if addr, ok := netip.AddrFromSlice(v[pos+2+pl+2 : pos+2+pl+2+al]); ok {
+			list = append(list, addr)
+		}
+		pos = pos + 2 + pl + 2 + al
+	}
+	return list
+}
+
+// LLDPCapabilities maps the CDP capabilities to the equivalent LLDP capabilities bits.
+func (p CDP) LLDPCapabilities() (c uint16) {
+	cdp := p.Capabilities()
+	if cdp&CDPCapabilityRouter != 0 {
+		c = c | LLDPCapabilityRouter
+	}
+	if cdp&(CDPCapabilityTBBridge|CDPCapabilitySRBridge|CDPCapabilitySwitch) != 0 {
+		c = c | LLDPCapabilityBridge
+	}
+	if cdp&CDPCapabilityHost != 0 {
+		c = c | LLDPCapabilityStation
+	}
+	if cdp&CDPCapabilityRepeater != 0 {
+		c = c | LLDPCapabilityRepeater
+	}
+	if cdp&CDPCapabilityTelephone != 0 {
+		c = c | LLDPCapabilityTelephone
+	}
+	return c
+}
+
+func (p CDP) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p CDP) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8("version", p.Version())
+	line.String("deviceID", p.DeviceID())
+	line.String("portID", p.PortID())
+	line.String("platform", p.Platform())
+	line.String("capabilities", lldpCapabilityString(p.LLDPCapabilities()))
+	for _, v := range p.Addresses() {
+		line.IP("addr", v)
+	}
+	if vlan := p.NativeVLAN(); vlan != 0 {
+		line.Uint16("vlan", vlan)
+	}
+	return line
+}
+
+// isCDP returns true if the SNAP frame carries CDP.
+func isCDP(snap SNAP) bool {
+	return bytes.Equal(snap.OrganisationID(), cdpOrganisationID) && snap.EtherType() == cdpProtocolID
+}
+
+// updateCDPNeighbour adds or refreshes the CDP neighbour in the neighbour table.
+// CDP neighbours share the LLDP table so callers see all switches in one place.
+func (h *Session) updateCDPNeighbour(addr Addr, p CDP) {
+	e := LLDPNeighbour{
+		Protocol:          PayloadCDP,
+		MAC:               CopyMAC(addr.MAC),
+		ChassisID:         p.DeviceID(),
+		PortID:            p.PortID(),
+		SystemName:        p.DeviceID(),
+		SystemDescription: p.SoftwareVersion(),
+		Platform:          p.Platform(),
+		Capabilities:      p.LLDPCapabilities(),
+		ManagementAddrs:   p.Addresses(),
+		PortVLANID:        p.NativeVLAN(),
+		TTL:               p.TTL(),
+		LastSeen:          time.Now(),
+	}
+	h.updateNeighbour(e)
+}
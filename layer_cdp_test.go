@@ -0,0 +1,63 @@
+package packet
+
+import (
+	"net/netip"
+	"testing"
+)
+
+var testCDPSwitch = []byte(
+	// 00:02:03:04:05:02 > 01:00:0c:cc:cc:cc, 802.3, length 81: LLC, dsap SNAP (0xaa) Individual, ssap SNAP (0xaa) Command, ctrl 0x03: oui Cisco (0x00000c), pid CDP (0x2000):
+	// CDPv2, ttl: 180s, Device-ID 'switch1', Address 192.168.0.2, Port-ID 'Gi0/1', Capability 0x28, Platform 'cisco WS-C2960', Native VLAN 10
+	`0100 0ccc cccc 0002 0304 0502 0051 aaaa` + //  ..............Q..
+		`0300 000c 2000 02b4 0000 0001 000b 7377` + //  ..............sw
+		`6974 6368 3100 0200 1100 0000 0101 01cc` + //  itch1...........
+		`0004 c0a8 0002 0003 0009 4769 302f 3100` + //  ..........Gi0/1.
+		`0400 0800 0000 2800 0600 1263 6973 636f` + //  ......(....cisco
+		`2057 532d 4332 3936 3000 0a00 0600 0a  `) //   WS-C2960......
+
+func TestCDP_Decode(t *testing.T) {
+	ether := Ether(mustHex(testCDPSwitch))
+	cdp := CDP(ether.Payload()[8:])
+	if err := cdp.IsValid(); err != nil {
+		t.Fatal("invalid cdp", err)
+	}
+	if cdp.Version() != 2 || cdp.TTL().Seconds() != 180 {
+		t.Errorf("invalid header version=%d ttl=%v", cdp.Version(), cdp.TTL())
+	}
+	if cdp.DeviceID() != "switch1" || cdp.PortID() != "Gi0/1" || cdp.Platform() != "cisco WS-C2960" {
+		t.Errorf("invalid tlvs %s", cdp)
+	}
+	if cdp.Capabilities() != CDPCapabilitySwitch|CDPCapabilityIGMP || cdp.LLDPCapabilities() != LLDPCapabilityBridge {
+		t.Errorf("invalid capabilities cdp=%x lldp=%x", cdp.Capabilities(), cdp.LLDPCapabilities())
+	}
+	if addrs := cdp.Addresses(); len(addrs) != 1 || addrs[0] != netip.MustParseAddr("192.168.0.2") {
+		t.Errorf("invalid addresses %v", addrs)
+	}
+	if cdp.NativeVLAN() != 10 {
+		t.Errorf("invalid native vlan %d", cdp.NativeVLAN())
+	}
+
+	// truncated tlv
+	if err := CDP(cdp[:len(cdp)-1]).IsValid(); err == nil {
+		t.Error("expected error for truncated tlv")
+	}
+}
+
+func TestSession_CDPNeighbours(t *testing.T) {
+	session, _ := testSession()
+
+	frame, err := session.Parse(mustHex(testCDPSwitch))
+	if err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if frame.PayloadID != PayloadCDP {
+		t.Errorf("invalid payloadID=%v", frame.PayloadID)
+	}
+	list := session.LLDPNeighbours()
+	if len(list) != 1 {
+		t.Fatalf("invalid neighbours %v", list)
+	}
+	if list[0].Protocol != PayloadCDP || list[0].ChassisID != "switch1" || list[0].PortID != "Gi0/1" || list[0].PortVLANID != 10 {
+		t.Errorf("invalid neighbour %v", list[0])
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/deeGraYve/packet/fastlog"
+	"golang.org/x/net/ipv6"
 )
 
 //go:generate stringer -type=PayloadID
@@ -44,6 +45,8 @@ const (
 	PayloadIEEE1905      PayloadID = 27
 	PayloadSonos         PayloadID = 28
 	Payload880a          PayloadID = 29
+	PayloadSTP           PayloadID = 30
+	PayloadCDP           PayloadID = 31
 )
 
 // Frame describes a network packet and the various protocol layers within it.
@@ -171,6 +174,40 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 	// while values of 1536 and above indicate that the field is used to represent an EtherType.
 	if frame.ether.EtherType() < 1536 {
 		frame.PayloadID = Payload8023
+		llc := LLC(frame.Payload())
+		if err := llc.IsValid(); err != nil {
+			return frame, err
+		}
+		h.Statistics[Payload8023].Count++
+
+		// the length field excludes the ethernet padding
+		llcLen := int(frame.ether.EtherType())
+		if llcLen > len(llc) {
+			llcLen = len(llc)
+		}
+		llc = llc[:llcLen]
+
+		switch {
+		case llc.DSAP() == 0x42 && llc.SSAP() == 0x42: // spanning tree BPDU
+			frame.PayloadID = PayloadSTP
+			frame.offsetPayload = frame.offsetPayload + 3
+			bpdu := BPDU(llc[3:])
+			if err := bpdu.IsValid(); err != nil {
+				return frame, err
+			}
+			h.Statistics[PayloadSTP].Count++
+			h.processBPDU(frame.SrcAddr, bpdu)
+
+		case llc.Type() == "snap" && len(llc) > 8 && isCDP(SNAP(llc)):
+			frame.PayloadID = PayloadCDP
+			frame.offsetPayload = frame.offsetPayload + 8
+			cdp := CDP(llc[8:])
+			if err := cdp.IsValid(); err != nil {
+				return frame, err
+			}
+			h.Statistics[PayloadCDP].Count++
+			h.updateCDPNeighbour(frame.SrcAddr, cdp)
+		}
 		return frame, nil
 	}
 
@@ -192,7 +229,7 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		// create host if ip is local lan IP (note that we may receive multicast and broadcast packets and should not create hosts for these)
 		// don't create host if packets sent via our interface.
 		// If we don't have this, then we received all sent and forwarded packets with client IPs containing our host mac
-		if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo.HostAddr4.MAC) && frame.Session.NICInfo.HomeLAN4.Contains(frame.SrcAddr.IP) {
+		if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) && frame.Session.NICInfo().HomeLAN4.Contains(frame.SrcAddr.IP) {
 			frame.Host, _ = frame.Session.findOrCreateHostWithLock(frame.SrcAddr) // will lock/unlock
 			if !frame.Host.Online {
 				frame.Session.onlineTransition(frame.Host)
@@ -212,6 +249,14 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		frame.DstAddr.IP = ip6.Dst()
 		frame.offsetIP6 = frame.offsetPayload
 		frame.offsetPayload = frame.offsetPayload + ip6.HeaderLen()
+		if proto == syscall.IPPROTO_HOPOPTS { // hop by hop extension with router alert used by MLD
+			hbh := HopByHopExtensionHeader(frame.Payload())
+			if !hbh.IsValid() {
+				return frame, ErrParseFrame
+			}
+			proto = hbh.NextHeader()
+			frame.offsetPayload = frame.offsetPayload + hbh.Len()
+		}
 		// create host if src IP is:
 		//     - unicast local link address (i.e. fe80::)
 		//     - global IP6 sent by a local host not the router
@@ -223,9 +268,12 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		//
 		// don't create host if packets sent via our interface.
 		// If we don't have this, then we received all sent and forwarded packets with client IPs containing our host mac
-		if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo.HostAddr4.MAC) &&
+		if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) &&
 			(frame.SrcAddr.IP.IsLinkLocalUnicast() ||
-				(frame.SrcAddr.IP.IsGlobalUnicast() && !bytes.Equal(frame.SrcAddr.MAC, frame.Session.NICInfo.RouterAddr4.MAC))) {
+				(frame.SrcAddr.IP.IsGlobalUnicast() && !bytes.Equal(frame.SrcAddr.MAC, frame.Session.NICInfo().RouterAddr4.MAC))) {
+			if frame.SrcAddr.IP.IsLinkLocalUnicast() { // before the host table moves the address to the new mac
+				h.processDeviceLLA(frame.SrcAddr.MAC, frame.SrcAddr.IP, time.Now())
+			}
 			frame.Host, _ = frame.Session.findOrCreateHostWithLock(frame.SrcAddr) // will lock/unlock
 			if !frame.Host.Online {
 				frame.Session.onlineTransition(frame.Host)
@@ -242,19 +290,26 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		}
 		h.Statistics[PayloadARP].Count++
 
+		// check for address conflicts before the host table is updated with the sender
+		if p := ARP(arp); p.IsValid() == nil && !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) {
+			h.processARPConflict(p, time.Now())
+			h.processARPSpoof(frame.Ether(), p, time.Now())
+		}
+
 		// create host if new IP appears in arp packet
 		// don't create host if packets sent via our interface.
 		// If we don't have this, then we received all sent and forwarded packets with client IPs containing our host mac
 		// Validates arp len and that hardware len is 6 for mac address
 		srcIP := netip.AddrFrom4(*((*[4]byte)(arp[14:18])))
-		if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo.HostAddr4.MAC) &&
-			frame.Session.NICInfo.HomeLAN4.Contains(srcIP) {
+		if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) &&
+			frame.Session.NICInfo().HomeLAN4.Contains(srcIP) {
 			addr := Addr{MAC: net.HardwareAddr(arp[8:14]), IP: srcIP}    // use arp src mac and ip for lookup
 			frame.Host, _ = frame.Session.findOrCreateHostWithLock(addr) // will lock/unlock
 			if !frame.Host.Online {
 				frame.Session.onlineTransition(frame.Host)
 				frame.flags = frame.markOnlineTransition()
 			}
+			h.scanNotify(addr) // stream the address if scanning
 		}
 		return frame, nil
 
@@ -266,14 +321,26 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 
 	case 0x8899: // Realtek remote control protocol (RRCP)
 		frame.PayloadID = PayloadRRCP
-		h.Statistics[PayloadRRCP].Count++
 		frame.offsetPayload = frame.Ether().HeaderLen()
+		p := RRCP(frame.Payload())
+		if err := p.IsValid(); err != nil {
+			return frame, err
+		}
+		h.Statistics[PayloadRRCP].Count++
+		if p.Protocol() == RRCPProtocolLoopDetection {
+			h.processRRCPLoop(frame.SrcAddr, p)
+		}
 		return frame, nil
 
 	case 0x88cc: // Local link discovery protocol (LLDP)
 		frame.PayloadID = PayloadLLDP
-		h.Statistics[PayloadLLDP].Count++
 		frame.offsetPayload = frame.Ether().HeaderLen()
+		lldp := LLDP(frame.Payload())
+		if err := lldp.IsValid(); err != nil {
+			return frame, err
+		}
+		h.Statistics[PayloadLLDP].Count++
+		h.updateLLDPNeighbour(frame.SrcAddr.MAC, lldp)
 		return frame, nil
 
 	case 0x890d: // 802.11r
@@ -284,14 +351,26 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 
 	case 0x893a: // IEEE 1905
 		frame.PayloadID = PayloadIEEE1905
-		h.Statistics[PayloadIEEE1905].Count++
 		frame.offsetPayload = frame.Ether().HeaderLen()
+		p := IEEE1905(frame.Payload())
+		if err := p.IsValid(); err != nil {
+			return frame, err
+		}
+		h.Statistics[PayloadIEEE1905].Count++
+		if err := h.processIEEE1905(p); err != nil {
+			return frame, err
+		}
 		return frame, nil
 
 	case 0x6970: // Sonos proprietary protocol
 		frame.PayloadID = PayloadSonos
-		h.Statistics[PayloadSonos].Count++
 		frame.offsetPayload = frame.Ether().HeaderLen()
+		p := Sonos(frame.Payload())
+		if err := p.IsValid(); err != nil {
+			return frame, err
+		}
+		h.Statistics[PayloadSonos].Count++
+		h.updateMACDeviceName(frame.SrcAddr.MAC, p.NameEntry())
 		return frame, nil
 
 	case 0x880a: // not sure what this is but seen often on home LANs
@@ -348,7 +427,8 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		case frame.DstAddr.Port == 137 || frame.DstAddr.Port == 138: // Netbions NBNS
 			frame.PayloadID = PayloadNBNS
 			h.Statistics[PayloadNBNS].Count++
-		case frame.DstAddr.Port == 32412 || frame.DstAddr.Port == 32414: // Plex application protocol
+		case frame.DstAddr.Port == 32412 || frame.DstAddr.Port == 32414 ||
+			frame.SrcAddr.Port == 32412 || frame.SrcAddr.Port == 32414: // Plex application protocol
 			frame.PayloadID = PayloadPlex
 			h.Statistics[PayloadPlex].Count++
 		case frame.SrcAddr.Port == 10001 || frame.DstAddr.Port == 10001: // Ubiquiti device discovery protocol
@@ -356,6 +436,44 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 			h.Statistics[PayloadUbiquiti].Count++
 		}
 		frame.offsetPayload = frame.offsetPayload + udp.HeaderLen() // only update offset if known header
+
+		// vendor discovery protocols carry the device name and model; dhcp replies carry the lan router and dns
+		//
+		// Discovery replies are sent from the service port to an ephemeral port; a frame that
+		// only matches the source port may belong to another protocol so it is not an error.
+		switch frame.PayloadID {
+		case PayloadUbiquiti:
+			p := UbiquitiDiscovery(frame.Payload())
+			if err := p.IsValid(); err != nil {
+				if frame.DstAddr.Port == 10001 {
+					return frame, err
+				}
+				break
+			}
+			if name := p.NameEntry(); frame.Host != nil && name.Type != "" {
+				frame.Host.UpdateDeviceName(name)
+			}
+		case PayloadPlex:
+			p := PlexGDM(frame.Payload())
+			if err := p.IsValid(); err != nil {
+				if frame.DstAddr.Port == 32412 || frame.DstAddr.Port == 32414 {
+					return frame, err
+				}
+				break
+			}
+			if name := p.NameEntry(); frame.Host != nil && name.Type != "" {
+				frame.Host.UpdateDeviceName(name)
+			}
+		case PayloadDHCP4:
+			// dhcp server replies; ignore our own offers when spoofing
+			if frame.SrcAddr.Port == DHCP4ServerPort && !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) {
+				h.processDHCP4Server(frame.SrcAddr, DHCP4(frame.Payload()), time.Now())
+			}
+			// dhcp client discover and request; link randomized macs to the same device
+			if frame.SrcAddr.Port == DHCP4ClientPort {
+				h.processDeviceDHCP4(DHCP4(frame.Payload()), time.Now())
+			}
+		}
 		return frame, nil
 
 	case syscall.IPPROTO_TCP:
@@ -375,13 +493,22 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		if err := icmpFrame.IsValid(); err != nil {
 			return frame, err
 		}
-		// process echo reply to unblock ping if running
-		if icmpFrame.Type() == ICMP4TypeEchoReply {
+		switch icmpFrame.Type() {
+		case ICMP4TypeEchoReply: // process echo reply to unblock ping if running
 			echo := ICMPEcho(icmpFrame)
 			if err := echo.IsValid(); err != nil {
 				return frame, err
 			}
-			echoNotify(echo.EchoID()) // unblock ping if waiting
+			h.Pinger.notify(echo.EchoID(), echo.EchoSeq()) // unblock ping if waiting
+			h.traceNotify(traceKey{proto: syscall.IPPROTO_ICMP, id: echo.EchoID(), seq: echo.EchoSeq()}, frame.SrcAddr.IP, true)
+		case ICMP4TypeDestinationUnreachable, ICMP4TypeTimeExceeded, ICMP4TypeParameterProblem:
+			p := ICMP4Error(icmpFrame)
+			if err := p.IsValid(); err != nil {
+				return frame, err
+			}
+			if !h.traceNotifyICMP4Error(frame.SrcAddr.IP, p) { // replies to traceroute probes are not errors
+				h.processICMP4Error(frame.SrcAddr, p)
+			}
 		}
 		frame.PayloadID = PayloadICMP4
 		h.Statistics[PayloadICMP4].Count++
@@ -392,13 +519,48 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 		if err := icmpFrame.IsValid(); err != nil {
 			return frame, err
 		}
-		// process echo reply to unblock ping if running
-		if icmpFrame.Type() == ICMP6TypeEchoReply {
+		switch icmpFrame.Type() {
+		case ICMP6TypeEchoReply: // process echo reply to unblock ping if running
 			echo := ICMPEcho(icmpFrame)
 			if err := echo.IsValid(); err != nil {
 				return frame, err
 			}
-			echoNotify(echo.EchoID()) // unblock ping if waiting
+			h.Pinger.notify(echo.EchoID(), echo.EchoSeq()) // unblock ping if waiting
+		case byte(ipv6.ICMPTypeNeighborSolicitation):
+			ns := ICMP6NeighborSolicitation(icmpFrame)
+			if err := ns.IsValid(); err != nil {
+				return frame, err
+			}
+			if frame.SrcAddr.IP.IsUnspecified() { // duplicate address detection
+				h.processDADConflict(frame.SrcAddr.MAC, ns, time.Now())
+			}
+		case byte(ipv6.ICMPTypeNeighborAdvertisement):
+			na := ICMP6NeighborAdvertisement(icmpFrame)
+			if err := na.IsValid(); err != nil {
+				return frame, err
+			}
+			h.scanNotify(Addr{MAC: frame.SrcAddr.MAC, IP: na.TargetAddress()}) // stream the address if scanning
+			// ignore our own spoofing
+			if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) {
+				h.processNASpoof(frame.Ether(), na, time.Now())
+			}
+		case byte(ipv6.ICMPTypeRouterAdvertisement):
+			ra := ICMP6RouterAdvertisement(icmpFrame)
+			if err := ra.IsValid(); err != nil {
+				return frame, err
+			}
+			// ignore our own advertisements
+			if !bytes.Equal(frame.SrcAddr.MAC, h.NICInfo().HostAddr4.MAC) {
+				h.processRAGuard(frame.SrcAddr, ra, time.Now())
+			}
+		case ICMP6TypeMLDv1Report, ICMP6TypeMLDv1Done, ICMP6TypeMLDv2Report:
+			mld := MLD(icmpFrame)
+			if err := mld.IsValid(); err != nil {
+				return frame, err
+			}
+			if frame.Host != nil {
+				frame.Host.updateMulticastGroups(mld.Memberships(), time.Now())
+			}
 		}
 		frame.PayloadID = PayloadICMP6
 		h.Statistics[PayloadICMP6].Count++
@@ -406,7 +568,14 @@ func (h *Session) Parse(p []byte) (frame Frame, err error) {
 
 	case syscall.IPPROTO_IGMP:
 		frame.PayloadID = PayloadIGMP
+		igmp := IGMP(frame.Payload())
+		if err := igmp.IsValid(); err != nil {
+			return frame, err
+		}
 		h.Statistics[PayloadIGMP].Count++
+		if frame.Host != nil {
+			frame.Host.updateMulticastGroups(igmp.Memberships(), time.Now())
+		}
 		return frame, nil
 	}
 	return frame, nil
@@ -431,6 +600,9 @@ func (h *Session) onlineTransition(host *Host) {
 			if line != nil {
 				line.IP("previous", host.MACEntry.IP4)
 			}
+			if host.MACEntry.IP4.IsValid() && !host.MACEntry.IP4.IsUnspecified() {
+				h.publishHost(EventIPChanged, host.Addr, IPChangeEvent{Addr: host.Addr, Previous: Addr{MAC: host.Addr.MAC, IP: host.MACEntry.IP4}})
+			}
 			host.MACEntry.IP4 = host.Addr.IP
 			for _, v := range host.MACEntry.HostList {
 				if v.Addr.IP.Is4() && v.Addr.IP != host.Addr.IP {
@@ -449,6 +621,9 @@ func (h *Session) onlineTransition(host *Host) {
 			if line != nil {
 				line.IP("previous", host.MACEntry.IP6GUA)
 			}
+			if host.MACEntry.IP6GUA.IsValid() && !host.MACEntry.IP6GUA.IsUnspecified() {
+				h.publishHost(EventIPChanged, host.Addr, IPChangeEvent{Addr: host.Addr, Previous: Addr{MAC: host.Addr.MAC, IP: host.MACEntry.IP6GUA}})
+			}
 			host.MACEntry.IP6GUA = host.Addr.IP
 		}
 		if host.Addr.IP.IsLinkLocalUnicast() && host.Addr.IP != host.MACEntry.IP6LLA { // changed IP6 link local address
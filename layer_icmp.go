@@ -14,10 +14,30 @@ import (
 )
 
 const (
-	ICMP4TypeEchoReply   = 0   // Echo Request
-	ICMP4TypeEchoRequest = 8   // Echo Reply
-	ICMP6TypeEchoRequest = 128 // Echo Request
-	ICMP6TypeEchoReply   = 129 // Echo Reply
+	ICMP4TypeEchoReply              = 0   // Echo Request
+	ICMP4TypeDestinationUnreachable = 3   // Destination Unreachable
+	ICMP4TypeEchoRequest            = 8   // Echo Reply
+	ICMP4TypeTimeExceeded           = 11  // Time Exceeded
+	ICMP4TypeParameterProblem       = 12  // Parameter Problem
+	ICMP6TypeEchoRequest            = 128 // Echo Request
+	ICMP6TypeEchoReply              = 129 // Echo Reply
+)
+
+// ICMP4 destination unreachable codes
+const (
+	ICMP4CodeNetUnreachable      = 0
+	ICMP4CodeHostUnreachable     = 1
+	ICMP4CodeProtocolUnreachable = 2
+	ICMP4CodePortUnreachable     = 3
+	ICMP4CodeFragmentationNeeded = 4 // fragmentation needed and DF set
+	ICMP4CodeSourceRouteFailed   = 5
+	ICMP4CodeAdminProhibited     = 13 // communication administratively prohibited
+)
+
+// ICMP4 time exceeded codes
+const (
+	ICMP4CodeTTLExceeded        = 0 // time to live exceeded in transit
+	ICMP4CodeReassemblyExceeded = 1 // fragment reassembly time exceeded
 )
 
 // ICMP enable access to ICMP frame without copying
@@ -178,6 +198,188 @@ func (p ICMP4Redirect) FastLog(line *fastlog.Line) *fastlog.Line {
 	return line
 }
 
+// ICMP4Error provides access to ICMPv4 error messages: destination unreachable,
+// time exceeded and parameter problem. Fragmentation needed is a destination
+// unreachable with code 4.
+//
+// All error messages carry the original IP header plus the first 8 bytes of the
+// original datagram so the error can be attributed to the originating flow.
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     Type      |     Code      |          Checksum             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|    Pointer    |    unused     |         Next-Hop MTU          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|      Internet Header + 64 bits of Original Data Datagram      |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// see https://datatracker.ietf.org/doc/html/rfc792 and rfc1191 for next-hop MTU
+type ICMP4Error []byte
+
+func (p ICMP4Error) IsValid() error {
+	if len(p) < 8+20 {
+		return fmt.Errorf("icmp error header too short len=%d: %w", len(p), ErrFrameLen)
+	}
+	switch p.Type() {
+	case ICMP4TypeDestinationUnreachable, ICMP4TypeTimeExceeded, ICMP4TypeParameterProblem:
+	default:
+		return fmt.Errorf("icmp error invalid type=%d: %w", p.Type(), ErrParseFrame)
+	}
+	if ip := IP4(p[8:]); ip.Version() != 4 || ip.IHL() < 20 || len(p) < 8+ip.IHL() {
+		return fmt.Errorf("icmp error invalid original header len=%d: %w", len(p), ErrParseFrame)
+	}
+	return nil
+}
+
+func (p ICMP4Error) Type() uint8      { return p[0] }
+func (p ICMP4Error) Code() uint8      { return p[1] }
+func (p ICMP4Error) Checksum() uint16 { return binary.BigEndian.Uint16(p[2:4]) }
+func (p ICMP4Error) Pointer() uint8   { return p[4] } // parameter problem: offset of the octet in error
+
+// NextHopMTU returns the MTU of the next hop for fragmentation needed messages.
+// It returns zero if the router does not support RFC 1191.
+func (p ICMP4Error) NextHopMTU() uint16 { return binary.BigEndian.Uint16(p[6:8]) }
+
+// IsFragmentationNeeded returns true if the message is a fragmentation needed and DF set.
+func (p ICMP4Error) IsFragmentationNeeded() bool {
+	return p.Type() == ICMP4TypeDestinationUnreachable && p.Code() == ICMP4CodeFragmentationNeeded
+}
+
+// OriginalIP returns the original IP header. The original datagram is truncated
+// so do not call Payload() on the returned header; use OriginalL4 instead.
+func (p ICMP4Error) OriginalIP() IP4 { return IP4(p[8 : 8+IP4(p[8:]).IHL()]) }
+
+// OriginalL4 returns the first 8 bytes of the original datagram or less if the
+// router truncated the datagram.
+func (p ICMP4Error) OriginalL4() []byte {
+	b := p[8+IP4(p[8:]).IHL():]
+	if len(b) > 8 {
+		return b[:8]
+	}
+	return b
+}
+
+// OriginalSrc returns the source address of the original datagram including
+// the port for UDP and TCP.
+func (p ICMP4Error) OriginalSrc() Addr {
+	addr := Addr{IP: p.OriginalIP().Src()}
+	if l4 := p.OriginalL4(); len(l4) >= 4 && p.isTransport() {
+		addr.Port = binary.BigEndian.Uint16(l4[0:2])
+	}
+	return addr
+}
+
+// OriginalDst returns the destination address of the original datagram including
+// the port for UDP and TCP.
+func (p ICMP4Error) OriginalDst() Addr {
+	addr := Addr{IP: p.OriginalIP().Dst()}
+	if l4 := p.OriginalL4(); len(l4) >= 4 && p.isTransport() {
+		addr.Port = binary.BigEndian.Uint16(l4[2:4])
+	}
+	return addr
+}
+
+func (p ICMP4Error) isTransport() bool {
+	proto := p.OriginalIP().Protocol()
+	return proto == syscall.IPPROTO_UDP || proto == syscall.IPPROTO_TCP
+}
+
+func (p ICMP4Error) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p ICMP4Error) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8("type", p.Type())
+	line.Uint8("code", p.Code())
+	line.Uint8("proto", p.OriginalIP().Protocol())
+	line.Struct(p.OriginalSrc())
+	line.Struct(p.OriginalDst())
+	switch {
+	case p.IsFragmentationNeeded():
+		line.Uint16("mtu", p.NextHopMTU())
+	case p.Type() == ICMP4TypeParameterProblem:
+		line.Uint8("pointer", p.Pointer())
+	}
+	return line
+}
+
+// ICMPErrorEvent is the event data sent when an ICMP error is received for a flow.
+type ICMPErrorEvent struct {
+	Type     uint8
+	Code     uint8
+	Router   netip.Addr // sender of the icmp error
+	Protocol uint8      // protocol of the original datagram
+	Src      Addr       // source of the original datagram
+	Dst      Addr       // destination of the original datagram
+	MTU      uint16     // next hop mtu for fragmentation needed
+}
+
+func (e ICMPErrorEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Uint8("type", e.Type)
+	l.Uint8("code", e.Code)
+	l.IP("router", e.Router)
+	l.Uint8("proto", e.Protocol)
+	l.Struct(e.Src)
+	l.Struct(e.Dst)
+	if e.MTU != 0 {
+		l.Uint16("mtu", e.MTU)
+	}
+	return l
+}
+
+// icmpErrorKey is the error type, code and original flow.
+type icmpErrorKey struct {
+	typ, code, protocol uint8
+	src, dst            netip.AddrPort
+}
+
+type icmpErrorState struct {
+	sync.Mutex
+	events eventLimiter[icmpErrorKey]
+}
+
+// processICMP4Error attributes the error to the originating flow and host and sends
+// a destination unreachable or fragmentation needed event. Events are sent at most once
+// per minute for each flow. Time exceeded and parameter problem are only logged.
+func (h *Session) processICMP4Error(srcAddr Addr, p ICMP4Error) {
+	e := ICMPErrorEvent{Type: p.Type(), Code: p.Code(), Router: srcAddr.IP, Protocol: p.OriginalIP().Protocol(),
+		Src: p.OriginalSrc(), Dst: p.OriginalDst()}
+	var eventType EventType
+	switch {
+	case p.IsFragmentationNeeded():
+		e.MTU = p.NextHopMTU()
+		eventType = EventICMPFragmentationNeeded
+	case p.Type() == ICMP4TypeDestinationUnreachable:
+		eventType = EventICMPDestinationUnreachable
+	default:
+		if Logger.IsDebug() {
+			Logger.Msg("icmp4 error").Struct(e).Write()
+		}
+		return
+	}
+
+	now := time.Now()
+	key := icmpErrorKey{typ: e.Type, code: e.Code, protocol: e.Protocol,
+		src: netip.AddrPortFrom(e.Src.IP, e.Src.Port), dst: netip.AddrPortFrom(e.Dst.IP, e.Dst.Port)}
+	h.icmpErrors.Lock()
+	allow := h.icmpErrors.events.allow(key, now)
+	h.icmpErrors.Unlock()
+	if !allow {
+		return
+	}
+
+	h.mutex.RLock()
+	if host := h.findIP(e.Src.IP); host != nil { // attribute to the originating host
+		e.Src.MAC = host.Addr.MAC
+	}
+	h.mutex.RUnlock()
+
+	h.sendEvent(Event{Type: eventType, Time: now, Addr: e.Src, Data: e})
+}
+
 type ICMP6RouterSolicitation []byte
 
 func (p ICMP6RouterSolicitation) IsValid() error {
@@ -431,7 +633,7 @@ func (h *Session) icmp4SendPacket(srcAddr Addr, dstAddr Addr, p ICMP) (err error
 	buf := EtherBufferPool.Get().(*[EthMaxSize]byte)
 	defer EtherBufferPool.Put(buf)
 	ether := Ether(buf[:])
-	ether = EncodeEther(ether, syscall.ETH_P_IP, h.NICInfo.HostAddr4.MAC, dstAddr.MAC)
+	ether = EncodeEther(ether, syscall.ETH_P_IP, h.NICInfo().HostAddr4.MAC, dstAddr.MAC)
 	ip4 := EncodeIP4(ether.Payload(), 50, srcAddr.IP, dstAddr.IP)
 	ICMP(p).SetChecksum(Checksum(p))
 	if ip4, err = ip4.AppendPayload(p, syscall.IPPROTO_ICMP); err != nil {
@@ -473,7 +675,7 @@ func (h *Session) icmp6SendPacket(srcAddr Addr, dstAddr Addr, b []byte) error {
 		hopLimit = 255
 	}
 
-	ether = EncodeEther(ether, syscall.ETH_P_IPV6, h.NICInfo.HostAddr4.MAC, dstAddr.MAC)
+	ether = EncodeEther(ether, syscall.ETH_P_IPV6, h.NICInfo().HostAddr4.MAC, dstAddr.MAC)
 	ip6 := EncodeIP6(ether.Payload(), hopLimit, srcAddr.IP, dstAddr.IP)
 	ip6, _ = ip6.AppendPayload(b, syscall.IPPROTO_ICMPV6)
 	ether, _ = ether.SetPayload(ip6)
@@ -502,110 +704,6 @@ func (h *Session) icmp6SendPacket(srcAddr Addr, dstAddr Addr, b []byte) error {
 	return nil
 }
 
-type icmpEntry struct {
-	msgRecv bool
-	expire  time.Time
-	wakeup  chan bool
-}
-
-var icmpTable = struct {
-	sync.Mutex
-	table map[uint16]*icmpEntry // must use pointer because of channel in struct
-	id    uint16
-}{
-	table: make(map[uint16]*icmpEntry),
-	id:    1,
-}
-
-func echoNotify(id uint16) {
-	icmpTable.Lock()
-	if len(icmpTable.table) <= 0 {
-		icmpTable.Unlock()
-		return
-	}
-
-	if entry, ok := icmpTable.table[id]; ok {
-		entry.msgRecv = true
-		close(entry.wakeup)
-		delete(icmpTable.table, id)
-	}
-	icmpTable.Unlock()
-}
-
-// Ping send a ping request and wait for a reply
-func (h *Session) Ping6(srcAddr Addr, dstAddr Addr, timeout time.Duration) (err error) {
-	if timeout <= 0 || timeout > time.Second*10 {
-		timeout = time.Second * 2
-	}
-	msg := icmpEntry{expire: time.Now().Add(timeout), wakeup: make(chan bool)}
-	seq := uint16(1)
-
-	icmpTable.Lock()
-	id := icmpTable.id
-	icmpTable.id++
-	icmpTable.table[id] = &msg
-	icmpTable.Unlock()
-
-	if err = h.ICMP6SendEchoRequest(srcAddr, dstAddr, id, seq); err != nil {
-		return err
-	}
-
-	// wait until chan closed or timeout
-	select {
-	case <-msg.wakeup:
-	case <-time.After(timeout):
-	}
-
-	// in case of timeout, the entry still exist
-	icmpTable.Lock()
-	delete(icmpTable.table, id)
-	icmpTable.Unlock()
-
-	if !msg.msgRecv {
-		return ErrTimeout
-	}
-	return nil
-}
-
-// Ping send a ping request and wait for a reply
-func (h *Session) Ping(dstAddr Addr, timeout time.Duration) (err error) {
-	return h.ping(h.NICInfo.HostAddr4, dstAddr, timeout)
-}
-
-func (h *Session) ping(srcAddr Addr, dstAddr Addr, timeout time.Duration) (err error) {
-	if timeout <= 0 || timeout > time.Second*10 {
-		timeout = time.Second * 2
-	}
-	msg := icmpEntry{expire: time.Now().Add(timeout), wakeup: make(chan bool)}
-	seq := uint16(1)
-
-	icmpTable.Lock()
-	id := icmpTable.id
-	icmpTable.id++
-	icmpTable.table[id] = &msg
-	icmpTable.Unlock()
-
-	if err = h.ICMP4SendEchoRequest(srcAddr, dstAddr, id, seq); err != nil {
-		return err
-	}
-
-	// wait until chan closed or timeout
-	select {
-	case <-msg.wakeup:
-	case <-time.After(timeout):
-	}
-
-	// in case of timeout, the entry still exist
-	icmpTable.Lock()
-	delete(icmpTable.table, id)
-	icmpTable.Unlock()
-
-	if !msg.msgRecv {
-		return ErrTimeout
-	}
-	return nil
-}
-
 // ValidateDefaultRouter validates the default route is pointing to us by pinging
 // client using home router IP as source IP. The reply will come to us
 // when the default route on client is netfilter. If not, the ping
@@ -622,16 +720,23 @@ func (h *Session) ValidateDefaultRouter(addr Addr) error {
 	}
 
 	// first attempt
-	err := h.ping(Addr{MAC: h.NICInfo.HostAddr4.MAC, IP: h.NICInfo.RouterAddr4.IP}, addr, time.Second*2)
+	err := h.ping(Addr{MAC: h.NICInfo().HostAddr4.MAC, IP: h.NICInfo().RouterAddr4.IP}, addr, time.Second*2)
 	if err == nil {
 		return nil
 	}
 
 	// second attempt
-	err = h.ping(Addr{MAC: h.NICInfo.HostAddr4.MAC, IP: h.NICInfo.RouterAddr4.IP}, addr, time.Second*2)
+	err = h.ping(Addr{MAC: h.NICInfo().HostAddr4.MAC, IP: h.NICInfo().RouterAddr4.IP}, addr, time.Second*2)
 	if err == nil {
 		return nil
 	}
 
 	return ErrNotRedirected
 }
+
+// purgeICMPErrors deletes event times older than a minute.
+func (h *Session) purgeICMPErrors(now time.Time) {
+	h.icmpErrors.Lock()
+	defer h.icmpErrors.Unlock()
+	h.icmpErrors.events.purge(now)
+}
@@ -250,10 +250,10 @@ func (h *Session) ICMP6SendRouterAdvertisement(prefixes []PrefixInformation, rdn
 			// TODO: single source of truth for search domain name
 			DomainNames: []string{"lan"},
 		},
-		NewMTU(uint32(h.NICInfo.IFI.MTU)),
+		NewMTU(uint32(h.NICInfo().IFI.MTU)),
 		&LinkLayerAddress{
 			Direction: Source,
-			MAC:       h.NICInfo.HostAddr4.MAC,
+			MAC:       h.NICInfo().HostAddr4.MAC,
 		},
 	)
 
@@ -268,7 +268,7 @@ func (h *Session) ICMP6SendRouterAdvertisement(prefixes []PrefixInformation, rdn
 		return err
 	}
 
-	return h.icmp6SendPacket(Addr{MAC: h.NICInfo.HostAddr4.MAC, IP: h.NICInfo.HostLLA.Addr()}, dstAddr, mb)
+	return h.icmp6SendPacket(Addr{MAC: h.NICInfo().HostAddr4.MAC, IP: h.NICInfo().HostLLA.Addr()}, dstAddr, mb)
 }
 
 func (h *Session) ICMP6SendRouterSolicitation() error {
@@ -276,7 +276,7 @@ func (h *Session) ICMP6SendRouterSolicitation() error {
 		Options: []Option{
 			&LinkLayerAddress{
 				Direction: Source,
-				MAC:       h.NICInfo.HostAddr4.MAC,
+				MAC:       h.NICInfo().HostAddr4.MAC,
 			},
 		},
 	}
@@ -285,7 +285,7 @@ func (h *Session) ICMP6SendRouterSolicitation() error {
 		return err
 	}
 
-	return h.icmp6SendPacket(Addr{MAC: h.NICInfo.HostAddr4.MAC, IP: h.NICInfo.HostLLA.Addr()}, IP6AllRoutersAddr, mb)
+	return h.icmp6SendPacket(Addr{MAC: h.NICInfo().HostAddr4.MAC, IP: h.NICInfo().HostLLA.Addr()}, IP6AllRoutersAddr, mb)
 }
 
 func (h *Session) ICMP6SendNeighborAdvertisement(srcAddr Addr, dstAddr Addr, targetAddr Addr) error {
@@ -296,7 +296,7 @@ func (h *Session) ICMP6SendNeighborAdvertisement(srcAddr Addr, dstAddr Addr, tar
 
 // SendNeighbourSolicitation send an ICMP6 NS
 func (h *Session) ICMP6SendNeighbourSolicitation(srcAddr Addr, dstAddr Addr, targetIP netip.Addr) error {
-	p, _ := ICMP6NeighborSolicitationMarshal(targetIP, h.NICInfo.HostAddr4.MAC)
+	p, _ := ICMP6NeighborSolicitationMarshal(targetIP, h.NICInfo().HostAddr4.MAC)
 
 	if Logger.IsDebug() {
 		Logger.Msg("send NS request - src").Struct(srcAddr).Label("dst").Struct(dstAddr).IP("targetip", targetIP).Write()
@@ -1,6 +1,7 @@
 package packet
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net/netip"
 	"syscall"
@@ -226,8 +227,8 @@ func TestSession_Ping(t *testing.T) {
 			panic(err)
 		}
 		out := make([]byte, EthMaxSize)
-		ether = EncodeEther(out, syscall.ETH_P_IP, addr.MAC, session.NICInfo.HostAddr4.MAC)
-		ip4 = EncodeIP4(ether.Payload(), 64, addr.IP, session.NICInfo.HostAddr4.IP)
+		ether = EncodeEther(out, syscall.ETH_P_IP, addr.MAC, session.NICInfo().HostAddr4.MAC)
+		ip4 = EncodeIP4(ether.Payload(), 64, addr.IP, session.NICInfo().HostAddr4.IP)
 		e := EncodeICMPEcho(ip4.Payload(), ICMP4TypeEchoReply, echo.Code(), echo.EchoID(), echo.EchoSeq(), echo.EchoData())
 		ip4 = ip4.SetPayload(e, syscall.IPPROTO_ICMP)
 		ether, _ = ether.SetPayload(ip4)
@@ -242,3 +243,101 @@ func TestSession_Ping(t *testing.T) {
 		t.Errorf("Session.Ping() error = %v", err)
 	}
 }
+
+// newICMP4Error returns an icmp error from the router to host for a udp datagram from host to dst
+func newICMP4Error(t uint8, code uint8, mtu uint16, dst Addr) []byte {
+	// original datagram: ip header + first 8 bytes of udp
+	orig := EncodeIP4(make([]byte, 60), 64, hostIP4, dst.IP)
+	udp := EncodeUDP(orig.Payload(), 40000, dst.Port)
+	orig = orig.SetPayload(udp, syscall.IPPROTO_UDP)
+	orig = orig[:orig.IHL()+8]
+
+	icmp := make([]byte, 8+len(orig))
+	icmp[0] = t
+	icmp[1] = code
+	binary.BigEndian.PutUint16(icmp[6:8], mtu)
+	copy(icmp[8:], orig)
+
+	ether := EncodeEther(make([]byte, EthMaxSize), syscall.ETH_P_IP, routerMAC, hostMAC)
+	ip4 := EncodeIP4(ether.Payload(), 64, routerIP4, hostIP4)
+	ip4, _ = ip4.AppendPayload(icmp, syscall.IPPROTO_ICMP)
+	ether, _ = ether.SetPayload(ip4)
+	return ether
+}
+
+func TestICMP4Error_Decode(t *testing.T) {
+	dst := Addr{IP: netip.MustParseAddr("8.8.8.8"), Port: 53}
+	tests := []struct {
+		name     string
+		p        ICMP4Error
+		wantErr  bool
+		wantFrag bool
+		wantMTU  uint16
+	}{
+		{name: "port unreachable", p: ICMP4Error(IP4(Ether(newICMP4Error(ICMP4TypeDestinationUnreachable, ICMP4CodePortUnreachable, 0, dst)).Payload()).Payload())},
+		{name: "fragmentation needed", p: ICMP4Error(IP4(Ether(newICMP4Error(ICMP4TypeDestinationUnreachable, ICMP4CodeFragmentationNeeded, 1400, dst)).Payload()).Payload()),
+			wantFrag: true, wantMTU: 1400},
+		{name: "time exceeded", p: ICMP4Error(IP4(Ether(newICMP4Error(ICMP4TypeTimeExceeded, ICMP4CodeTTLExceeded, 0, dst)).Payload()).Payload())},
+		{name: "echo", p: ICMP4Error(append([]byte{ICMP4TypeEchoReply}, make([]byte, 40)...)), wantErr: true},
+		{name: "short", p: ICMP4Error{ICMP4TypeTimeExceeded, 0, 0, 0, 0, 0, 0, 0, 0x45}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.IsValid(); (err != nil) != tt.wantErr {
+				t.Fatalf("ICMP4Error.IsValid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.p.IsFragmentationNeeded() != tt.wantFrag || (tt.wantFrag && tt.p.NextHopMTU() != tt.wantMTU) {
+				t.Errorf("ICMP4Error invalid fragmentation needed %s", tt.p)
+			}
+			if tt.p.OriginalIP().Protocol() != syscall.IPPROTO_UDP || len(tt.p.OriginalL4()) != 8 {
+				t.Errorf("ICMP4Error invalid original datagram %s", tt.p)
+			}
+			if src := tt.p.OriginalSrc(); src.IP != hostIP4 || src.Port != 40000 {
+				t.Errorf("ICMP4Error invalid original src %s", src)
+			}
+			if d := tt.p.OriginalDst(); d.IP != dst.IP || d.Port != dst.Port {
+				t.Errorf("ICMP4Error invalid original dst %s", d)
+			}
+		})
+	}
+}
+
+func TestSession_ICMP4ErrorEvents(t *testing.T) {
+	session, _ := testSession()
+	dst := Addr{IP: netip.MustParseAddr("8.8.8.8"), Port: 53}
+
+	// time exceeded is not an event
+	if _, err := session.Parse(newICMP4Error(ICMP4TypeTimeExceeded, ICMP4CodeTTLExceeded, 0, dst)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if len(session.Events) != 0 {
+		t.Fatalf("unexpected event %s", <-session.Events)
+	}
+
+	// repeated fragmentation needed sends a single event
+	for i := 0; i < 3; i++ {
+		if _, err := session.Parse(newICMP4Error(ICMP4TypeDestinationUnreachable, ICMP4CodeFragmentationNeeded, 1400, dst)); err != nil {
+			t.Fatal("unexpected parse error", err)
+		}
+	}
+	session.Parse(newICMP4Error(ICMP4TypeDestinationUnreachable, ICMP4CodePortUnreachable, 0, dst))
+	if len(session.Events) != 2 {
+		t.Fatalf("invalid events len=%d", len(session.Events))
+	}
+	e := <-session.Events
+	data, ok := e.Data.(ICMPErrorEvent)
+	if e.Type != EventICMPFragmentationNeeded || !ok || data.MTU != 1400 || data.Router != routerIP4 || e.Addr.IP != hostIP4 {
+		t.Errorf("invalid event %s", e)
+	}
+	if e = <-session.Events; e.Type != EventICMPDestinationUnreachable || e.Data.(ICMPErrorEvent).Dst.Port != 53 {
+		t.Errorf("invalid event %s", e)
+	}
+
+	session.purgeICMPErrors(time.Now().Add(time.Minute))
+	if n := session.icmpErrors.events.len(); n != 0 {
+		t.Errorf("entries not purged len=%d", n)
+	}
+}
@@ -0,0 +1,573 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// IEEE 1905.1 CMDU message types
+const (
+	IEEE1905TopologyDiscovery    = 0x0000
+	IEEE1905TopologyNotification = 0x0001
+	IEEE1905TopologyQuery        = 0x0002
+	IEEE1905TopologyResponse     = 0x0003
+	IEEE1905VendorSpecific       = 0x0004
+	IEEE1905LinkMetricQuery      = 0x0005
+	IEEE1905LinkMetricResponse   = 0x0006
+	IEEE1905APAutoconfigSearch   = 0x0007
+	IEEE1905APAutoconfigResponse = 0x0008
+	IEEE1905APAutoconfigWSC      = 0x0009
+	IEEE1905APAutoconfigRenew    = 0x000a
+	IEEE1905PushButtonEvent      = 0x000b
+	IEEE1905PushButtonJoin       = 0x000c
+	IEEE1905HigherLayerQuery     = 0x000d
+	IEEE1905HigherLayerResponse  = 0x000e
+)
+
+var ieee1905MessageNames = []string{
+	"topology_discovery", "topology_notification", "topology_query", "topology_response",
+	"vendor_specific", "link_metric_query", "link_metric_response",
+	"autoconfig_search", "autoconfig_response", "autoconfig_wsc", "autoconfig_renew",
+	"push_button_event", "push_button_join", "higher_layer_query", "higher_layer_response",
+}
+
+// IEEE 1905.1 TLV types
+const (
+	IEEE1905TLVEndOfMessage          = 0
+	IEEE1905TLVALMACAddress          = 1
+	IEEE1905TLVMACAddress            = 2
+	IEEE1905TLVDeviceInformation     = 3
+	IEEE1905TLVDeviceBridging        = 4
+	IEEE1905TLVNon1905NeighbourList  = 6
+	IEEE1905TLVNeighbourDeviceList   = 7
+	IEEE1905TLVLinkMetricQuery       = 8
+	IEEE1905TLVTransmitterLinkMetric = 9
+	IEEE1905TLVReceiverLinkMetric    = 10
+	IEEE1905TLVVendorSpecific        = 11
+	IEEE1905TLVLinkMetricResult      = 12
+	IEEE1905TLVDeviceIdentification  = 21
+)
+
+// IEEE 1905.1 media types - see table 6-12
+const (
+	IEEE1905MediaFastEthernet    = 0x0000
+	IEEE1905MediaGigabitEthernet = 0x0001
+	IEEE1905Media80211b          = 0x0100
+	IEEE1905Media80211g          = 0x0101
+	IEEE1905Media80211a          = 0x0102
+	IEEE1905Media80211n24        = 0x0103
+	IEEE1905Media80211n5         = 0x0104
+	IEEE1905Media80211ac         = 0x0105
+	IEEE1905Media80211ad         = 0x0106
+	IEEE1905Media80211af         = 0x0107
+	IEEE1905Media80211ax         = 0x0108
+	IEEE1905Media1901Wavelet     = 0x0200
+	IEEE1905Media1901FFT         = 0x0201
+	IEEE1905MediaMoCA            = 0x0300
+	IEEE1905MediaUnknown         = 0xffff
+)
+
+// IEEE 1905.1 802.11 interface roles
+const (
+	IEEE1905RoleAP        = 0x00
+	IEEE1905RoleSTA       = 0x04
+	IEEE1905RoleP2PClient = 0x08
+	IEEE1905RoleP2PGO     = 0x09
+)
+
+// IEEE1905 provide access to IEEE 1905.1 home networking frame fields.
+// These frames are sent by mesh wifi routers and extenders to exchange the network topology.
+//
+//	+--------+--------+--------+--------+--------+--------+--------+--------+
+//	| version| rsvd   | message type    | message id      | frag id| flags  |
+//	+--------+--------+--------+--------+--------+--------+--------+--------+
+//	| tlv type (1)    | len (2)         | value ...
+//	+--------+--------+--------+--------+
+//
+// see IEEE Std 1905.1-2013
+type IEEE1905 []byte
+
+func (p IEEE1905) IsValid() error {
+	if len(p) < 8 {
+		return ErrFrameLen
+	}
+	return nil
+}
+
+func (p IEEE1905) Version() uint8       { return p[0] }
+func (p IEEE1905) Reserved() uint8      { return p[1] }
+func (p IEEE1905) Type() uint16         { return binary.BigEndian.Uint16(p[2:4]) }
+func (p IEEE1905) ID() uint16           { return binary.BigEndian.Uint16(p[4:6]) }
+func (p IEEE1905) FragmentID() uint8    { return p[6] }
+func (p IEEE1905) Flags() uint8         { return p[7] }
+func (p IEEE1905) LastFragment() bool   { return p[7]&0x80 != 0 }
+func (p IEEE1905) RelayIndicator() bool { return p[7]&0x40 != 0 }
+func (p IEEE1905) TLV() []byte          { return p[8:] }
+
+// MessageType returns the CMDU message type name.
+func (p IEEE1905) MessageType() string {
+	if t := int(p.Type()); t < len(ieee1905MessageNames) {
+		return ieee1905MessageNames[t]
+	}
+	return "type(" + strconv.Itoa(int(p.Type())) + ")"
+}
+
+// TLVs returns the list of TLVs in the message. The list stops at the
+// end of message TLV. It returns an error if a TLV overflows the frame.
+func (p IEEE1905) TLVs() (list []IEEE1905TLV, err error) {
+	b := p.TLV()
+	for len(b) >= 3 {
+		l := int(binary.BigEndian.Uint16(b[1:3]))
+		if 3+l > len(b) {
+			return list, fmt.Errorf("ieee1905 tlv type=%d len=%d: %w", b[0], l, ErrFrameLen)
+		}
+		if b[0] == IEEE1905TLVEndOfMessage {
+			return list, nil
+		}
+		list = append(list, IEEE1905TLV(b[:3+l]))
+		b = b[3+l:]
+	}
+	return list, nil
+}
+
+func (p IEEE1905) String() string {
+	line := Logger.Msg("")
+	return p.FastLog(line).ToString()
+}
+
+func (p IEEE1905) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8("version", p.Version())
+	line.String("type", p.MessageType())
+	line.Uint16("id", p.ID())
+	line.Uint8("fragment", p.FragmentID())
+	line.Uint8Hex("flags", p.Flags())
+	tlvs, err := p.TLVs()
+	if err != nil {
+		line.ByteArray("tlv", p.TLV())
+		return line
+	}
+	for _, v := range tlvs {
+		line.Uint8("tlv", v.Type())
+	}
+	return line
+}
+
+// IEEE1905TLV provides access to a single 1905.1 TLV
+//
+//	type (1) | length (2) | value
+type IEEE1905TLV []byte
+
+func (t IEEE1905TLV) Type() uint8   { return t[0] }
+func (t IEEE1905TLV) Len() uint16   { return binary.BigEndian.Uint16(t[1:3]) }
+func (t IEEE1905TLV) Value() []byte { return t[3:] }
+
+// MAC returns the mac in AL MAC address and MAC address TLVs.
+func (t IEEE1905TLV) MAC() (net.HardwareAddr, error) {
+	if len(t.Value()) < 6 {
+		return nil, ErrFrameLen
+	}
+	return net.HardwareAddr(t.Value()[0:6]), nil
+}
+
+// IEEE1905Interface describes a local interface in the device information TLV.
+type IEEE1905Interface struct {
+	MAC       net.HardwareAddr
+	MediaType uint16
+	BSSID     net.HardwareAddr // 802.11 only - network membership
+	Role      uint8            // 802.11 only - IEEE1905RoleAP, IEEE1905RoleSTA...
+}
+
+// IsWifi returns true if the interface is a 802.11 interface.
+func (i IEEE1905Interface) IsWifi() bool { return i.MediaType&0xff00 == 0x0100 }
+
+// DeviceInformation decodes the device information TLV
+//
+//	al mac (6) | n (1) | n * [ mac (6) | media type (2) | media info len (1) | media info ]
+func (t IEEE1905TLV) DeviceInformation() (alMAC net.HardwareAddr, list []IEEE1905Interface, err error) {
+	v := t.Value()
+	if len(v) < 7 {
+		return nil, nil, ErrFrameLen
+	}
+	alMAC = net.HardwareAddr(v[0:6])
+	n := int(v[6])
+	v = v[7:]
+	for i := 0; i < n; i++ {
+		if len(v) < 9 || len(v) < 9+int(v[8]) {
+			return nil, nil, fmt.Errorf("ieee1905 device information interface=%d: %w", i, ErrFrameLen)
+		}
+		e := IEEE1905Interface{MAC: net.HardwareAddr(v[0:6]), MediaType: binary.BigEndian.Uint16(v[6:8])}
+		info := v[9 : 9+int(v[8])]
+		if e.IsWifi() && len(info) >= 7 {
+			e.BSSID = net.HardwareAddr(info[0:6])
+			e.Role = info[6] >> 4
+		}
+		list = append(list, e)
+		v = v[9+len(info):]
+	}
+	return alMAC, list, nil
+}
+
+// IEEE1905Neighbour describes a neighbour seen on a local interface.
+type IEEE1905Neighbour struct {
+	LocalMAC net.HardwareAddr // local interface
+	MAC      net.HardwareAddr // neighbour AL MAC for 1905 devices or interface MAC for non 1905 devices
+	Is1905   bool
+	Bridge   bool // 1905 only - an IEEE 802.1 bridge exists between the devices
+}
+
+// NeighbourDevices decodes the 1905 neighbour device list TLV
+//
+//	local mac (6) | n * [ al mac (6) | flags (1) ]
+func (t IEEE1905TLV) NeighbourDevices() (list []IEEE1905Neighbour, err error) {
+	v := t.Value()
+	if len(v) < 6 || (len(v)-6)%7 != 0 {
+		return nil, ErrFrameLen
+	}
+	local := net.HardwareAddr(v[0:6])
+	for v = v[6:]; len(v) >= 7; v = v[7:] {
+		list = append(list, IEEE1905Neighbour{LocalMAC: local, MAC: net.HardwareAddr(v[0:6]), Is1905: true, Bridge: v[6]&0x80 != 0})
+	}
+	return list, nil
+}
+
+// Non1905Neighbours decodes the non-1905 neighbour device list TLV. These are the
+// clients attached to the local interface.
+//
+//	local mac (6) | n * [ mac (6) ]
+func (t IEEE1905TLV) Non1905Neighbours() (list []IEEE1905Neighbour, err error) {
+	v := t.Value()
+	if len(v) < 6 || (len(v)-6)%6 != 0 {
+		return nil, ErrFrameLen
+	}
+	local := net.HardwareAddr(v[0:6])
+	for v = v[6:]; len(v) >= 6; v = v[6:] {
+		list = append(list, IEEE1905Neighbour{LocalMAC: local, MAC: net.HardwareAddr(v[0:6])})
+	}
+	return list, nil
+}
+
+// IEEE1905LinkMetric holds the metrics for a link between a local interface and a neighbour interface.
+type IEEE1905LinkMetric struct {
+	NeighbourALMAC net.HardwareAddr
+	LocalMAC       net.HardwareAddr
+	NeighbourMAC   net.HardwareAddr
+	MediaType      uint16
+	Bridge         bool   // transmitter only
+	PacketErrors   uint32 // transmitter or receiver errors
+	TxPackets      uint32 // transmitter only
+	RxPackets      uint32 // receiver only
+	Throughput     uint16 // transmitter only - MAC throughput capacity in Mbps
+	Availability   uint16 // transmitter only - link availability in percent
+	PhyRate        uint16 // transmitter only - Mbps
+	RSSI           uint8  // receiver only - dB
+}
+
+// TransmitterLinkMetrics decodes the transmitter link metric TLV
+//
+//	tx al mac (6) | neighbour al mac (6) | n * [ local mac (6) | neighbour mac (6) | media (2) | bridge (1) |
+//	    errors (4) | tx packets (4) | throughput (2) | availability (2) | phy rate (2) ]
+func (t IEEE1905TLV) TransmitterLinkMetrics() (alMAC net.HardwareAddr, list []IEEE1905LinkMetric, err error) {
+	v := t.Value()
+	if len(v) < 12 || (len(v)-12)%29 != 0 {
+		return nil, nil, ErrFrameLen
+	}
+	alMAC = net.HardwareAddr(v[0:6])
+	neighbour := net.HardwareAddr(v[6:12])
+	for v = v[12:]; len(v) >= 29; v = v[29:] {
+		list = append(list, IEEE1905LinkMetric{NeighbourALMAC: neighbour,
+			LocalMAC: net.HardwareAddr(v[0:6]), NeighbourMAC: net.HardwareAddr(v[6:12]),
+			MediaType: binary.BigEndian.Uint16(v[12:14]), Bridge: v[14] != 0,
+			PacketErrors: binary.BigEndian.Uint32(v[15:19]), TxPackets: binary.BigEndian.Uint32(v[19:23]),
+			Throughput: binary.BigEndian.Uint16(v[23:25]), Availability: binary.BigEndian.Uint16(v[25:27]),
+			PhyRate: binary.BigEndian.Uint16(v[27:29])})
+	}
+	return alMAC, list, nil
+}
+
+// ReceiverLinkMetrics decodes the receiver link metric TLV
+//
+//	tx al mac (6) | neighbour al mac (6) | n * [ local mac (6) | neighbour mac (6) | media (2) |
+//	    errors (4) | rx packets (4) | rssi (1) ]
+func (t IEEE1905TLV) ReceiverLinkMetrics() (alMAC net.HardwareAddr, list []IEEE1905LinkMetric, err error) {
+	v := t.Value()
+	if len(v) < 12 || (len(v)-12)%23 != 0 {
+		return nil, nil, ErrFrameLen
+	}
+	alMAC = net.HardwareAddr(v[0:6])
+	neighbour := net.HardwareAddr(v[6:12])
+	for v = v[12:]; len(v) >= 23; v = v[23:] {
+		list = append(list, IEEE1905LinkMetric{NeighbourALMAC: neighbour,
+			LocalMAC: net.HardwareAddr(v[0:6]), NeighbourMAC: net.HardwareAddr(v[6:12]),
+			MediaType:    binary.BigEndian.Uint16(v[12:14]),
+			PacketErrors: binary.BigEndian.Uint32(v[14:18]), RxPackets: binary.BigEndian.Uint32(v[18:22]),
+			RSSI: v[22]})
+	}
+	return alMAC, list, nil
+}
+
+// DeviceIdentification decodes the device identification TLV
+//
+//	friendly name (64) | manufacturer (64) | model (64)
+func (t IEEE1905TLV) DeviceIdentification() (name string, manufacturer string, model string, err error) {
+	v := t.Value()
+	if len(v) < 192 {
+		return "", "", "", ErrFrameLen
+	}
+	return string(bytes.TrimRight(v[0:64], "\x00")), string(bytes.TrimRight(v[64:128], "\x00")), string(bytes.TrimRight(v[128:192], "\x00")), nil
+}
+
+// DefaultMeshExpiry is the time a mesh device is kept after the last 1905 message.
+// Topology discovery messages are sent every 60 seconds.
+const DefaultMeshExpiry = time.Minute * 3
+
+// MeshDevice holds the topology information learned from a 1905.1 device (i.e. a mesh access point).
+type MeshDevice struct {
+	ALMAC        net.HardwareAddr // abstraction layer mac - identifies the device
+	Name         string
+	Manufacturer string
+	Model        string
+	Interfaces   []IEEE1905Interface
+	Neighbours   []IEEE1905Neighbour // 1905 neighbours - other mesh devices
+	Clients      []IEEE1905Neighbour // non 1905 neighbours
+	LinkMetrics  []IEEE1905LinkMetric
+	LastSeen     time.Time
+}
+
+func (e MeshDevice) String() string {
+	return Logger.Msg("").Struct(e).ToString()
+}
+
+// FastLog implements fastlog interface
+func (e MeshDevice) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.MAC("almac", e.ALMAC)
+	if e.Name != "" {
+		line.String("name", e.Name)
+	}
+	if e.Model != "" {
+		line.String("model", e.Model)
+	}
+	line.Int("interfaces", len(e.Interfaces))
+	for _, v := range e.Neighbours {
+		line.MAC("neighbour", v.MAC)
+	}
+	line.Int("clients", len(e.Clients))
+	return line
+}
+
+// Backhaul returns the link metric to the neighbour mesh device with the highest phy rate.
+// It returns false if no link metric is known.
+func (e MeshDevice) Backhaul() (IEEE1905LinkMetric, bool) {
+	var best IEEE1905LinkMetric
+	found := false
+	for _, v := range e.LinkMetrics {
+		if !found || v.PhyRate > best.PhyRate {
+			best = v
+			found = true
+		}
+	}
+	return best, found
+}
+
+// MeshTable holds the 1905.1 devices seen on the interface keyed by AL MAC.
+type MeshTable struct {
+	Table map[string]*MeshDevice
+}
+
+func newMeshTable() MeshTable {
+	return MeshTable{Table: make(map[string]*MeshDevice)}
+}
+
+// processIEEE1905 updates the mesh topology with the TLVs in the message.
+// Fragments carry whole TLVs so each fragment is processed on its own; the first
+// fragment replaces the lists and subsequent fragments append to them.
+func (h *Session) processIEEE1905(p IEEE1905) error {
+	tlvs, err := p.TLVs()
+	if err != nil {
+		return err
+	}
+
+	// the device AL MAC is in the AL MAC TLV or the device information TLV
+	var alMAC net.HardwareAddr
+	for _, t := range tlvs {
+		switch t.Type() {
+		case IEEE1905TLVALMACAddress:
+			alMAC, err = t.MAC()
+		case IEEE1905TLVDeviceInformation:
+			alMAC, _, err = t.DeviceInformation()
+		case IEEE1905TLVTransmitterLinkMetric:
+			alMAC, _, err = t.TransmitterLinkMetrics()
+		case IEEE1905TLVReceiverLinkMetric:
+			alMAC, _, err = t.ReceiverLinkMetrics()
+		}
+		if err != nil {
+			return fmt.Errorf("ieee1905 tlv type=%d: %w", t.Type(), err)
+		}
+		if alMAC != nil {
+			break
+		}
+	}
+	if alMAC == nil {
+		return nil
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	e, found := h.MeshTable.Table[string(alMAC)]
+	if !found {
+		e = &MeshDevice{ALMAC: CopyMAC(alMAC)}
+		h.MeshTable.Table[string(alMAC)] = e
+	}
+	first := p.FragmentID() == 0
+	reset := map[uint8]bool{}
+	for _, t := range tlvs {
+		// replace the list on the first TLV of each type in the first fragment
+		replace := first && !reset[t.Type()]
+		reset[t.Type()] = true
+
+		switch t.Type() {
+		case IEEE1905TLVDeviceInformation:
+			_, list, err := t.DeviceInformation()
+			if err != nil {
+				return err
+			}
+			if replace {
+				e.Interfaces = nil
+			}
+			// full slice expression forces append to copy so lists handed to callers are not modified
+			e.Interfaces = append(e.Interfaces[:len(e.Interfaces):len(e.Interfaces)], copyInterfaces(list)...)
+		case IEEE1905TLVNeighbourDeviceList:
+			list, err := t.NeighbourDevices()
+			if err != nil {
+				return err
+			}
+			if replace {
+				e.Neighbours = nil
+			}
+			e.Neighbours = append(e.Neighbours[:len(e.Neighbours):len(e.Neighbours)], copyNeighbours(list)...)
+		case IEEE1905TLVNon1905NeighbourList:
+			list, err := t.Non1905Neighbours()
+			if err != nil {
+				return err
+			}
+			if replace {
+				e.Clients = nil
+			}
+			e.Clients = append(e.Clients[:len(e.Clients):len(e.Clients)], copyNeighbours(list)...)
+		case IEEE1905TLVTransmitterLinkMetric, IEEE1905TLVReceiverLinkMetric:
+			receiver := t.Type() == IEEE1905TLVReceiverLinkMetric
+			var list []IEEE1905LinkMetric
+			if receiver {
+				_, list, err = t.ReceiverLinkMetrics()
+			} else {
+				_, list, err = t.TransmitterLinkMetrics()
+			}
+			if err != nil {
+				return err
+			}
+			for _, v := range list {
+				e.LinkMetrics = updateLinkMetric(e.LinkMetrics, v, receiver)
+			}
+		case IEEE1905TLVDeviceIdentification:
+			if name, manufacturer, model, err := t.DeviceIdentification(); err == nil {
+				e.Name, e.Manufacturer, e.Model = name, manufacturer, model
+			}
+		}
+	}
+	e.LastSeen = time.Now()
+	if !found && Logger.IsInfo() {
+		Logger.Msg("new mesh device").Struct(e).Write()
+	}
+	return nil
+}
+
+func copyInterfaces(list []IEEE1905Interface) []IEEE1905Interface {
+	for i := range list {
+		list[i].MAC = CopyMAC(list[i].MAC)
+		if list[i].BSSID != nil {
+			list[i].BSSID = CopyMAC(list[i].BSSID)
+		}
+	}
+	return list
+}
+
+func copyNeighbours(list []IEEE1905Neighbour) []IEEE1905Neighbour {
+	for i := range list {
+		list[i].LocalMAC = CopyMAC(list[i].LocalMAC)
+		list[i].MAC = CopyMAC(list[i].MAC)
+	}
+	return list
+}
+
+// updateLinkMetric merges the transmitter and receiver metrics for the same link.
+func updateLinkMetric(list []IEEE1905LinkMetric, v IEEE1905LinkMetric, receiver bool) []IEEE1905LinkMetric {
+	list = append(make([]IEEE1905LinkMetric, 0, len(list)+1), list...)
+	for i := range list {
+		if bytes.Equal(list[i].LocalMAC, v.LocalMAC) && bytes.Equal(list[i].NeighbourMAC, v.NeighbourMAC) {
+			if receiver {
+				list[i].RxPackets, list[i].RSSI = v.RxPackets, v.RSSI
+			} else {
+				v.RxPackets, v.RSSI = list[i].RxPackets, list[i].RSSI
+				list[i] = v
+			}
+			return list
+		}
+	}
+	v.NeighbourALMAC = CopyMAC(v.NeighbourALMAC)
+	v.LocalMAC = CopyMAC(v.LocalMAC)
+	v.NeighbourMAC = CopyMAC(v.NeighbourMAC)
+	return append(list, v)
+}
+
+// MeshDevices returns a copy of the mesh topology table.
+func (h *Session) MeshDevices() []MeshDevice {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]MeshDevice, 0, len(h.MeshTable.Table))
+	for _, v := range h.MeshTable.Table {
+		list = append(list, *v)
+	}
+	return list
+}
+
+// FindMeshAccessPoint returns the mesh device and local interface the client mac is attached to.
+// It returns false if no mesh device reported the client.
+func (h *Session) FindMeshAccessPoint(mac net.HardwareAddr) (MeshDevice, IEEE1905Interface, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for _, e := range h.MeshTable.Table {
+		for _, c := range e.Clients {
+			if !bytes.Equal(c.MAC, mac) {
+				continue
+			}
+			iface := IEEE1905Interface{MAC: c.LocalMAC, MediaType: IEEE1905MediaUnknown}
+			for _, v := range e.Interfaces {
+				if bytes.Equal(v.MAC, c.LocalMAC) {
+					iface = v
+					break
+				}
+			}
+			return *e, iface, true
+		}
+	}
+	return MeshDevice{}, IEEE1905Interface{}, false
+}
+
+// purgeMesh deletes mesh devices not seen recently.
+func (h *Session) purgeMesh(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for k, v := range h.MeshTable.Table {
+		if v.LastSeen.Add(DefaultMeshExpiry).Before(now) {
+			if Logger.IsDebug() {
+				Logger.Msg("mesh device expired").Struct(v).Write()
+			}
+			delete(h.MeshTable.Table, k)
+		}
+	}
+}
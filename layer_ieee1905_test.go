@@ -0,0 +1,98 @@
+package packet
+
+import (
+	"net"
+	"testing"
+)
+
+var testIEEE1905TopologyResponse = []byte(
+	// 02:00:00:00:01:02 > 01:80:c2:00:00:13, ethertype IEEE1905 (0x893a): topology response id 1, last fragment
+	// device information al mac 02:00:00:00:00:01 [02:00:00:00:01:01 802.11ac AP bssid 02:00:00:00:01:01] [02:00:00:00:01:02 gigabit ethernet]
+	// 1905 neighbours 02:00:00:00:01:01 [02:00:00:00:00:02], non-1905 neighbours 02:00:00:00:01:01 [00:02:03:04:05:0a 00:02:03:04:05:0b]
+	`0180 c200 0013 0200 0000 0102 893a 0000` + //  ...............:..
+		`0003 0001 0080 0300 2302 0000 0000 0102` + //  ........#.......
+		`0200 0000 0101 0105 0a02 0000 0001 0100` + //  ................
+		`0000 0002 0000 0001 0200 0100 0700 0d02` + //  ................
+		`0000 0001 0102 0000 0000 0200 0600 1202` + //  ................
+		`0000 0001 0100 0203 0405 0a00 0203 0405` + //  ................
+		`0b00 0000                              `) //  ....
+
+var testIEEE1905LinkMetricResponse = []byte(
+	// 02:00:00:00:01:02 > 01:80:c2:00:00:13, ethertype IEEE1905 (0x893a): link metric response id 2, last fragment
+	// transmitter 02:00:00:00:01:01 > 02:00:00:00:02:01 802.11ac tx 1000 throughput 400 availability 100 phy 866
+	// receiver 02:00:00:00:01:01 > 02:00:00:00:02:01 rx 900 rssi 45
+	`0180 c200 0013 0200 0000 0102 893a 0000` + //  ...............:..
+		`0006 0002 0080 0900 2902 0000 0000 0102` + //  ........).......
+		`0000 0000 0202 0000 0001 0102 0000 0002` + //  ................
+		`0101 0500 0000 0000 0000 03e8 0190 0064` + //  ...............d
+		`0362 0a00 2302 0000 0000 0102 0000 0000` + //  .b..#...........
+		`0202 0000 0001 0102 0000 0002 0101 0500` + //  ................
+		`0000 0000 0003 842d 0000 00            `) //  .......-...
+
+func TestIEEE1905_TLVs(t *testing.T) {
+	p := IEEE1905(Ether(mustHex(testIEEE1905TopologyResponse)).Payload())
+	if err := p.IsValid(); err != nil {
+		t.Fatal("invalid frame", err)
+	}
+	if p.MessageType() != "topology_response" || !p.LastFragment() {
+		t.Errorf("invalid header %s", p)
+	}
+	tlvs, err := p.TLVs()
+	if err != nil || len(tlvs) != 3 {
+		t.Fatalf("invalid tlvs %v err=%v", tlvs, err)
+	}
+	alMAC, ifaces, err := tlvs[0].DeviceInformation()
+	if err != nil {
+		t.Fatal("invalid device information", err)
+	}
+	if alMAC.String() != "02:00:00:00:00:01" || len(ifaces) != 2 {
+		t.Fatalf("invalid device information almac=%s interfaces=%v", alMAC, ifaces)
+	}
+	if !ifaces[0].IsWifi() || ifaces[0].Role != IEEE1905RoleAP || ifaces[0].BSSID.String() != "02:00:00:00:01:01" {
+		t.Errorf("invalid wifi interface %+v", ifaces[0])
+	}
+	if ifaces[1].IsWifi() || ifaces[1].MediaType != IEEE1905MediaGigabitEthernet {
+		t.Errorf("invalid ethernet interface %+v", ifaces[1])
+	}
+	if list, err := tlvs[1].NeighbourDevices(); err != nil || len(list) != 1 || list[0].MAC.String() != "02:00:00:00:00:02" || !list[0].Is1905 {
+		t.Errorf("invalid 1905 neighbours %v err=%v", list, err)
+	}
+	if list, err := tlvs[2].Non1905Neighbours(); err != nil || len(list) != 2 || list[1].MAC.String() != "00:02:03:04:05:0b" {
+		t.Errorf("invalid non 1905 neighbours %v err=%v", list, err)
+	}
+
+	// tlv overflow
+	if _, err := IEEE1905(p[:len(p)-8]).TLVs(); err == nil {
+		t.Error("expected error for truncated tlv")
+	}
+}
+
+func TestSession_MeshTopology(t *testing.T) {
+	session, _ := testSession()
+
+	if _, err := session.Parse(mustHex(testIEEE1905TopologyResponse)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if _, err := session.Parse(mustHex(testIEEE1905LinkMetricResponse)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if list := session.MeshDevices(); len(list) != 1 {
+		t.Fatalf("invalid mesh devices %v", list)
+	}
+
+	ap, iface, found := session.FindMeshAccessPoint(net.HardwareAddr{0x00, 0x02, 0x03, 0x04, 0x05, 0x0a})
+	if !found {
+		t.Fatal("client not found")
+	}
+	if ap.ALMAC.String() != "02:00:00:00:00:01" || iface.MediaType != IEEE1905Media80211ac {
+		t.Errorf("invalid access point %v interface %+v", ap, iface)
+	}
+	backhaul, ok := ap.Backhaul()
+	if !ok || backhaul.NeighbourALMAC.String() != "02:00:00:00:00:02" || backhaul.PhyRate != 866 || backhaul.RSSI != 45 || backhaul.RxPackets != 900 {
+		t.Errorf("invalid backhaul %+v", backhaul)
+	}
+
+	if _, _, found := session.FindMeshAccessPoint(mac1); found {
+		t.Error("unexpected client found")
+	}
+}
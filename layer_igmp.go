@@ -0,0 +1,303 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// IGMP message types
+// see https://www.iana.org/assignments/igmp-type-numbers/igmp-type-numbers.xhtml
+const (
+	IGMPTypeMembershipQuery    = 0x11 // query for v1, v2 and v3
+	IGMPTypeV1MembershipReport = 0x12
+	IGMPTypeV2MembershipReport = 0x16
+	IGMPTypeV3MembershipReport = 0x22
+	IGMPTypeLeaveGroup         = 0x17 // v2 leave
+)
+
+// MLD message types are carried in ICMPv6
+// see https://tools.ietf.org/html/rfc2710 and https://tools.ietf.org/html/rfc3810
+const (
+	ICMP6TypeMLDQuery    = 130 // query for v1 and v2
+	ICMP6TypeMLDv1Report = 131
+	ICMP6TypeMLDv1Done   = 132
+	ICMP6TypeMLDv2Report = 143
+)
+
+// Multicast group record types common to IGMPv3 and MLDv2
+// see https://tools.ietf.org/html/rfc3376#section-4.2.12
+const (
+	MulticastModeIsInclude   = 1
+	MulticastModeIsExclude   = 2
+	MulticastChangeToInclude = 3
+	MulticastChangeToExclude = 4
+	MulticastAllowNewSources = 5
+	MulticastBlockOldSources = 6
+)
+
+// MulticastMembership describes a group join or leave decoded from an IGMP or MLD report.
+type MulticastMembership struct {
+	Group netip.Addr
+	Join  bool
+}
+
+// membershipFromRecord returns the membership state for a v3 group record.
+//
+// An exclude record with any source list means the host wants the group; an include record
+// with an empty source list is the v3 equivalent of a leave.
+func membershipFromRecord(recordType uint8, nSources int) bool {
+	switch recordType {
+	case MulticastModeIsExclude, MulticastChangeToExclude, MulticastAllowNewSources:
+		return true
+	case MulticastModeIsInclude, MulticastChangeToInclude:
+		return nSources > 0
+	}
+	return true // block old sources does not change membership
+}
+
+// IGMP provides access to IGMPv1, v2 and v3 messages
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|      Type     | Max Resp Time |           Checksum            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                         Group Address                         |  v1, v2 and query
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|           Reserved            |  Number of Group Records (M)  |  v3 report
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// see https://tools.ietf.org/html/rfc3376
+type IGMP []byte
+
+func (p IGMP) IsValid() error {
+	if len(p) < 8 {
+		return fmt.Errorf("igmp len=%d: %w", len(p), ErrFrameLen)
+	}
+	if p.Type() != IGMPTypeV3MembershipReport {
+		return nil
+	}
+	pos := 8
+	for i := 0; i < int(p.NumberOfGroupRecords()); i++ {
+		r := IGMPGroupRecord(p[pos:])
+		if err := r.IsValid(); err != nil {
+			return err
+		}
+		pos = pos + r.Len()
+	}
+	return nil
+}
+
+func (p IGMP) Type() uint8                  { return p[0] }
+func (p IGMP) MaxResponseTime() uint8       { return p[1] } // in 1/10 second
+func (p IGMP) Checksum() uint16             { return binary.BigEndian.Uint16(p[2:4]) }
+func (p IGMP) GroupAddress() netip.Addr     { return netip.AddrFrom4(*(*[4]byte)(p[4:8])) } // v1, v2 and query only
+func (p IGMP) NumberOfGroupRecords() uint16 { return binary.BigEndian.Uint16(p[6:8]) }      // v3 report only
+
+// GroupRecords returns the list of group records in an IGMPv3 report.
+// The packet must be valid.
+func (p IGMP) GroupRecords() (records []IGMPGroupRecord) {
+	if p.Type() != IGMPTypeV3MembershipReport {
+		return nil
+	}
+	pos := 8
+	for i := 0; i < int(p.NumberOfGroupRecords()); i++ {
+		r := IGMPGroupRecord(p[pos:])
+		records = append(records, r[:r.Len()])
+		pos = pos + r.Len()
+	}
+	return records
+}
+
+// Memberships returns the group joins and leaves in the report. It returns nil for queries.
+// The packet must be valid.
+func (p IGMP) Memberships() []MulticastMembership {
+	switch p.Type() {
+	case IGMPTypeV1MembershipReport, IGMPTypeV2MembershipReport:
+		return []MulticastMembership{{Group: p.GroupAddress(), Join: true}}
+	case IGMPTypeLeaveGroup:
+		return []MulticastMembership{{Group: p.GroupAddress(), Join: false}}
+	case IGMPTypeV3MembershipReport:
+		records := p.GroupRecords()
+		list := make([]MulticastMembership, 0, len(records))
+		for _, r := range records {
+			list = append(list, MulticastMembership{Group: r.MulticastAddress(), Join: membershipFromRecord(r.RecordType(), int(r.NumberOfSources()))})
+		}
+		return list
+	}
+	return nil
+}
+
+func (p IGMP) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p IGMP) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8Hex("type", p.Type())
+	if p.Type() == IGMPTypeV3MembershipReport {
+		line.Uint16("records", p.NumberOfGroupRecords())
+		return line
+	}
+	line.IP("group", p.GroupAddress())
+	return line
+}
+
+// IGMPGroupRecord provides access to a group record in an IGMPv3 report
+//
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|  Record Type  |  Aux Data Len |     Number of Sources (N)     |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                       Multicast Address                       |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                Source Address [1..N]                          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                         Auxiliary Data                        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type IGMPGroupRecord []byte
+
+func (p IGMPGroupRecord) IsValid() error {
+	if len(p) < 8 || len(p) < p.Len() {
+		return fmt.Errorf("igmp group record len=%d: %w", len(p), ErrFrameLen)
+	}
+	return nil
+}
+
+func (p IGMPGroupRecord) RecordType() uint8            { return p[0] }
+func (p IGMPGroupRecord) AuxDataLen() uint8            { return p[1] } // in 32 bit words
+func (p IGMPGroupRecord) NumberOfSources() uint16      { return binary.BigEndian.Uint16(p[2:4]) }
+func (p IGMPGroupRecord) MulticastAddress() netip.Addr { return netip.AddrFrom4(*(*[4]byte)(p[4:8])) }
+func (p IGMPGroupRecord) Len() int {
+	return 8 + int(p.NumberOfSources())*4 + int(p.AuxDataLen())*4
+}
+func (p IGMPGroupRecord) Sources() (list []netip.Addr) {
+	for i := 0; i < int(p.NumberOfSources()); i++ {
+		list = append(list, netip.AddrFrom4(*(*[4]byte)(p[8+i*4 : 8+i*4+4])))
+	}
+	return list
+}
+
+// MLD provides access to MLDv1 and MLDv2 messages carried in ICMPv6
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     Type      |     Code      |          Checksum             |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     Maximum Response Delay    |          Reserved             |  v1 and query
+//	|           Reserved            |Nr of Mcast Address Records (M)|  v2 report
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                                                               |
+//	+                       Multicast Address                       +  v1 and query
+//	|                                                               |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// see https://tools.ietf.org/html/rfc3810
+type MLD []byte
+
+func (p MLD) IsValid() error {
+	if len(p) < 8 {
+		return fmt.Errorf("mld len=%d: %w", len(p), ErrFrameLen)
+	}
+	if p.Type() != ICMP6TypeMLDv2Report {
+		if len(p) < 24 {
+			return fmt.Errorf("mld len=%d: %w", len(p), ErrFrameLen)
+		}
+		return nil
+	}
+	pos := 8
+	for i := 0; i < int(p.NumberOfRecords()); i++ {
+		r := MLDAddressRecord(p[pos:])
+		if err := r.IsValid(); err != nil {
+			return err
+		}
+		pos = pos + r.Len()
+	}
+	return nil
+}
+
+func (p MLD) Type() uint8                  { return p[0] }
+func (p MLD) Code() uint8                  { return p[1] }
+func (p MLD) Checksum() uint16             { return binary.BigEndian.Uint16(p[2:4]) }
+func (p MLD) MaxResponseDelay() uint16     { return binary.BigEndian.Uint16(p[4:6]) }         // v1 and query only
+func (p MLD) MulticastAddress() netip.Addr { return netip.AddrFrom16(*(*[16]byte)(p[8:24])) } // v1 and query only
+func (p MLD) NumberOfRecords() uint16      { return binary.BigEndian.Uint16(p[6:8]) }         // v2 report only
+
+// AddressRecords returns the list of multicast address records in an MLDv2 report.
+// The packet must be valid.
+func (p MLD) AddressRecords() (records []MLDAddressRecord) {
+	if p.Type() != ICMP6TypeMLDv2Report {
+		return nil
+	}
+	pos := 8
+	for i := 0; i < int(p.NumberOfRecords()); i++ {
+		r := MLDAddressRecord(p[pos:])
+		records = append(records, r[:r.Len()])
+		pos = pos + r.Len()
+	}
+	return records
+}
+
+// Memberships returns the group joins and leaves in the report. It returns nil for queries.
+// The packet must be valid.
+func (p MLD) Memberships() []MulticastMembership {
+	switch p.Type() {
+	case ICMP6TypeMLDv1Report:
+		return []MulticastMembership{{Group: p.MulticastAddress(), Join: true}}
+	case ICMP6TypeMLDv1Done:
+		return []MulticastMembership{{Group: p.MulticastAddress(), Join: false}}
+	case ICMP6TypeMLDv2Report:
+		records := p.AddressRecords()
+		list := make([]MulticastMembership, 0, len(records))
+		for _, r := range records {
+			list = append(list, MulticastMembership{Group: r.MulticastAddress(), Join: membershipFromRecord(r.RecordType(), int(r.NumberOfSources()))})
+		}
+		return list
+	}
+	return nil
+}
+
+func (p MLD) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p MLD) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8("type", p.Type())
+	if p.Type() == ICMP6TypeMLDv2Report {
+		line.Uint16("records", p.NumberOfRecords())
+		return line
+	}
+	line.IP("group", p.MulticastAddress())
+	return line
+}
+
+// MLDAddressRecord provides access to a multicast address record in an MLDv2 report.
+// It has the same layout as IGMPGroupRecord with 16 byte addresses.
+type MLDAddressRecord []byte
+
+func (p MLDAddressRecord) IsValid() error {
+	if len(p) < 20 || len(p) < p.Len() {
+		return fmt.Errorf("mld address record len=%d: %w", len(p), ErrFrameLen)
+	}
+	return nil
+}
+
+func (p MLDAddressRecord) RecordType() uint8       { return p[0] }
+func (p MLDAddressRecord) AuxDataLen() uint8       { return p[1] } // in 32 bit words
+func (p MLDAddressRecord) NumberOfSources() uint16 { return binary.BigEndian.Uint16(p[2:4]) }
+func (p MLDAddressRecord) MulticastAddress() netip.Addr {
+	return netip.AddrFrom16(*(*[16]byte)(p[4:20]))
+}
+func (p MLDAddressRecord) Len() int {
+	return 20 + int(p.NumberOfSources())*16 + int(p.AuxDataLen())*4
+}
+func (p MLDAddressRecord) Sources() (list []netip.Addr) {
+	for i := 0; i < int(p.NumberOfSources()); i++ {
+		list = append(list, netip.AddrFrom16(*(*[16]byte)(p[20+i*16 : 20+i*16+16])))
+	}
+	return list
+}
@@ -0,0 +1,118 @@
+package packet
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+var testIGMPv2Report = []byte(
+	// 00:02:03:04:05:0a > 01:00:5e:7f:ff:fa, ethertype IPv4 (0x0800), length 60: (tos 0x0, ttl 1, id 0, offset 0, flags [DF], proto IGMP (2), length 32, options (RA))
+	// 192.168.0.10 > 239.255.255.250: igmp v2 report 239.255.255.250
+	`0100 5e7f fffa 0002 0304 050a 0800 4600` + //  ..^.............F.
+		`0020 0000 4000 0102 0000 c0a8 000a efff` + //  . ..@...........
+		`fffa 9404 0000 1600 0000 efff fffa 0000` + //  ................
+		`0000 0000 0000 0000 0000 0000          `) //  ............
+
+var testIGMPv2Leave = []byte(
+	// 192.168.0.10 > 224.0.0.2: igmp leave 239.255.255.250
+	`0100 5e00 0002 0002 0304 050a 0800 4600` + //  ..^.............F.
+		`0020 0000 4000 0102 0000 c0a8 000a e000` + //  . ..@...........
+		`0002 9404 0000 1700 0000 efff fffa 0000` + //  ................
+		`0000 0000 0000 0000 0000 0000          `) //  ............
+
+var testIGMPv3Report = []byte(
+	// 192.168.0.10 > 224.0.0.22: igmp v3 report, 2 group record(s) [gaddr 224.0.0.251 to_ex { }] [gaddr 239.1.1.1 is_in { 192.168.0.1 }]
+	`0100 5e00 0016 0002 0304 050a 0800 4600` + //  ..^.............F.
+		`0034 0000 4000 0102 0000 c0a8 000a e000` + //  .4..@...........
+		`0016 9404 0000 2200 0000 0000 0002 0400` + //  ......".........
+		`0000 e000 00fb 0100 0001 ef01 0101 c0a8` + //  ................
+		`0001                                   `) //  ..
+
+var testMLDv2Report = []byte(
+	// 00:02:03:04:05:0a > 33:33:00:00:00:16, ethertype IPv6 (0x86dd), length 90: (hlim 1, next-header Options (0) payload length: 36)
+	// fe80::2:3ff:fe04:50a > ff02::16: HBH (rtalert: 0x0000) (padn) [icmp6 sum ok] ICMP6, multicast listener report v2, 1 group record(s) [gaddr ff02::fb to_ex { }]
+	`3333 0000 0016 0002 0304 050a 86dd 6000` + //  33............`.
+		`0000 0024 0001 fe80 0000 0000 0000 0002` + //  ...$............
+		`03ff fe04 050a ff02 0000 0000 0000 0000` + //  ................
+		`0000 0000 0016 3a00 0502 0000 0100 8f00` + //  ......:.........
+		`0000 0000 0001 0400 0000 ff02 0000 0000` + //  ................
+		`0000 0000 0000 0000 00fb               `) //  ..........
+
+func TestIGMP_Memberships(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       []byte
+		trim    int
+		wantErr bool
+		want    []MulticastMembership
+	}{
+		{name: "v2 report", p: mustHex(testIGMPv2Report), want: []MulticastMembership{{Group: netip.MustParseAddr("239.255.255.250"), Join: true}}},
+		{name: "v2 leave", p: mustHex(testIGMPv2Leave), want: []MulticastMembership{{Group: netip.MustParseAddr("239.255.255.250"), Join: false}}},
+		{name: "v3 report", p: mustHex(testIGMPv3Report), want: []MulticastMembership{
+			{Group: netip.MustParseAddr("224.0.0.251"), Join: true},
+			{Group: netip.MustParseAddr("239.1.1.1"), Join: true}}},
+		{name: "v3 query", p: mustHex(testIGMP), want: nil},
+		{name: "v3 short", p: mustHex(testIGMPv3Report), trim: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip4 := IP4(Ether(tt.p).Payload())
+			igmp := IGMP(ip4.Payload())
+			igmp = igmp[:len(igmp)-tt.trim]
+			if err := igmp.IsValid(); (err != nil) != tt.wantErr {
+				t.Fatalf("IGMP.IsValid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := igmp.Memberships()
+			if len(got) != len(tt.want) {
+				t.Fatalf("IGMP.Memberships() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("IGMP.Memberships() = %v, want %v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSession_MulticastGroups(t *testing.T) {
+	session, _ := testSession()
+
+	ssdp := netip.MustParseAddr("239.255.255.250")
+	mdns6 := netip.MustParseAddr("ff02::fb")
+
+	if _, err := session.Parse(mustHex(testIGMPv2Report)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	frame, err := session.Parse(mustHex(testMLDv2Report))
+	if err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if frame.PayloadID != PayloadICMP6 {
+		t.Errorf("invalid payloadID=%v", frame.PayloadID)
+	}
+	if list := session.FindMulticastGroup(ssdp); len(list) != 1 || list[0].IP != netip.MustParseAddr("192.168.0.10") {
+		t.Errorf("invalid ipv4 group members %v", list)
+	}
+	if list := session.FindMulticastGroup(mdns6); len(list) != 1 || list[0].IP != netip.MustParseAddr("fe80::2:3ff:fe04:50a") {
+		t.Errorf("invalid ipv6 group members %v", list)
+	}
+
+	// leave removes the group
+	if _, err := session.Parse(mustHex(testIGMPv2Leave)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if list := session.FindMulticastGroup(ssdp); len(list) != 0 {
+		t.Errorf("invalid group members after leave %v", list)
+	}
+
+	// memberships expire if not refreshed
+	session.purge(time.Now().Add(DefaultMulticastGroupExpiry + time.Second))
+	if list := session.FindMulticastGroup(mdns6); len(list) != 0 {
+		t.Errorf("invalid group members after expiry %v", list)
+	}
+}
@@ -0,0 +1,491 @@
+package packet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// LLDP TLV types
+// see https://en.wikipedia.org/wiki/Link_Layer_Discovery_Protocol
+const (
+	LLDPTypeEnd               = 0
+	LLDPTypeChassisID         = 1
+	LLDPTypePortID            = 2
+	LLDPTypeTTL               = 3
+	LLDPTypePortDescription   = 4
+	LLDPTypeSystemName        = 5
+	LLDPTypeSystemDescription = 6
+	LLDPTypeCapabilities      = 7
+	LLDPTypeManagementAddress = 8
+	LLDPTypeOrg               = 127
+)
+
+// LLDP system capabilities bits
+const (
+	LLDPCapabilityOther     = 0x0001
+	LLDPCapabilityRepeater  = 0x0002
+	LLDPCapabilityBridge    = 0x0004
+	LLDPCapabilityWLANAP    = 0x0008
+	LLDPCapabilityRouter    = 0x0010
+	LLDPCapabilityTelephone = 0x0020
+	LLDPCapabilityDOCSIS    = 0x0040
+	LLDPCapabilityStation   = 0x0080
+)
+
+// Organisationally unique identifiers for org specific TLVs
+var (
+	lldpOUI8021 = []byte{0x00, 0x80, 0xc2} // IEEE 802.1
+	lldpOUI8023 = []byte{0x00, 0x12, 0x0f} // IEEE 802.3
+)
+
+// LLDPVLAN holds a vlan advertised in an 802.1 VLAN name TLV
+type LLDPVLAN struct {
+	ID   uint16
+	Name string
+}
+
+// LLDPLinkAggregation holds the link aggregation status advertised in the 802.1 or 802.3 TLV
+type LLDPLinkAggregation struct {
+	Capable bool
+	Enabled bool
+	PortID  uint32 // aggregated port id
+}
+
+// LLDPPower holds the 802.3 power via MDI (PoE) TLV
+type LLDPPower struct {
+	Support   uint8  // MDI power support bits
+	PowerPair uint8  // PSE power pair
+	Class     uint8  // power class
+	Requested uint16 // PD requested power in 0.1 watts (802.3at only)
+	Allocated uint16 // PSE allocated power in 0.1 watts (802.3at only)
+}
+
+// LLDP provides access to Local Link Discovery Protocol
+type LLDP []byte
+
+func (p LLDP) IsValid() error {
+	if len(p) < 6 {
+		return ErrFrameLen
+	}
+	return nil
+}
+
+func (p LLDP) ChassisID() []byte {
+	_, _, v, _ := p.getTLV(0)
+	return v
+}
+func (p LLDP) PortID() []byte {
+	c := p.ChassisID()
+	_, _, v, _ := p.getTLV(len(c) + 2) // skip first PDU
+	return v
+}
+
+func (p LLDP) GetPDU(pduType int) []byte {
+	pos := 0
+	for {
+		t, l, v, err := p.getTLV(pos)
+		if err != nil {
+			return nil
+		}
+		if t == pduType || t == 0 { // return if end of TLV
+			return v
+		}
+		pos = pos + l + 2
+	}
+}
+
+// forEachTLV calls fn for each TLV until the end of LLDPDU or fn returns false.
+func (p LLDP) forEachTLV(fn func(t int, v []byte) bool) {
+	pos := 0
+	for {
+		t, l, v, err := p.getTLV(pos)
+		if err != nil || t == LLDPTypeEnd {
+			return
+		}
+		if !fn(t, v) {
+			return
+		}
+		pos = pos + l + 2
+	}
+}
+
+func (p LLDP) getTLV(n int) (t int, l int, v []byte, err error) {
+	if len(p) < n+2 {
+		return 0, 0, nil, ErrParseFrame
+	}
+	t = int(p[n] >> 1) // type = 7 bits
+	l = (int(p[n]) & 0x01 << 8) + int(p[n+1])
+	if t == 0 && l == 0 { // end of LLPDU
+		return t, l, nil, nil
+	}
+	if len(p) >= n+2+l {
+		return t, l, p[n+2 : n+2+l], nil
+	}
+	return 0, 0, nil, ErrParseFrame
+}
+
+// TTL returns the time to live for the neighbour information. A zero TTL indicates
+// the neighbour is shutting down and the information should be removed.
+func (p LLDP) TTL() time.Duration {
+	if v := p.GetPDU(LLDPTypeTTL); len(v) >= 2 {
+		return time.Duration(binary.BigEndian.Uint16(v)) * time.Second
+	}
+	return 0
+}
+
+func (p LLDP) PortDescription() string   { return string(p.GetPDU(LLDPTypePortDescription)) }
+func (p LLDP) SystemName() string        { return string(p.GetPDU(LLDPTypeSystemName)) }
+func (p LLDP) SystemDescription() string { return string(p.GetPDU(LLDPTypeSystemDescription)) }
+
+// Capabilities returns the system capabilities and the enabled capabilities bit maps.
+func (p LLDP) Capabilities() (system uint16, enabled uint16) {
+	if v := p.GetPDU(LLDPTypeCapabilities); len(v) >= 4 {
+		return binary.BigEndian.Uint16(v[0:2]), binary.BigEndian.Uint16(v[2:4])
+	}
+	return 0, 0
+}
+
+// ManagementAddresses returns the IPv4 and IPv6 management addresses. There may be multiple management address TLVs.
+func (p LLDP) ManagementAddresses() (list []netip.Addr) {
+	p.forEachTLV(func(t int, v []byte) bool {
+		// addr string len (1 byte, includes subtype) | addr subtype | addr | interface subtype | interface number | oid
+		if t != LLDPTypeManagementAddress || len(v) < 2 || v[0] < 2 || int(v[0])+1 > len(v) {
+			return true
+		}
+		if addr, ok := netip.AddrFromSlice(v[2 : 1+int(v[0])]); ok {
+			list = append(list, addr)
+		}
+		return true
+	})
+	return list
+}
+
+// orgTLVs returns the information string of all org specific TLVs matching oui and subtype.
+func (p LLDP) orgTLVs(oui []byte, subtype byte) (list [][]byte) {
+	p.forEachTLV(func(t int, v []byte) bool {
+		if t == LLDPTypeOrg && len(v) >= 4 && bytes.Equal(v[0:3], oui) && v[3] == subtype {
+			list = append(list, v[4:])
+		}
+		return true
+	})
+	return list
+}
+
+func (p LLDP) orgTLV(oui []byte, subtype byte) []byte {
+	if list := p.orgTLVs(oui, subtype); len(list) > 0 {
+		return list[0]
+	}
+	return nil
+}
+
+// PortVLANID returns the 802.1 port vlan id or zero if not present.
+func (p LLDP) PortVLANID() uint16 {
+	if v := p.orgTLV(lldpOUI8021, 1); len(v) >= 2 {
+		return binary.BigEndian.Uint16(v[0:2])
+	}
+	return 0
+}
+
+// VLANs returns the 802.1 vlan names advertised on the port.
+func (p LLDP) VLANs() (list []LLDPVLAN) {
+	for _, v := range p.orgTLVs(lldpOUI8021, 3) {
+		if len(v) < 3 || len(v) < 3+int(v[2]) {
+			continue
+		}
+		list = append(list, LLDPVLAN{ID: binary.BigEndian.Uint16(v[0:2]), Name: string(v[3 : 3+int(v[2])])})
+	}
+	return list
+}
+
+// LinkAggregation returns the link aggregation status from either the 802.1 TLV or
+// the deprecated 802.3 TLV. It returns false if none are present.
+func (p LLDP) LinkAggregation() (LLDPLinkAggregation, bool) {
+	v := p.orgTLV(lldpOUI8021, 7)
+	if v == nil {
+		v = p.orgTLV(lldpOUI8023, 3)
+	}
+	if len(v) < 5 {
+		return LLDPLinkAggregation{}, false
+	}
+	return LLDPLinkAggregation{Capable: v[0]&0x01 == 0x01, Enabled: v[0]&0x02 == 0x02, PortID: binary.BigEndian.Uint32(v[1:5])}, true
+}
+
+// Power returns the 802.3 power via MDI TLV. It returns false if not present.
+func (p LLDP) Power() (LLDPPower, bool) {
+	v := p.orgTLV(lldpOUI8023, 2)
+	if len(v) < 3 {
+		return LLDPPower{}, false
+	}
+	power := LLDPPower{Support: v[0], PowerPair: v[1], Class: v[2]}
+	if len(v) >= 8 { // 802.3at extension
+		power.Requested = binary.BigEndian.Uint16(v[4:6])
+		power.Allocated = binary.BigEndian.Uint16(v[6:8])
+	}
+	return power, true
+}
+
+// MaxFrameSize returns the 802.3 maximum frame size or zero if not present.
+func (p LLDP) MaxFrameSize() uint16 {
+	if v := p.orgTLV(lldpOUI8023, 4); len(v) >= 2 {
+		return binary.BigEndian.Uint16(v[0:2])
+	}
+	return 0
+}
+
+func (p LLDP) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+func (p LLDP) Type(t int) string {
+	switch t {
+	case 0:
+		return "endpdu"
+	case 1:
+		return "chassisID"
+	case 2:
+		return "port"
+	case 3:
+		return "ttl"
+	case 4:
+		return "portdesc"
+	case 5:
+		return "name"
+	case 6:
+		return "description"
+	case 7:
+		return "capabilities"
+	case 8:
+		return "mngntaddr"
+	case 127:
+		return "org"
+	default:
+		return strconv.Itoa(t)
+	}
+}
+
+func (p LLDP) Capability(v []byte) string {
+	if len(v) < 2 {
+		return ""
+	}
+	// System capabilities TLV: Indicates the primary function(s) of the device and whether or not these
+	// functions are enabled in the device. The capabilities are indicated by two octects.
+	// Bits 0 through 7 indicate Other, Repeater, Bridge, WLAN AP, Router, Telephone, DOCSIS cable device and Station respectively. Bits 8 through 15 are reserved.
+	return lldpCapabilityString(binary.BigEndian.Uint16(v[0:2]))
+}
+
+func lldpCapabilityString(c uint16) string {
+	s := ""
+	if c&LLDPCapabilityOther != 0 {
+		s = s + "other,"
+	}
+	if c&LLDPCapabilityRepeater != 0 {
+		s = s + "repeater,"
+	}
+	if c&LLDPCapabilityBridge != 0 {
+		s = s + "bridge,"
+	}
+	if c&LLDPCapabilityWLANAP != 0 {
+		s = s + "AP,"
+	}
+	if c&LLDPCapabilityRouter != 0 {
+		s = s + "router,"
+	}
+	if c&LLDPCapabilityTelephone != 0 {
+		s = s + "phone,"
+	}
+	if c&LLDPCapabilityDOCSIS != 0 {
+		s = s + "docsis,"
+	}
+	if c&LLDPCapabilityStation != 0 {
+		s = s + "station,"
+	}
+	if len(s) > 0 {
+		return s[:len(s)-1]
+	}
+	return ""
+}
+
+func (p LLDP) FastLog(line *fastlog.Line) *fastlog.Line {
+	p.forEachTLV(func(t int, v []byte) bool {
+		switch t {
+		case LLDPTypeChassisID:
+			line.String(p.Type(t), lldpChassisID(v))
+		case LLDPTypePortID:
+			line.String(p.Type(t), lldpPortID(v))
+		case LLDPTypePortDescription, LLDPTypeSystemName, LLDPTypeSystemDescription:
+			line.String(p.Type(t), string(v))
+		case LLDPTypeCapabilities:
+			line.ByteArray("capability", v)
+			line.String("type", p.Capability(v))
+		default:
+			line.ByteArray(p.Type(t), v)
+		}
+		return true
+	})
+	return line
+}
+
+// lldpID returns a printable chassis or port id. The first byte is the id subtype and
+// the mac and network address subtypes differ between the chassis and port TLVs.
+func lldpID(v []byte, macSubtype byte, addrSubtype byte) string {
+	if len(v) < 2 {
+		return ""
+	}
+	switch {
+	case v[0] == macSubtype && len(v) == 7:
+		return net.HardwareAddr(v[1:]).String()
+	case v[0] == addrSubtype && len(v) > 2: // family | address
+		if addr, ok := netip.AddrFromSlice(v[2:]); ok {
+			return addr.String()
+		}
+	}
+	return string(v[1:])
+}
+
+func lldpChassisID(v []byte) string { return lldpID(v, 4, 5) }
+func lldpPortID(v []byte) string    { return lldpID(v, 3, 4) }
+
+// LLDPNeighbour holds the decoded information received from an LLDP neighbour.
+type LLDPNeighbour struct {
+	Protocol          PayloadID        // PayloadLLDP or PayloadCDP
+	MAC               net.HardwareAddr // source mac of the LLDP frame
+	ChassisID         string
+	PortID            string
+	PortDescription   string
+	SystemName        string
+	SystemDescription string
+	Platform          string // CDP only
+	Capabilities      uint16 // enabled capabilities
+	ManagementAddrs   []netip.Addr
+	PortVLANID        uint16
+	VLANs             []LLDPVLAN
+	LinkAggregation   LLDPLinkAggregation
+	Power             LLDPPower
+	TTL               time.Duration
+	LastSeen          time.Time
+}
+
+func (e LLDPNeighbour) String() string {
+	return Logger.Msg("").Struct(e).ToString()
+}
+
+// FastLog implements fastlog interface
+func (e LLDPNeighbour) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.String("protocol", e.Protocol.String())
+	line.MAC("mac", e.MAC)
+	line.String("chassisID", e.ChassisID)
+	line.String("portID", e.PortID)
+	if e.PortDescription != "" {
+		line.String("portdesc", e.PortDescription)
+	}
+	line.String("name", e.SystemName)
+	line.String("description", e.SystemDescription)
+	if e.Platform != "" {
+		line.String("platform", e.Platform)
+	}
+	line.String("capabilities", lldpCapabilityString(e.Capabilities))
+	for _, v := range e.ManagementAddrs {
+		line.IP("mngntaddr", v)
+	}
+	if e.PortVLANID != 0 {
+		line.Uint16("vlan", e.PortVLANID)
+	}
+	if e.LinkAggregation.Enabled {
+		line.Uint32("aggregationPort", e.LinkAggregation.PortID)
+	}
+	if e.Power.Class != 0 {
+		line.Uint8("powerClass", e.Power.Class)
+	}
+	line.Duration("ttl", e.TTL)
+	return line
+}
+
+// newLLDPNeighbour decodes a valid LLDP frame into a neighbour entry.
+func newLLDPNeighbour(mac net.HardwareAddr, p LLDP, now time.Time) LLDPNeighbour {
+	e := LLDPNeighbour{
+		Protocol:          PayloadLLDP,
+		MAC:               CopyMAC(mac),
+		ChassisID:         lldpChassisID(p.ChassisID()),
+		PortID:            lldpPortID(p.PortID()),
+		PortDescription:   p.PortDescription(),
+		SystemName:        p.SystemName(),
+		SystemDescription: p.SystemDescription(),
+		ManagementAddrs:   p.ManagementAddresses(),
+		PortVLANID:        p.PortVLANID(),
+		VLANs:             p.VLANs(),
+		TTL:               p.TTL(),
+		LastSeen:          now,
+	}
+	_, e.Capabilities = p.Capabilities()
+	e.LinkAggregation, _ = p.LinkAggregation()
+	e.Power, _ = p.Power()
+	return e
+}
+
+// LLDPTable holds the LLDP and CDP neighbours seen on the interface keyed by chassis and port id.
+type LLDPTable struct {
+	Table map[string]*LLDPNeighbour
+}
+
+func newLLDPTable() LLDPTable {
+	return LLDPTable{Table: make(map[string]*LLDPNeighbour)}
+}
+
+// updateLLDPNeighbour adds or refreshes the neighbour entry for the LLDP frame.
+// A frame with TTL zero removes the neighbour.
+func (h *Session) updateLLDPNeighbour(mac net.HardwareAddr, p LLDP) {
+	h.updateNeighbour(newLLDPNeighbour(mac, p, time.Now()))
+}
+
+// updateNeighbour adds or refreshes the neighbour entry in the table.
+// A neighbour with TTL zero is removed.
+func (h *Session) updateNeighbour(e LLDPNeighbour) {
+	key := e.ChassisID + "/" + e.PortID
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if e.TTL == 0 {
+		if _, found := h.LLDPTable.Table[key]; found {
+			if Logger.IsInfo() {
+				Logger.Msg("neighbour shutdown").Struct(e).Write()
+			}
+			delete(h.LLDPTable.Table, key)
+		}
+		return
+	}
+	if _, found := h.LLDPTable.Table[key]; !found && Logger.IsInfo() {
+		Logger.Msg("new neighbour").Struct(e).Write()
+	}
+	h.LLDPTable.Table[key] = &e
+}
+
+// LLDPNeighbours returns a copy of the LLDP and CDP neighbour table.
+func (h *Session) LLDPNeighbours() []LLDPNeighbour {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	list := make([]LLDPNeighbour, 0, len(h.LLDPTable.Table))
+	for _, v := range h.LLDPTable.Table {
+		list = append(list, *v)
+	}
+	return list
+}
+
+// purgeLLDP deletes neighbours whose TTL expired.
+func (h *Session) purgeLLDP(now time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for k, v := range h.LLDPTable.Table {
+		if v.LastSeen.Add(v.TTL).Before(now) {
+			if Logger.IsDebug() {
+				Logger.Msg("lldp neighbour expired").Struct(v).Write()
+			}
+			delete(h.LLDPTable.Table, k)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package packet
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+var testLLDPSwitch = []byte(
+	// 00:11:22:33:44:55 > 01:80:c2:00:00:0e, ethertype LLDP (0x88cc), length 106: LLDP, length 92: sw1
+	//   Chassis ID TLV (1), length 7: Subtype MAC address (4): 00:11:22:33:44:55
+	//   Port ID TLV (2), length 4: Subtype Interface Name (5): ge1
+	//   Time to Live TLV (3), length 2: TTL 120s
+	//   System Name TLV (5), length 3: sw1
+	//   System Capabilities TLV (7), length 4: [Bridge, Router] enabled [Bridge, Router]
+	//   Management Address TLV (8), length 12: AFI IPv4 (1): 192.168.0.2, Interface Index (2): 1
+	//   Organization specific TLV (127), length 6: OUI Ethernet bridged (0x0080c2) Port VLAN Id: 10
+	//   Organization specific TLV (127), length 12: OUI Ethernet bridged (0x0080c2) VLAN name: vlan id (VID): 10, VLAN name: iot01
+	//   Organization specific TLV (127), length 9: OUI Ethernet bridged (0x0080c2) Link aggregation: capable enabled, port 5
+	//   Organization specific TLV (127), length 12: OUI IEEE 802.3 Private (0x00120f) Power via MDI: class 5 requested 12.8W allocated 12.0W
+	//   End TLV (0), length 0
+	`0180 c200 000e 0011 2233 4455 88cc 0207` + //  ..........3DU...
+		`0400 1122 3344 5504 0405 6765 3106 0200` + //  ..."3DU...ge1...
+		`780a 0373 7731 0e04 0014 0014 100c 0501` + //  x..sw1..........
+		`c0a8 0002 0200 0000 0100 fe06 0080 c201` + //  ................
+		`000a fe0c 0080 c203 000a 0569 6f74 3031` + //  ...........iot01
+		`fe09 0080 c207 0300 0000 05fe 0c00 120f` + //  ................
+		`0207 0105 1100 8000 7800 00            `) //  ........x..
+
+func TestLLDP_Decode(t *testing.T) {
+	p := LLDP(Ether(mustHex(testLLDPSwitch)).Payload())
+	if err := p.IsValid(); err != nil {
+		t.Fatal("invalid lldp", err)
+	}
+	e := newLLDPNeighbour(Ether(mustHex(testLLDPSwitch)).Src(), p, time.Now())
+	if e.ChassisID != "00:11:22:33:44:55" || e.PortID != "ge1" || e.SystemName != "sw1" || e.TTL != time.Second*120 {
+		t.Errorf("invalid lldp ids %+v", e)
+	}
+	if e.Capabilities != LLDPCapabilityBridge|LLDPCapabilityRouter {
+		t.Errorf("invalid capabilities=%x", e.Capabilities)
+	}
+	if len(e.ManagementAddrs) != 1 || e.ManagementAddrs[0] != netip.MustParseAddr("192.168.0.2") {
+		t.Errorf("invalid management addr=%v", e.ManagementAddrs)
+	}
+	if e.PortVLANID != 10 || len(e.VLANs) != 1 || e.VLANs[0] != (LLDPVLAN{ID: 10, Name: "iot01"}) {
+		t.Errorf("invalid vlan pvid=%d vlans=%+v", e.PortVLANID, e.VLANs)
+	}
+	if e.LinkAggregation != (LLDPLinkAggregation{Capable: true, Enabled: true, PortID: 5}) {
+		t.Errorf("invalid link aggregation=%+v", e.LinkAggregation)
+	}
+	if e.Power != (LLDPPower{Support: 0x07, PowerPair: 0x01, Class: 0x05, Requested: 128, Allocated: 120}) {
+		t.Errorf("invalid power=%+v", e.Power)
+	}
+
+	// Unifi access point captured on a home lan
+	unifi := LLDP([]byte{0x02, 0x07, 0x04, 0xb4, 0xfb, 0xe4, 0x76, 0x2e, 0x0b, 0x04, 0x07, 0x03, 0xb4, 0xfb, 0xe4, 0x76, 0x2e, 0x0b, 0x06, 0x02, 0x00, 0x78, 0x0a, 0x0f, 0x55, 0x6e, 0x69, 0x66, 0x69, 0x45, 0x73, 0x63, 0x72, 0x69, 0x74, 0x6f, 0x72, 0x69, 0x6f, 0x0c, 0x19, 0x55, 0x41, 0x50, 0x2d, 0x41, 0x43, 0x2d, 0x4c, 0x69, 0x74, 0x65, 0x2c, 0x20, 0x34, 0x2e, 0x33, 0x2e, 0x32, 0x38, 0x2e, 0x31, 0x31, 0x33, 0x36, 0x31, 0x0e, 0x04, 0x00, 0x9c, 0x00, 0x0c, 0x10, 0x0c, 0x05, 0x01, 0xc0, 0xa8, 0x01, 0x86, 0x02, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x10, 0x18, 0x11, 0x02, 0x28, 0x04, 0x01, 0x90, 0x02, 0x0d, 0x8e, 0x00, 0xb6, 0xfb, 0xe4, 0xff, 0xfe, 0x76, 0x2e, 0x0b, 0x02, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x08, 0x03, 0x62, 0x72, 0x30, 0xfe, 0x09, 0x00, 0x12, 0x0f, 0x03, 0x01, 0x00, 0x00, 0x00, 0x00, 0xfe, 0x09, 0x00, 0x12, 0x0f, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfe, 0x07, 0x00, 0x12, 0xbb, 0x01, 0x00, 0x3f, 0x04, 0xfe, 0x0b, 0x00, 0x12, 0xbb, 0x07, 0x34, 0x2e, 0x34, 0x2e, 0x31, 0x35, 0x33, 0x00, 0x00})
+	if unifi.SystemName() != "UnifiEscritorio" || unifi.PortDescription() != "br0" || unifi.SystemDescription() != "UAP-AC-Lite, 4.3.28.11361" {
+		t.Errorf("invalid unifi names %s", unifi)
+	}
+	if _, enabled := unifi.Capabilities(); lldpCapabilityString(enabled) != "bridge,AP" {
+		t.Errorf("invalid unifi capabilities=%s", lldpCapabilityString(enabled))
+	}
+	if list := unifi.ManagementAddresses(); len(list) != 2 || list[0] != netip.MustParseAddr("192.168.1.134") || !list[1].Is6() {
+		t.Errorf("invalid unifi management addr=%v", list)
+	}
+	if la, found := unifi.LinkAggregation(); !found || !la.Capable || la.Enabled {
+		t.Errorf("invalid unifi link aggregation=%+v", la)
+	}
+}
+
+func TestSession_LLDPNeighbours(t *testing.T) {
+	session, _ := testSession()
+
+	frame, err := session.Parse(mustHex(testLLDPSwitch))
+	if err != nil || frame.PayloadID != PayloadLLDP {
+		t.Fatalf("unexpected parse error=%v payloadID=%v", err, frame.PayloadID)
+	}
+	if _, err := session.Parse(mustHex(testLLDPSwitch)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	list := session.LLDPNeighbours()
+	if len(list) != 1 || list[0].SystemName != "sw1" || list[0].PortID != "ge1" {
+		t.Fatalf("invalid lldp table %+v", list)
+	}
+
+	session.purgeLLDP(time.Now().Add(time.Second * 121))
+	if list := session.LLDPNeighbours(); len(list) != 0 {
+		t.Errorf("invalid lldp table after expiry %+v", list)
+	}
+}
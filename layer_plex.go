@@ -0,0 +1,100 @@
+package packet
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// PlexGDM provides access to Plex G'Day Mate (GDM) discovery messages sent to UDP ports 32410-32414.
+// GDM uses HTTP like text messages: clients send a M-SEARCH, servers reply with HTTP/1.0 200 OK and
+// players announce themselves with HELLO and BYE.
+//
+//	HTTP/1.0 200 OK
+//	Content-Type: plex/media-server
+//	Resource-Identifier: 23f2d6867befb9c83f51
+//	Name: office
+//	Port: 32400
+//	Version: 1.32.5.7349
+//
+// see https://github.com/NineWorlds/serenity-android/wiki/Good-Day-Mate
+type PlexGDM []byte
+
+func (p PlexGDM) IsValid() error {
+	if bytes.IndexByte(p, '\n') == -1 {
+		return fmt.Errorf("plex gdm missing start line: %w", ErrParseFrame)
+	}
+	switch {
+	case bytes.HasPrefix(p, []byte("M-SEARCH ")), bytes.HasPrefix(p, []byte("HELLO ")),
+		bytes.HasPrefix(p, []byte("BYE ")), bytes.HasPrefix(p, []byte("HTTP/")):
+		return nil
+	}
+	return fmt.Errorf("plex gdm invalid start line: %w", ErrParseFrame)
+}
+
+// Method returns the first word in the start line: M-SEARCH, HELLO, BYE or HTTP/1.0 for responses.
+func (p PlexGDM) Method() string {
+	line := p[:bytes.IndexByte(p, '\n')]
+	if n := bytes.IndexByte(line, ' '); n != -1 {
+		line = line[:n]
+	}
+	return string(bytes.TrimSpace(line))
+}
+
+// IsResponse returns true if the message is a reply to a M-SEARCH
+func (p PlexGDM) IsResponse() bool { return bytes.HasPrefix(p, []byte("HTTP/")) }
+
+// Header returns the value of the header key. Key is case insensitive.
+func (p PlexGDM) Header(key string) string {
+	lines := p[bytes.IndexByte(p, '\n')+1:]
+	for len(lines) > 0 {
+		var line []byte
+		if n := bytes.IndexByte(lines, '\n'); n != -1 {
+			line, lines = lines[:n], lines[n+1:]
+		} else {
+			line, lines = lines, nil
+		}
+		n := bytes.IndexByte(line, ':')
+		if n == -1 {
+			continue
+		}
+		if bytes.EqualFold(bytes.TrimSpace(line[:n]), []byte(key)) {
+			return string(bytes.TrimSpace(line[n+1:]))
+		}
+	}
+	return ""
+}
+
+func (p PlexGDM) Name() string               { return p.Header("Name") }
+func (p PlexGDM) ContentType() string        { return p.Header("Content-Type") }
+func (p PlexGDM) Product() string            { return p.Header("Product") }
+func (p PlexGDM) Version() string            { return p.Header("Version") }
+func (p PlexGDM) ResourceIdentifier() string { return p.Header("Resource-Identifier") }
+
+// NameEntry returns the server or player name and model.
+// It returns an empty entry for M-SEARCH and BYE messages.
+func (p PlexGDM) NameEntry() NameEntry {
+	if !p.IsResponse() && p.Method() != "HELLO" {
+		return NameEntry{}
+	}
+	name := NameEntry{Type: "plex", Name: p.Name(), Model: p.Product()}
+	if name.Model == "" && p.ContentType() == "plex/media-server" {
+		name.Model = "Plex Media Server"
+	}
+	return name
+}
+
+func (p PlexGDM) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p PlexGDM) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.String("method", p.Method())
+	line.String("name", p.Name())
+	line.String("contentType", p.ContentType())
+	line.String("product", p.Product())
+	line.String("version", p.Version())
+	return line
+}
@@ -0,0 +1,68 @@
+package packet
+
+import (
+	"testing"
+)
+
+func TestPlexGDM_NameEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        string
+		wantErr  bool
+		method   string
+		wantName NameEntry
+	}{
+		{name: "response", method: "HTTP/1.0",
+			p:        "HTTP/1.0 200 OK\r\nContent-Type: plex/media-server\r\nResource-Identifier: 23f2d6867befb9c83f51\r\nName: office\r\nPort: 32400\r\nVersion: 1.32.5.7349\r\n\r\n",
+			wantName: NameEntry{Type: "plex", Name: "office", Model: "Plex Media Server"}},
+		{name: "hello", method: "HELLO",
+			p:        "HELLO * HTTP/1.0\r\nname: Living Room TV\r\ncontent-type: plex/media-player\r\nproduct: Plex for Android (TV)\r\nversion: 9.0.0\r\n\r\n",
+			wantName: NameEntry{Type: "plex", Name: "Living Room TV", Model: "Plex for Android (TV)"}},
+		{name: "msearch", method: "M-SEARCH",
+			p:        "M-SEARCH * HTTP/1.1\r\n\r\n",
+			wantName: NameEntry{}},
+		{name: "bye", method: "BYE",
+			p:        "BYE * HTTP/1.0\r\nName: Living Room TV\r\n\r\n",
+			wantName: NameEntry{}},
+		{name: "invalid", p: "GET / HTTP/1.1\r\n\r\n", wantErr: true},
+		{name: "noline", p: "HELLO", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PlexGDM(tt.p)
+			if err := p.IsValid(); (err != nil) != tt.wantErr {
+				t.Fatalf("PlexGDM.IsValid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if m := p.Method(); m != tt.method {
+				t.Errorf("PlexGDM.Method() = %v, want %v", m, tt.method)
+			}
+			if got := p.NameEntry(); got != tt.wantName {
+				t.Errorf("PlexGDM.NameEntry() = %+v, want %+v", got, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestSession_PlexDeviceName(t *testing.T) {
+	session, _ := testSession()
+
+	srcAddr := Addr{MAC: mac1, IP: ip1, Port: 32414}
+	dstAddr := Addr{MAC: hostMAC, IP: hostIP4, Port: 40000}
+	response := []byte("HTTP/1.0 200 OK\r\nContent-Type: plex/media-server\r\nName: office\r\n\r\n")
+	frame, err := session.Parse(testUDPFrame(srcAddr, dstAddr, response))
+	if err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if frame.PayloadID != PayloadPlex || frame.Host == nil {
+		t.Fatalf("invalid frame payloadID=%v host=%v", frame.PayloadID, frame.Host)
+	}
+	session.mutex.RLock()
+	name := frame.Host.MACEntry.DeviceName
+	session.mutex.RUnlock()
+	if name.Type != "plex" || name.Name != "office" || name.Model != "Plex Media Server" {
+		t.Errorf("invalid device name %+v", name)
+	}
+}
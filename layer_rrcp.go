@@ -2,6 +2,9 @@ package packet
 
 import (
 	"encoding/binary"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/deeGraYve/packet/fastlog"
 )
@@ -26,6 +29,25 @@ import (
 //    0xax - DSA
 //
 
+// RRCP protocol numbers
+const (
+	RRCPProtocolRRCP          = 0x01
+	RRCPProtocolLoopDetection = 0x23
+)
+
+// RRCP opcodes
+const (
+	RRCPOpCodeHello = 0x00
+	RRCPOpCodeGet   = 0x01
+	RRCPOpCodeSet   = 0x02
+)
+
+// RRCPDefaultAuthKey is the factory default authentication key in Realtek switches
+const RRCPDefaultAuthKey = 0x2379
+
+// RRCPLen is the minimum len of a RRCP payload; the ethernet frame is padded to 60 bytes.
+const RRCPLen = 18
+
 type RRCP []byte
 
 func (p RRCP) IsValid() error {
@@ -35,18 +57,52 @@ func (p RRCP) IsValid() error {
 	return nil
 }
 
+// EncodeRRCP encodes a RRCP request in b. Register addr and data are ignored for Hello.
+func EncodeRRCP(b []byte, opcode uint8, authKey uint16, registerAddr uint16, registerData uint16) RRCP {
+	if cap(b) < RRCPLen {
+		panic("invalid rrcp buffer")
+	}
+	b = b[:RRCPLen]
+	for i := range b {
+		b[i] = 0
+	}
+	b[0] = RRCPProtocolRRCP
+	b[1] = opcode & 0x7f
+	binary.BigEndian.PutUint16(b[2:4], authKey)
+	if opcode != RRCPOpCodeHello {
+		binary.LittleEndian.PutUint16(b[4:6], registerAddr)
+		binary.LittleEndian.PutUint16(b[6:8], registerData)
+	}
+	return b
+}
+
 // Protocol 0x01 - the original format but not seen in logs yet.
 //
 //	RRCP protocol description: http://realtek.info/pdf/rtl8324.pdf
 //	                           http://openrrcp.org.ru/download/datasheets/RTL8326_8326S_DataSheet_3.1.pdf
 //	some sample C code here: https://www.wireshark.org/lists/ethereal-dev/200409/msg00090.html
 //	                         https://github.com/the-tcpdump-group/tcpdump/blob/master/print-rrcp.c
-func (p RRCP) Protocol() uint8      { return p[0] }                            // 8bits - 0x01 Realtek Remote Control Protocol; 0x23 Loop detection
-func (p RRCP) Reply() bool          { return (p[1]&0x80 == 0x80) }             // 1 bit - 1 reply from switch to management station
-func (p RRCP) OpCode() uint8        { return p[1] & 0x7f }                     // 7 bits - 00 Hello; 01 Get configuration; 02 Set configuration
-func (p RRCP) AuthKey() uint16      { return binary.BigEndian.Uint16(p[2:4]) } // Authentication key - default 0x2379
-func (p RRCP) RegisterAddr() uint16 { return binary.BigEndian.Uint16(p[4:6]) } // register addr
-func (p RRCP) RegisterData() uint16 { return binary.BigEndian.Uint16(p[6:8]) } // register data
+func (p RRCP) Protocol() uint8      { return p[0] }                               // 8bits - 0x01 Realtek Remote Control Protocol; 0x23 Loop detection
+func (p RRCP) Reply() bool          { return (p[1]&0x80 == 0x80) }                // 1 bit - 1 reply from switch to management station
+func (p RRCP) OpCode() uint8        { return p[1] & 0x7f }                        // 7 bits - 00 Hello; 01 Get configuration; 02 Set configuration
+func (p RRCP) AuthKey() uint16      { return binary.BigEndian.Uint16(p[2:4]) }    // Authentication key - default 0x2379
+func (p RRCP) RegisterAddr() uint16 { return binary.LittleEndian.Uint16(p[4:6]) } // register addr - little endian as per tcpdump
+func (p RRCP) RegisterData() uint16 { return binary.LittleEndian.Uint16(p[6:8]) } // register data - 16 bits registers; little endian
+
+// Hello reply fields
+//
+// The switch replies to a Hello with its identity: the chip id and vendor id registers
+// plus the port and uplink mac of the switch that received the hello.
+func (p RRCP) DownlinkPort() uint8         { return p[4] }
+func (p RRCP) UplinkPort() uint8           { return p[5] }
+func (p RRCP) UplinkMAC() net.HardwareAddr { return net.HardwareAddr(p[6:12]) }
+func (p RRCP) ChipID() uint16              { return binary.BigEndian.Uint16(p[12:14]) }
+func (p RRCP) VendorID() uint32 {
+	if len(p) < RRCPLen {
+		return 0
+	}
+	return binary.BigEndian.Uint32(p[14:18])
+}
 
 func (p RRCP) String() string {
 	return Logger.Msg("").Struct(p).ToString()
@@ -62,6 +118,17 @@ func (p RRCP) FastLog(l *fastlog.Line) *fastlog.Line {
 		l.String("protocol", "realtek (0x01)")
 		l.Bool("reply", p.Reply())
 		l.Uint8Hex("opcode", p.OpCode())
+		switch {
+		case p.OpCode() == RRCPOpCodeHello && p.Reply():
+			l.Uint8("downlink", p.DownlinkPort())
+			l.Uint8("uplink", p.UplinkPort())
+			l.MAC("uplinkMAC", p.UplinkMAC())
+			l.Uint16Hex("chipID", p.ChipID())
+			l.Uint32("vendorID", p.VendorID())
+		case p.OpCode() != RRCPOpCodeHello:
+			l.Uint16Hex("reg", p.RegisterAddr())
+			l.Uint16Hex("data", p.RegisterData())
+		}
 	default:
 		l.Uint8Hex("protocol", p.Protocol())
 		l.String("msg", "unknown realtek protocol")
@@ -109,3 +176,78 @@ func (p RRCP) Zeros() []byte    { return p[7:] }      // not sure what this is;
 // + *       +------+------+------+------+------+------+------+------+
 // + *  7: 0 |  Reserved   |          Destination port mask          |
 // + *       +------+------+------+------+------+------+------+------+
+
+// DefaultRRCPLoopWindow is the time window to detect a repeated loop detection frame.
+// Switches send a new random signature every couple of seconds so the same signature
+// seen twice means the frame has travelled around a cabling loop.
+var DefaultRRCPLoopWindow = time.Second * 2
+
+// RRCPLoopEvent is the event data sent when loop detection frames indicate a cabling loop.
+type RRCPLoopEvent struct {
+	MAC   net.HardwareAddr // switch sending the loop detection frame
+	Count int              // number of times the signature was seen
+}
+
+func (e RRCPLoopEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.MAC("switch", e.MAC)
+	l.Int("count", e.Count)
+	return l
+}
+
+type rrcpLoopEntry struct {
+	count    int
+	lastSeen time.Time
+}
+
+// rrcpLoopKey is the switch mac and the loop detection signature.
+type rrcpLoopKey struct {
+	mac       [6]byte
+	signature [6]byte
+}
+
+type rrcpState struct {
+	sync.Mutex
+	signatures map[rrcpLoopKey]rrcpLoopEntry
+	events     eventLimiter[[6]byte] // key is switch mac
+}
+
+// processRRCPLoop checks loop detection frames for a repeated signature and sends an event if
+// a loop is detected. Events are sent at most once per minute for each switch.
+func (h *Session) processRRCPLoop(addr Addr, p RRCP) {
+	now := time.Now()
+	var key rrcpLoopKey
+	copy(key.mac[:], addr.MAC)
+	copy(key.signature[:], p.SixBytes())
+
+	h.rrcp.Lock()
+	if h.rrcp.signatures == nil {
+		h.rrcp.signatures = make(map[rrcpLoopKey]rrcpLoopEntry)
+	}
+	e := h.rrcp.signatures[key]
+	if now.Sub(e.lastSeen) > DefaultRRCPLoopWindow {
+		e.count = 0 // expired entry not purged yet
+	}
+	e.count++
+	e.lastSeen = now
+	h.rrcp.signatures[key] = e
+	if e.count < 2 || !h.rrcp.events.allow(key.mac, now) {
+		h.rrcp.Unlock()
+		return
+	}
+	h.rrcp.Unlock()
+
+	h.sendEvent(Event{Type: EventRRCPLoopDetected, Time: now, Addr: addr,
+		Data: RRCPLoopEvent{MAC: CopyMAC(addr.MAC), Count: e.count}})
+}
+
+// purgeRRCP deletes expired loop detection signatures and event times.
+func (h *Session) purgeRRCP(now time.Time) {
+	h.rrcp.Lock()
+	defer h.rrcp.Unlock()
+	for k, v := range h.rrcp.signatures {
+		if now.Sub(v.lastSeen) > DefaultRRCPLoopWindow {
+			delete(h.rrcp.signatures, k)
+		}
+	}
+	h.rrcp.events.purge(now)
+}
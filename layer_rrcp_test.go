@@ -3,6 +3,7 @@ package packet
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 // Each of these; repeated 4 times with 2 sec interval - one per port perhaps?
@@ -52,3 +53,44 @@ func TestRRCP_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestSession_RRCPLoopDetection(t *testing.T) {
+	session, _ := testSession()
+
+	loopFrame := func(signature byte) []byte {
+		ether := EncodeEther(make([]byte, EthMaxSize), 0x8899, mac1, EthernetBroadcast)
+		payload := make([]byte, 46)
+		payload[0] = RRCPProtocolLoopDetection
+		copy(payload[1:7], []byte{signature, 0x44, 0xa2, 0x2e, 0xc3, 0x0d})
+		ether, _ = ether.AppendPayload(payload)
+		return ether
+	}
+
+	// new signature every time - no loop
+	for i := 0; i < 3; i++ {
+		if _, err := session.Parse(loopFrame(byte(i))); err != nil {
+			t.Fatal("unexpected parse error", err)
+		}
+	}
+	if len(session.Events) != 0 {
+		t.Fatalf("unexpected event %v", <-session.Events)
+	}
+
+	// same signature again - frame came back through a loop
+	for i := 0; i < 3; i++ {
+		session.Parse(loopFrame(0x10))
+	}
+	if len(session.Events) != 1 {
+		t.Fatalf("invalid events len=%d", len(session.Events))
+	}
+	e := <-session.Events
+	data, ok := e.Data.(RRCPLoopEvent)
+	if e.Type != EventRRCPLoopDetected || !ok || data.MAC.String() != mac1.String() || data.Count != 2 {
+		t.Errorf("invalid event %s", e)
+	}
+
+	session.purgeRRCP(time.Now().Add(time.Minute))
+	if n, m := len(session.rrcp.signatures), session.rrcp.events.len(); n != 0 || m != 0 {
+		t.Errorf("entries not purged signatures=%d events=%d", n, m)
+	}
+}
@@ -0,0 +1,38 @@
+package packet
+
+import (
+	"fmt"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// Sonos provides access to the Sonos proprietary frames sent with EtherType 0x6970.
+//
+// The frame format is not documented and the payload fields could not be verified against
+// captures; Sonos speakers send these frames periodically to coordinate the speaker mesh.
+// The payload is kept as opaque bytes and the frame is only used to identify the sender as
+// a Sonos device. Decode the fields here once the format is confirmed.
+type Sonos []byte
+
+func (p Sonos) IsValid() error {
+	if len(p) < 1 {
+		return fmt.Errorf("sonos len=%d: %w", len(p), ErrFrameLen)
+	}
+	return nil
+}
+
+// NameEntry returns the manufacturer and model for the sender.
+func (p Sonos) NameEntry() NameEntry {
+	return NameEntry{Type: "sonos", Model: "Sonos", Manufacturer: "Sonos, Inc."}
+}
+
+func (p Sonos) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p Sonos) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Int("len", len(p))
+	line.ByteArray("payload", p)
+	return line
+}
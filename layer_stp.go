@@ -0,0 +1,223 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// BPDU versions
+const (
+	BPDUVersionSTP  = 0
+	BPDUVersionRSTP = 2
+	BPDUVersionMSTP = 3
+)
+
+// BPDU types
+const (
+	BPDUTypeConfig = 0x00 // STP configuration
+	BPDUTypeTCN    = 0x80 // STP topology change notification
+	BPDUTypeRST    = 0x02 // RSTP and MSTP
+)
+
+// BPDU flags
+const (
+	BPDUFlagTopologyChange    = 0x01
+	BPDUFlagProposal          = 0x02
+	BPDUFlagPortRole          = 0x0c
+	BPDUFlagLearning          = 0x10
+	BPDUFlagForwarding        = 0x20
+	BPDUFlagAgreement         = 0x40
+	BPDUFlagTopologyChangeAck = 0x80
+)
+
+// BridgeID holds a spanning tree bridge identifier: 2 bytes priority followed by the bridge mac.
+type BridgeID [8]byte
+
+func (b BridgeID) Priority() uint16      { return binary.BigEndian.Uint16(b[0:2]) }
+func (b BridgeID) MAC() net.HardwareAddr { return net.HardwareAddr(b[2:8]) }
+func (b BridgeID) IsZero() bool          { return b == BridgeID{} }
+func (b BridgeID) String() string {
+	return fmt.Sprintf("%d/%s", b.Priority(), b.MAC())
+}
+
+// BPDU provides access to spanning tree bridge protocol data units (STP, RSTP and MSTP).
+// BPDUs are sent in 802.3 frames with LLC DSAP and SSAP 0x42 to 01:80:c2:00:00:00.
+//
+//	+--------+--------+--------+--------+--------+-----------------+-----------+-----------------+
+//	| protocol id     | version| type   | flags  | root id (8)     | cost (4)  | bridge id (8)   |
+//	+--------+--------+--------+--------+--------+-----------------+-----------+-----------------+
+//	| port id (2)     | msg age (2)     | max age (2)     | hello (2)       | fwd delay (2)    |
+//	+--------+--------+--------+--------+--------+--------+--------+--------+--------+--------+
+//	| v1 len | v3 len (2)      | MST configuration id (51) | CIST ... (13) | MSTI records (16) ...
+//	+--------+--------+--------+--------+--------+--------+--------+--------+--------+--------+
+//
+// see https://en.wikipedia.org/wiki/Spanning_Tree_Protocol
+type BPDU []byte
+
+func (p BPDU) IsValid() error {
+	if len(p) < 4 {
+		return fmt.Errorf("bpdu len=%d: %w", len(p), ErrFrameLen)
+	}
+	if p.ProtocolID() != 0 {
+		return fmt.Errorf("bpdu protocol=%d: %w", p.ProtocolID(), ErrParseFrame)
+	}
+	switch {
+	case p.Type() == BPDUTypeTCN:
+		return nil
+	case p.Type() == BPDUTypeConfig && len(p) >= 35:
+		return nil
+	case p.Type() == BPDUTypeRST && p.Version() == BPDUVersionRSTP && len(p) >= 36:
+		return nil
+	case p.Type() == BPDUTypeRST && p.Version() == BPDUVersionMSTP && len(p) >= 102 && len(p) >= 38+int(p.Version3Len()):
+		return nil
+	}
+	return fmt.Errorf("bpdu type=%x version=%d len=%d: %w", p.Type(), p.Version(), len(p), ErrFrameLen)
+}
+
+func (p BPDU) ProtocolID() uint16 { return binary.BigEndian.Uint16(p[0:2]) }
+func (p BPDU) Version() uint8     { return p[2] }
+func (p BPDU) Type() uint8        { return p[3] }
+func (p BPDU) Flags() uint8       { return p[4] }
+func (p BPDU) TopologyChange() bool {
+	return p.Type() == BPDUTypeTCN || p[4]&BPDUFlagTopologyChange != 0
+}
+func (p BPDU) TopologyChangeAck() bool     { return p[4]&BPDUFlagTopologyChangeAck != 0 }
+func (p BPDU) RootID() BridgeID            { return *(*BridgeID)(p[5:13]) }
+func (p BPDU) RootPathCost() uint32        { return binary.BigEndian.Uint32(p[13:17]) }
+func (p BPDU) BridgeID() BridgeID          { return *(*BridgeID)(p[17:25]) }
+func (p BPDU) PortID() uint16              { return binary.BigEndian.Uint16(p[25:27]) }
+func (p BPDU) MessageAge() time.Duration   { return bpduTime(p[27:29]) }
+func (p BPDU) MaxAge() time.Duration       { return bpduTime(p[29:31]) }
+func (p BPDU) HelloTime() time.Duration    { return bpduTime(p[31:33]) }
+func (p BPDU) ForwardDelay() time.Duration { return bpduTime(p[33:35]) }
+func (p BPDU) Version3Len() uint16         { return binary.BigEndian.Uint16(p[36:38]) } // MSTP only
+
+// PortRole returns the RSTP port role: 0 unknown, 1 alternate/backup, 2 root, 3 designated
+func (p BPDU) PortRole() uint8 { return (p[4] & BPDUFlagPortRole) >> 2 }
+
+// MSTConfigName returns the MST region name.
+func (p BPDU) MSTConfigName() string {
+	name := p[39 : 39+32]
+	for i := range name {
+		if name[i] == 0 {
+			return string(name[:i])
+		}
+	}
+	return string(name)
+}
+func (p BPDU) MSTConfigRevision() uint16        { return binary.BigEndian.Uint16(p[71:73]) }
+func (p BPDU) CISTInternalRootPathCost() uint32 { return binary.BigEndian.Uint32(p[89:93]) }
+func (p BPDU) CISTBridgeID() BridgeID           { return *(*BridgeID)(p[93:101]) }
+func (p BPDU) CISTRemainingHops() uint8         { return p[101] }
+
+// MSTIRecords returns the number of MST instance records in the BPDU.
+func (p BPDU) MSTIRecords() int {
+	if p.Version3Len() < 64 {
+		return 0
+	}
+	return (int(p.Version3Len()) - 64) / 16
+}
+
+// bpduTime converts the 1/256 seconds unit to a duration.
+func bpduTime(b []byte) time.Duration {
+	return time.Duration(binary.BigEndian.Uint16(b)) * time.Second / 256
+}
+
+func (p BPDU) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p BPDU) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8("version", p.Version())
+	line.Uint8Hex("type", p.Type())
+	if p.Type() == BPDUTypeTCN {
+		return line
+	}
+	line.Uint8Hex("flags", p.Flags())
+	line.String("root", p.RootID().String())
+	line.Uint32("cost", p.RootPathCost())
+	line.String("bridge", p.BridgeID().String())
+	line.Uint16Hex("port", p.PortID())
+	line.Bool("tc", p.TopologyChange())
+	if p.Version() == BPDUVersionMSTP {
+		line.String("region", p.MSTConfigName())
+		line.Int("msti", p.MSTIRecords())
+	}
+	return line
+}
+
+// STPEvent holds the details of a spanning tree event
+type STPEvent struct {
+	Root         BridgeID // current root bridge
+	PreviousRoot BridgeID // previous root bridge - zero if first root seen
+	Bridge       BridgeID // bridge that sent the BPDU
+	Port         uint16   // sending bridge port
+}
+
+// FastLog implements fastlog interface
+func (e STPEvent) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.String("root", e.Root.String())
+	if !e.PreviousRoot.IsZero() {
+		line.String("previousRoot", e.PreviousRoot.String())
+	}
+	line.String("bridge", e.Bridge.String())
+	line.Uint16Hex("port", e.Port)
+	return line
+}
+
+// stpState tracks the spanning tree root and topology change flag seen on the LAN
+type stpState struct {
+	root           BridgeID
+	lastSeen       time.Time
+	topologyChange bool
+}
+
+// STPRoot returns the current spanning tree root bridge and the time the last BPDU was received.
+// It returns a zero BridgeID if no BPDU has been seen.
+func (h *Session) STPRoot() (BridgeID, time.Time) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.stp.root, h.stp.lastSeen
+}
+
+// processBPDU updates the spanning tree state and sends an event if the root
+// bridge changed or a new topology change started.
+func (h *Session) processBPDU(addr Addr, p BPDU) {
+	now := time.Now()
+	events := make([]Event, 0, 2)
+
+	h.mutex.Lock()
+	if p.Type() != BPDUTypeTCN {
+		root := p.RootID()
+		if root != h.stp.root {
+			if !h.stp.root.IsZero() {
+				events = append(events, Event{Type: EventSTPRootChange, Time: now, Addr: addr,
+					Data: STPEvent{Root: root, PreviousRoot: h.stp.root, Bridge: p.BridgeID(), Port: p.PortID()}})
+			} else if Logger.IsInfo() {
+				Logger.Msg("stp root bridge").String("root", root.String()).Write()
+			}
+			h.stp.root = root
+		}
+	}
+	tc := p.TopologyChange()
+	if tc && !h.stp.topologyChange {
+		e := STPEvent{Root: h.stp.root}
+		if p.Type() != BPDUTypeTCN {
+			e.Bridge = p.BridgeID()
+			e.Port = p.PortID()
+		}
+		events = append(events, Event{Type: EventSTPTopologyChange, Time: now, Addr: addr, Data: e})
+	}
+	h.stp.topologyChange = tc
+	h.stp.lastSeen = now
+	h.mutex.Unlock()
+
+	for _, e := range events {
+		h.sendEvent(e)
+	}
+}
@@ -0,0 +1,148 @@
+package packet
+
+import (
+	"testing"
+	"time"
+)
+
+var testSTPConfig = []byte(
+	// 00:02:03:04:05:01 > 01:80:c2:00:00:00, 802.3, length 38: LLC, dsap STP (0x42) Individual, ssap STP (0x42) Command, ctrl 0x03: STP 802.1d, Config, Flags [none], bridge-id 8000.00:02:03:04:05:01.8001, length 35
+	`0180 c200 0000 0002 0304 0501 0026 4242` + //  ..............&BB
+		`0300 0000 0000 8000 0002 0304 0501 0000` + //  ................
+		`0004 8000 0002 0304 0501 8001 0000 1400` + //  ................
+		`0200 0f00 0000 0000 0000 0000          `) //  ............
+
+var testRSTPNewRoot = []byte(
+	// 00:02:03:04:05:01 > 01:80:c2:00:00:00, 802.3, length 39: LLC, dsap STP (0x42) Individual, ssap STP (0x42) Command, ctrl 0x03: STP 802.1w, Rapid STP, Flags [Learn, Forward], bridge-id 8000.00:02:03:04:05:01.8001, length 36
+	// root-id 1000.00:02:03:04:05:09
+	`0180 c200 0000 0002 0304 0501 0027 4242` + //  ..............'BB
+		`0300 0002 023c 1000 0002 0304 0509 0000` + //  .....<..........
+		`0004 8000 0002 0304 0501 8001 0000 1400` + //  ................
+		`0200 0f00 0000 0000 0000 0000          `) //  ............
+
+var testRSTPTopologyChange = []byte(
+	// same as testRSTPNewRoot with Flags [Topology change, Learn, Forward]
+	`0180 c200 0000 0002 0304 0501 0027 4242` + //  ..............'BB
+		`0300 0002 023d 1000 0002 0304 0509 0000` + //  .....=..........
+		`0004 8000 0002 0304 0501 8001 0000 1400` + //  ................
+		`0200 0f00 0000 0000 0000 0000          `) //  ............
+
+func TestBPDU_Decode(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        []byte
+		wantErr  bool
+		version  uint8
+		root     string
+		bridge   string
+		port     uint16
+		tc       bool
+		role     uint8
+		maxAge   time.Duration
+		fwdDelay time.Duration
+	}{
+		{name: "stp config", p: mustHex(testSTPConfig), version: BPDUVersionSTP, root: "32768/00:02:03:04:05:01", bridge: "32768/00:02:03:04:05:01",
+			port: 0x8001, maxAge: 20 * time.Second, fwdDelay: 15 * time.Second},
+		{name: "rstp", p: mustHex(testRSTPNewRoot), version: BPDUVersionRSTP, root: "4096/00:02:03:04:05:09", bridge: "32768/00:02:03:04:05:01",
+			port: 0x8001, role: 3, maxAge: 20 * time.Second, fwdDelay: 15 * time.Second},
+		{name: "rstp tc", p: mustHex(testRSTPTopologyChange), version: BPDUVersionRSTP, root: "4096/00:02:03:04:05:09", bridge: "32768/00:02:03:04:05:01",
+			port: 0x8001, tc: true, role: 3, maxAge: 20 * time.Second, fwdDelay: 15 * time.Second},
+		{name: "tcn", p: []byte{0x00, 0x00, 0x00, 0x80}, tc: true},
+		{name: "short", p: []byte{0x00, 0x00, 0x00, 0x00, 0x00}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := BPDU(tt.p)
+			if len(tt.p) > 17 { // full frame
+				p = BPDU(Ether(tt.p).Payload()[3:])
+			}
+			if err := p.IsValid(); (err != nil) != tt.wantErr {
+				t.Fatalf("BPDU.IsValid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if p.TopologyChange() != tt.tc {
+				t.Errorf("BPDU.TopologyChange() = %v, want %v", p.TopologyChange(), tt.tc)
+			}
+			if p.Type() == BPDUTypeTCN {
+				return
+			}
+			if p.Version() != tt.version {
+				t.Errorf("BPDU.Version() = %v, want %v", p.Version(), tt.version)
+			}
+			if got := p.RootID().String(); got != tt.root {
+				t.Errorf("BPDU.RootID() = %v, want %v", got, tt.root)
+			}
+			if got := p.BridgeID().String(); got != tt.bridge {
+				t.Errorf("BPDU.BridgeID() = %v, want %v", got, tt.bridge)
+			}
+			if p.PortID() != tt.port {
+				t.Errorf("BPDU.PortID() = %x, want %x", p.PortID(), tt.port)
+			}
+			if p.Version() == BPDUVersionRSTP && p.PortRole() != tt.role {
+				t.Errorf("BPDU.PortRole() = %v, want %v", p.PortRole(), tt.role)
+			}
+			if p.MaxAge() != tt.maxAge || p.ForwardDelay() != tt.fwdDelay {
+				t.Errorf("BPDU timers maxAge=%v fwdDelay=%v, want %v %v", p.MaxAge(), p.ForwardDelay(), tt.maxAge, tt.fwdDelay)
+			}
+		})
+	}
+}
+
+func TestSession_STPEvents(t *testing.T) {
+	session, _ := testSession()
+
+	readEvent := func() (Event, bool) {
+		select {
+		case e := <-session.Events:
+			return e, true
+		default:
+			return Event{}, false
+		}
+	}
+
+	// first root is recorded without an event
+	frame, err := session.Parse(mustHex(testSTPConfig))
+	if err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if frame.PayloadID != PayloadSTP {
+		t.Errorf("invalid payloadID=%v", frame.PayloadID)
+	}
+	if e, ok := readEvent(); ok {
+		t.Errorf("unexpected event %v", e)
+	}
+	if root, _ := session.STPRoot(); root.String() != "32768/00:02:03:04:05:01" {
+		t.Errorf("invalid root %v", root)
+	}
+
+	// new root
+	if _, err := session.Parse(mustHex(testRSTPNewRoot)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	e, ok := readEvent()
+	if !ok || e.Type != EventSTPRootChange {
+		t.Fatalf("invalid root change event %v", e)
+	}
+	if data := e.Data.(STPEvent); data.Root.String() != "4096/00:02:03:04:05:09" || data.PreviousRoot.String() != "32768/00:02:03:04:05:01" {
+		t.Errorf("invalid root change data %v", data)
+	}
+
+	// topology change is reported once
+	if _, err := session.Parse(mustHex(testRSTPTopologyChange)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if e, ok := readEvent(); !ok || e.Type != EventSTPTopologyChange {
+		t.Fatalf("invalid topology change event %v", e)
+	}
+	if _, err := session.Parse(mustHex(testRSTPTopologyChange)); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if e, ok := readEvent(); ok {
+		t.Errorf("unexpected event %v", e)
+	}
+	if session.Statistics[PayloadSTP].Count != 4 {
+		t.Errorf("invalid stp count=%d", session.Statistics[PayloadSTP].Count)
+	}
+}
@@ -0,0 +1,149 @@
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// Ubiquiti discovery TLV types
+const (
+	UbiquitiTypeMAC          = 0x01
+	UbiquitiTypeMACIP        = 0x02
+	UbiquitiTypeFirmware     = 0x03
+	UbiquitiTypeUptime       = 0x0a
+	UbiquitiTypeHostname     = 0x0b
+	UbiquitiTypePlatform     = 0x0c
+	UbiquitiTypeESSID        = 0x0d
+	UbiquitiTypeWirelessMode = 0x0e
+	UbiquitiTypeSystemID     = 0x10
+	UbiquitiTypeSerial       = 0x13
+	UbiquitiTypeModel        = 0x14
+	UbiquitiTypeModelShort   = 0x15
+	UbiquitiTypeVersion      = 0x16
+)
+
+// UbiquitiDiscovery provides access to Ubiquiti device discovery packets sent to UDP port 10001.
+// The controller and the UniFi app send an empty request and devices reply with a list of TLVs.
+//
+//	+--------+--------+--------+--------+
+//	| version| command| length (2)      |
+//	+--------+--------+--------+--------+
+//	| type   | length (2)      | value ...
+//	+--------+--------+--------+
+//
+// see https://github.com/nitefood/python-ubnt-discovery
+type UbiquitiDiscovery []byte
+
+func (p UbiquitiDiscovery) IsValid() error {
+	if len(p) < 4 {
+		return fmt.Errorf("ubiquiti len=%d: %w", len(p), ErrFrameLen)
+	}
+	if p.Version() != 1 && p.Version() != 2 {
+		return fmt.Errorf("ubiquiti version=%d: %w", p.Version(), ErrParseFrame)
+	}
+	if 4+int(p.Len()) > len(p) {
+		return fmt.Errorf("ubiquiti len=%d tlvlen=%d: %w", len(p), p.Len(), ErrFrameLen)
+	}
+	for b := p[4 : 4+p.Len()]; len(b) > 0; {
+		if len(b) < 3 || 3+int(binary.BigEndian.Uint16(b[1:3])) > len(b) {
+			return fmt.Errorf("ubiquiti tlv: %w", ErrParseFrame)
+		}
+		b = b[3+binary.BigEndian.Uint16(b[1:3]):]
+	}
+	return nil
+}
+
+func (p UbiquitiDiscovery) Version() uint8 { return p[0] }
+func (p UbiquitiDiscovery) Command() uint8 { return p[1] }
+func (p UbiquitiDiscovery) Len() uint16    { return binary.BigEndian.Uint16(p[2:4]) }
+
+// forEachTLV calls f for each TLV in the packet. The packet must be valid.
+func (p UbiquitiDiscovery) forEachTLV(f func(t uint8, v []byte)) {
+	for b := p[4 : 4+p.Len()]; len(b) >= 3; {
+		l := binary.BigEndian.Uint16(b[1:3])
+		f(b[0], b[3:3+l])
+		b = b[3+l:]
+	}
+}
+
+// GetTLV returns the value of the first TLV matching t or nil if not present.
+func (p UbiquitiDiscovery) GetTLV(t uint8) (value []byte) {
+	p.forEachTLV(func(tlv uint8, v []byte) {
+		if tlv == t && value == nil {
+			value = v
+		}
+	})
+	return value
+}
+
+func (p UbiquitiDiscovery) Hostname() string { return string(p.GetTLV(UbiquitiTypeHostname)) }
+func (p UbiquitiDiscovery) Platform() string { return string(p.GetTLV(UbiquitiTypePlatform)) }
+func (p UbiquitiDiscovery) Firmware() string { return string(p.GetTLV(UbiquitiTypeFirmware)) }
+func (p UbiquitiDiscovery) ESSID() string    { return string(p.GetTLV(UbiquitiTypeESSID)) }
+
+// Model returns the long model name if present or the short model name otherwise.
+func (p UbiquitiDiscovery) Model() string {
+	if v := p.GetTLV(UbiquitiTypeModel); v != nil {
+		return string(v)
+	}
+	return string(p.GetTLV(UbiquitiTypeModelShort))
+}
+
+func (p UbiquitiDiscovery) MAC() net.HardwareAddr {
+	if v := p.GetTLV(UbiquitiTypeMAC); len(v) == 6 {
+		return net.HardwareAddr(v)
+	}
+	return nil
+}
+
+func (p UbiquitiDiscovery) Uptime() time.Duration {
+	if v := p.GetTLV(UbiquitiTypeUptime); len(v) == 4 {
+		return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+	}
+	return 0
+}
+
+// Addrs returns the list of IPv4 addresses in the mac/ip TLVs.
+func (p UbiquitiDiscovery) Addrs() (list []netip.Addr) {
+	p.forEachTLV(func(t uint8, v []byte) {
+		if t == UbiquitiTypeMACIP && len(v) == 10 {
+			list = append(list, netip.AddrFrom4(*(*[4]byte)(v[6:10])))
+		}
+	})
+	return list
+}
+
+// NameEntry returns the device name and model.
+// It returns an empty entry for discovery requests.
+func (p UbiquitiDiscovery) NameEntry() NameEntry {
+	if p.Len() == 0 {
+		return NameEntry{}
+	}
+	return NameEntry{Type: "ubiquiti", Name: p.Hostname(), Model: p.Model(), Manufacturer: "Ubiquiti Inc."}
+}
+
+func (p UbiquitiDiscovery) String() string {
+	return Logger.Msg("").Struct(p).ToString()
+}
+
+// FastLog implements fastlog interface
+func (p UbiquitiDiscovery) FastLog(line *fastlog.Line) *fastlog.Line {
+	line.Uint8("version", p.Version())
+	line.Uint8Hex("cmd", p.Command())
+	if p.Len() == 0 {
+		return line
+	}
+	line.String("hostname", p.Hostname())
+	line.String("model", p.Model())
+	line.String("platform", p.Platform())
+	line.String("firmware", p.Firmware())
+	for _, v := range p.Addrs() {
+		line.IP("ip", v)
+	}
+	return line
+}
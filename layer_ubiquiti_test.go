@@ -0,0 +1,130 @@
+package packet
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+)
+
+// ubiquitiTLV returns a ubiquiti tlv with the value v
+func ubiquitiTLV(t uint8, v []byte) []byte {
+	b := []byte{t, 0, 0}
+	binary.BigEndian.PutUint16(b[1:3], uint16(len(v)))
+	return append(b, v...)
+}
+
+// testUbiquitiResponse returns a v1 discovery response from mac1 / ip1
+func testUbiquitiResponse() UbiquitiDiscovery {
+	var tlvs []byte
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypeMAC, mac1)...)
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypeMACIP, append(CopyBytes(mac1), ip1.AsSlice()...))...)
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypeFirmware, []byte("US.mt7621.v6.5.54"))...)
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypeUptime, []byte{0x00, 0x00, 0x0e, 0x10})...)
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypeHostname, []byte("switch-office"))...)
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypePlatform, []byte("USMINI"))...)
+	tlvs = append(tlvs, ubiquitiTLV(UbiquitiTypeModel, []byte("USW-Flex-Mini"))...)
+	p := []byte{0x01, 0x00, 0x00, 0x00}
+	binary.BigEndian.PutUint16(p[2:4], uint16(len(tlvs)))
+	return append(p, tlvs...)
+}
+
+// testUDPFrame returns an ethernet frame carrying payload from srcAddr to dstAddr
+func testUDPFrame(srcAddr Addr, dstAddr Addr, payload []byte) Ether {
+	ether := EncodeEther(make([]byte, EthMaxSize), syscall.ETH_P_IP, srcAddr.MAC, dstAddr.MAC)
+	ip4 := EncodeIP4(ether.Payload(), 255, srcAddr.IP, dstAddr.IP)
+	udp := EncodeUDP(ip4.Payload(), srcAddr.Port, dstAddr.Port)
+	udp, _ = udp.AppendPayload(payload)
+	ip4 = ip4.SetPayload(udp, syscall.IPPROTO_UDP)
+	ether, _ = ether.SetPayload(ip4)
+	return ether
+}
+
+func TestUbiquitiDiscovery_Decode(t *testing.T) {
+	p := testUbiquitiResponse()
+	if err := p.IsValid(); err != nil {
+		t.Fatal("invalid ubiquiti packet", err)
+	}
+	if p.Version() != 1 || p.Command() != 0 {
+		t.Errorf("invalid header version=%d cmd=%d", p.Version(), p.Command())
+	}
+	if p.Hostname() != "switch-office" || p.Model() != "USW-Flex-Mini" || p.Platform() != "USMINI" || p.Firmware() != "US.mt7621.v6.5.54" {
+		t.Errorf("invalid tlvs %s", p)
+	}
+	if p.MAC().String() != mac1.String() || p.Uptime().Hours() != 1 {
+		t.Errorf("invalid mac=%s uptime=%v", p.MAC(), p.Uptime())
+	}
+	if addrs := p.Addrs(); len(addrs) != 1 || addrs[0] != ip1 {
+		t.Errorf("invalid addrs %v", addrs)
+	}
+	name := p.NameEntry()
+	if name.Type != "ubiquiti" || name.Name != "switch-office" || name.Model != "USW-Flex-Mini" || name.Manufacturer != "Ubiquiti Inc." {
+		t.Errorf("invalid name %+v", name)
+	}
+
+	// discovery request is empty
+	request := UbiquitiDiscovery{0x01, 0x00, 0x00, 0x00}
+	if err := request.IsValid(); err != nil {
+		t.Error("invalid request", err)
+	}
+	if name := request.NameEntry(); name.Type != "" {
+		t.Errorf("unexpected request name %+v", name)
+	}
+
+	// invalid packets
+	for _, b := range []UbiquitiDiscovery{p[:3], p[:len(p)-1], {0x03, 0x00, 0x00, 0x00}, {0x01, 0x00, 0x00, 0x02, 0x0b, 0x00}} {
+		if err := b.IsValid(); err == nil {
+			t.Errorf("expected error for % x", []byte(b))
+		}
+	}
+}
+
+func TestSession_UbiquitiDeviceName(t *testing.T) {
+	session, _ := testSession()
+
+	srcAddr := Addr{MAC: mac1, IP: ip1, Port: 10001}
+	dstAddr := Addr{MAC: hostMAC, IP: hostIP4, Port: 40000}
+	frame, err := session.Parse(testUDPFrame(srcAddr, dstAddr, testUbiquitiResponse()))
+	if err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	if frame.PayloadID != PayloadUbiquiti || frame.Host == nil {
+		t.Fatalf("invalid frame payloadID=%v host=%v", frame.PayloadID, frame.Host)
+	}
+	session.mutex.RLock()
+	name, macName := frame.Host.DeviceName, frame.Host.MACEntry.DeviceName
+	session.mutex.RUnlock()
+	if name.Name != "switch-office" || name.Model != "USW-Flex-Mini" {
+		t.Errorf("invalid host device name %+v", name)
+	}
+	if macName.Name != "switch-office" {
+		t.Errorf("invalid mac device name %+v", macName)
+	}
+
+	// truncated payload is an error only when sent to the discovery port
+	if _, err := session.Parse(testUDPFrame(srcAddr, dstAddr, testUbiquitiResponse()[:10])); err != nil {
+		t.Error("unexpected error for source port", err)
+	}
+	if _, err := session.Parse(testUDPFrame(dstAddr, Addr{MAC: mac1, IP: ip1, Port: 10001}, testUbiquitiResponse()[:10])); err == nil {
+		t.Error("expected error for truncated packet")
+	}
+}
+
+func TestSession_SonosDeviceName(t *testing.T) {
+	session, _ := testSession()
+	frame := newTestHost(session, Addr{MAC: mac1, IP: ip1})
+
+	ether := EncodeEther(make([]byte, EthMaxSize), 0x6970, mac1, EthBroadcast)
+	ether, _ = ether.AppendPayload([]byte{0x01, 0x02, 0x03, 0x04})
+	if _, err := session.Parse(ether); err != nil {
+		t.Fatal("unexpected parse error", err)
+	}
+	session.mutex.RLock()
+	name := frame.Host.DeviceName
+	session.mutex.RUnlock()
+	if name.Type != "sonos" || name.Manufacturer != "Sonos, Inc." {
+		t.Errorf("invalid device name %+v", name)
+	}
+	if n := session.Statistics[PayloadSonos].Count; n != 1 {
+		t.Errorf("invalid sonos count %d", n)
+	}
+}
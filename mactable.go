@@ -31,6 +31,9 @@ type MACEntry struct {
 	SSDPName     NameEntry
 	LLMNRName    NameEntry
 	NBNSName     NameEntry
+	WSDName      NameEntry
+	DeviceName   NameEntry // name from vendor discovery protocols - ubiquiti, plex and sonos
+	FirstSeen    time.Time
 	LastSeen     time.Time
 }
 
@@ -63,6 +66,8 @@ func (e *MACEntry) FastLog(l *fastlog.Line) *fastlog.Line {
 	l.Struct(e.SSDPName)
 	l.Struct(e.LLMNRName)
 	l.Struct(e.NBNSName)
+	l.Struct(e.WSDName)
+	l.Struct(e.DeviceName)
 	return l
 }
 
@@ -107,7 +112,7 @@ func (s *MACTable) findOrCreate(mac net.HardwareAddr) *MACEntry {
 	if e, _ := s.findMAC(mac); e != nil {
 		return e
 	}
-	e := &MACEntry{MAC: CopyMAC(mac), IP4: IPv4zero, IP6GUA: IPv6zero, IP6LLA: IPv6zero, IP4Offer: netip.Addr{}}
+	e := &MACEntry{MAC: CopyMAC(mac), IP4: IPv4zero, IP6GUA: IPv6zero, IP6LLA: IPv6zero, IP4Offer: netip.Addr{}, FirstSeen: time.Now()}
 	s.Table = append(s.Table, e)
 	return e
 }
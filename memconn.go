@@ -3,6 +3,8 @@ package packet
 import (
 	"fmt"
 	"net"
+	"os"
+	"sync"
 	"time"
 )
 
@@ -12,6 +14,10 @@ const maxBufSize = 512
 type bufferedPacketConn struct {
 	clientChan chan []byte
 	serverChan chan []byte
+	mutex      sync.Mutex
+	deadline   chan struct{} // closed when the read deadline expires
+	expired    bool
+	timer      *time.Timer
 }
 
 // TestNewBufferedConn create a mem conn for testing
@@ -28,14 +34,61 @@ func (p *bufferedPacketConn) Close() error {
 
 func (p *bufferedPacketConn) LocalAddr() net.Addr                { return nil }
 func (p *bufferedPacketConn) SetDeadline(t time.Time) error      { return nil }
-func (p *bufferedPacketConn) SetReadDeadline(t time.Time) error  { return nil }
 func (p *bufferedPacketConn) SetWriteDeadline(t time.Time) error { return nil }
 
+// SetReadDeadline sets the deadline for ReadFrom; a zero t clears the deadline.
+// A deadline in the past unblocks a pending ReadFrom.
+func (p *bufferedPacketConn) SetReadDeadline(t time.Time) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if p.deadline == nil || p.expired {
+		p.deadline, p.expired = make(chan struct{}), false
+	}
+	switch d := time.Until(t); {
+	case t.IsZero():
+	case d <= 0:
+		p.expire()
+	default:
+		deadline := p.deadline
+		p.timer = time.AfterFunc(d, func() {
+			p.mutex.Lock()
+			defer p.mutex.Unlock()
+			if p.deadline == deadline {
+				p.expire()
+			}
+		})
+	}
+	return nil
+}
+
+// expire closes the deadline channel; the caller must hold the mutex.
+func (p *bufferedPacketConn) expire() {
+	if !p.expired {
+		close(p.deadline)
+		p.expired = true
+	}
+}
+
 func (p *bufferedPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	p.mutex.Lock()
+	if p.deadline == nil {
+		p.deadline = make(chan struct{})
+	}
+	deadline := p.deadline
+	p.mutex.Unlock()
+
 	// will lock and wait
-	buf := <-p.serverChan
-	n := copy(b[:cap(b)], buf)
-	return n, nil, nil
+	select {
+	case buf := <-p.serverChan:
+		n := copy(b[:cap(b)], buf)
+		return n, nil, nil
+	case <-deadline:
+		return 0, nil, os.ErrDeadlineExceeded
+	}
 }
 
 func (p *bufferedPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
@@ -0,0 +1,305 @@
+package packet
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+	"github.com/vishvananda/netlink"
+)
+
+// nicUpdateDelay groups the burst of netlink messages sent for a single change, i.e. a dhcp
+// renew deletes and adds addresses and routes.
+// It is a variable so we can test easily.
+var nicUpdateDelay = time.Millisecond * 500
+
+// NICChangeEvent is the Data of EventNICChanged events.
+type NICChangeEvent struct {
+	Previous NICInfo
+	Current  NICInfo
+}
+
+func (e NICChangeEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.String("previous", e.Previous.String())
+	l.String("current", e.Current.String())
+	return l
+}
+
+// equal returns true if the addresses in both nics are the same.
+func (e NICInfo) equal(info NICInfo) bool {
+	return e.HomeLAN4 == info.HomeLAN4 &&
+		e.HostAddr4.IP == info.HostAddr4.IP && bytes.Equal(e.HostAddr4.MAC, info.HostAddr4.MAC) &&
+		e.RouterAddr4.IP == info.RouterAddr4.IP && bytes.Equal(e.RouterAddr4.MAC, info.RouterAddr4.MAC) &&
+		e.HostLLA == info.HostLLA && e.HostGUA == info.HostGUA &&
+		e.RouterLLA == info.RouterLLA && e.RouterGUA == info.RouterGUA &&
+		e.RouterPrefix.Equal(info.RouterPrefix)
+}
+
+// SetNICInfo replaces the session nic information and sends an EventNICChanged event.
+// It also sends an EventRouterChanged to subscribers if the ipv4 router changed.
+//
+// Session never modifies NICInfo in place; NICInfo() returns a new copy after each change
+// so the caller should call NICInfo() once to get a consistent view.
+func (h *Session) SetNICInfo(info NICInfo) {
+	h.mutex.Lock()
+	previous := *h.NICInfo()
+	if previous.equal(info) {
+		h.mutex.Unlock()
+		return
+	}
+	info.HostAddr4 = Addr{MAC: CopyMAC(info.HostAddr4.MAC), IP: info.HostAddr4.IP}
+	info.RouterPrefix = CopyIP(info.RouterPrefix)
+	if host := h.HostTable.Table[info.RouterAddr4.IP]; info.RouterAddr4.MAC == nil && host != nil {
+		info.RouterAddr4.MAC = host.MACEntry.MAC // neighbour entry not resolved yet
+	}
+	routerChanged := previous.RouterAddr4.IP != info.RouterAddr4.IP || !bytes.Equal(previous.RouterAddr4.MAC, info.RouterAddr4.MAC)
+	if routerChanged {
+		info.RouterAddr4 = h.setRouter(previous.RouterAddr4, info.RouterAddr4)
+	}
+	hostChanged := previous.HostAddr4.IP != info.HostAddr4.IP || !bytes.Equal(previous.HostAddr4.MAC, info.HostAddr4.MAC)
+	if host := h.HostTable.Table[previous.HostAddr4.IP]; hostChanged && host != nil {
+		host.LastSeen = time.Now() // let the previous entry expire
+		host.MACEntry.LastSeen = host.LastSeen
+	}
+	h.nicInfo.Store(&info)
+	h.mutex.Unlock()
+
+	if hostChanged {
+		h.setHostEntry(info)
+	}
+	if Logger.IsInfo() {
+		Logger.Msg("nic changed").String("previous", previous.String()).String("current", info.String()).Write()
+	}
+	h.sendEvent(Event{Type: EventNICChanged, Time: time.Now(), Addr: info.HostAddr4, Data: NICChangeEvent{Previous: previous, Current: info}})
+	if routerChanged {
+		h.publishHost(EventRouterChanged, info.RouterAddr4, RouterChangeEvent{Router: info.RouterAddr4, Previous: previous.RouterAddr4})
+	}
+}
+
+// setHostEntry creates our own host entry manually because we don't create it for host packets.
+// The entry never expires.
+func (h *Session) setHostEntry(info NICInfo) {
+	host, _ := h.findOrCreateHostWithLock(info.HostAddr4)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	host.LastSeen = time.Now().Add(time.Hour * 24 * 365) // never expire
+	host.MACEntry.LastSeen = host.LastSeen
+	host.MACEntry.IP4 = host.Addr.IP
+	host.MACEntry.IP6LLA = info.HostLLA.Addr()
+	host.Online = true
+	host.MACEntry.Online = true
+}
+
+// setRouter moves the router flag from the previous router mac entry to the router mac entry
+// and returns a copy of router. The caller must hold the lock.
+func (h *Session) setRouter(previous Addr, router Addr) Addr {
+	if e, _ := h.MACTable.findMAC(previous.MAC); e != nil {
+		e.IsRouter = false
+	}
+	router = Addr{MAC: CopyMAC(router.MAC), IP: router.IP}
+	if len(router.MAC) == EthAddrLen {
+		e := h.MACTable.findOrCreate(router.MAC)
+		e.IsRouter = true
+		e.Captured = false // never capture the router
+	}
+	return router
+}
+
+// monitorNetlink subscribes to netlink link, address, route and neighbour updates for the session nic
+// and calls SetNICInfo when the nic configuration changes. Neighbour updates are only used to
+// resolve the router mac; it is often unknown when the router changes.
+func (h *Session) monitorNetlink() error {
+	index := h.NICInfo().IFI.Index
+	done := make(chan struct{})
+	links := make(chan netlink.LinkUpdate, 16)
+	addrs := make(chan netlink.AddrUpdate, 16)
+	routes := make(chan netlink.RouteUpdate, 16)
+	neighs := make(chan netlink.NeighUpdate, 16)
+	if err := netlink.LinkSubscribe(links, done); err != nil {
+		close(done)
+		return err
+	}
+	if err := netlink.AddrSubscribe(addrs, done); err != nil {
+		close(done)
+		return err
+	}
+	if err := netlink.RouteSubscribe(routes, done); err != nil {
+		close(done)
+		return err
+	}
+	if err := netlink.NeighSubscribe(neighs, done); err != nil {
+		close(done)
+		return err
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer close(done) // netlink closes the update channels
+		timer := time.NewTimer(nicUpdateDelay)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			changed := false
+			select {
+			case u, ok := <-links:
+				if !ok {
+					return
+				}
+				changed = u.Attrs().Index == index
+			case u, ok := <-addrs:
+				if !ok {
+					return
+				}
+				changed = u.LinkIndex == index
+			case u, ok := <-routes:
+				if !ok {
+					return
+				}
+				changed = u.LinkIndex == index
+			case u, ok := <-neighs:
+				if !ok {
+					return
+				}
+				changed = routerNeighChanged(u, index, h.NICInfo().RouterAddr4)
+			case <-timer.C:
+				if err := h.refreshNICInfo(index); err != nil {
+					Logger.Msg("failed to refresh nic").Int("index", index).Error(err).Write()
+				}
+			case <-h.ctx.Done():
+				if Logger.IsDebug() {
+					Logger.Msg("netlink monitoring goroutine ended").Write()
+				}
+				return
+			}
+			if changed {
+				timer.Reset(nicUpdateDelay)
+			}
+		}
+	}()
+	return nil
+}
+
+// routerNeighChanged returns true if the neighbour update resolves a new mac for the router on the nic.
+func routerNeighChanged(u netlink.NeighUpdate, index int, router Addr) bool {
+	if u.Type != syscall.RTM_NEWNEIGH || u.LinkIndex != index || len(u.HardwareAddr) != EthAddrLen {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(u.IP)
+	return ok && ip.Unmap() == router.IP && !bytes.Equal(u.HardwareAddr, router.MAC)
+}
+
+// refreshNICInfo reads the nic addresses, routes and neighbours and updates the session nic.
+func (h *Session) refreshNICInfo(index int) error {
+	link, err := netlink.LinkByIndex(index)
+	if err != nil {
+		return err
+	}
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return err
+	}
+	routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		return err
+	}
+	neighs, err := netlink.NeighList(index, netlink.FAMILY_ALL)
+	if err != nil {
+		return err
+	}
+	current := *h.NICInfo()
+	h.SetNICInfo(nicInfoFromNetlink(current, link.Attrs(), addrs, routes, neighs))
+	return nil
+}
+
+// nicInfoFromNetlink returns a copy of current updated with the netlink configuration.
+//
+// The current addresses are kept if still present so a secondary address does not replace the
+// primary one. The current ipv4 address and router are also kept if the nic has none; this
+// happens temporarily during a dhcp renumbering. When the current router has no default route,
+// the default route with the lowest metric is selected.
+func nicInfoFromNetlink(current NICInfo, attrs *netlink.LinkAttrs, addrs []netlink.Addr, routes []netlink.Route, neighs []netlink.Neigh) NICInfo {
+	info := current
+	if current.IFI != nil && attrs != nil {
+		ifi := *current.IFI
+		ifi.Name, ifi.MTU, ifi.HardwareAddr, ifi.Flags = attrs.Name, attrs.MTU, CopyMAC(attrs.HardwareAddr), attrs.Flags
+		info.IFI = &ifi
+	}
+	if attrs != nil && len(attrs.HardwareAddr) == EthAddrLen {
+		info.HostAddr4.MAC = CopyMAC(attrs.HardwareAddr)
+	}
+
+	var ip4, lla, gua netip.Prefix
+	for _, v := range addrs {
+		if v.IPNet == nil {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(v.IPNet.IP)
+		if !ok {
+			continue
+		}
+		ip = ip.Unmap()
+		bits, _ := v.IPNet.Mask.Size()
+		prefix := netip.PrefixFrom(ip, bits)
+		switch {
+		case ip.Is4() && ip.IsGlobalUnicast():
+			if !ip4.IsValid() || ip == current.HostAddr4.IP {
+				ip4 = prefix
+			}
+		case ip.Is6() && ip.IsLinkLocalUnicast():
+			if !lla.IsValid() || prefix == current.HostLLA {
+				lla = prefix
+			}
+		case ip.Is6() && ip.IsGlobalUnicast():
+			if !gua.IsValid() || prefix == current.HostGUA {
+				gua = prefix
+			}
+		}
+	}
+	if ip4.IsValid() {
+		info.HostAddr4.IP = ip4.Addr()
+		info.HomeLAN4 = ip4.Masked()
+	}
+	info.HostLLA, info.HostGUA = lla, gua
+	info.RouterPrefix = nil
+	if gua.IsValid() {
+		info.RouterPrefix = net.IP(gua.Masked().Addr().AsSlice())
+	}
+
+	var router4 netip.Addr
+	priority := -1
+	for _, r := range routes {
+		if (r.Dst != nil && !r.Dst.IP.IsUnspecified()) || r.Gw == nil {
+			continue // not a default route
+		}
+		gw, ok := netip.AddrFromSlice(r.Gw)
+		if !ok {
+			continue
+		}
+		gw = gw.Unmap()
+		switch {
+		case gw.Is4():
+			// keep the current router while its route exists; otherwise use the lowest metric
+			if router4 != current.RouterAddr4.IP && (gw == current.RouterAddr4.IP || priority == -1 || r.Priority < priority) {
+				router4, priority = gw, r.Priority
+			}
+		case gw.Is6() && gw.IsLinkLocalUnicast():
+			info.RouterLLA = netip.PrefixFrom(gw, 64)
+		case gw.Is6():
+			info.RouterGUA = netip.PrefixFrom(gw, 64)
+		}
+	}
+	if router4.IsValid() && router4 != current.RouterAddr4.IP {
+		info.RouterAddr4 = Addr{IP: router4}
+	}
+	for _, n := range neighs {
+		if ip, ok := netip.AddrFromSlice(n.IP); ok && ip.Unmap() == info.RouterAddr4.IP && len(n.HardwareAddr) == EthAddrLen {
+			info.RouterAddr4.MAC = CopyMAC(n.HardwareAddr)
+			break
+		}
+	}
+	return info
+}
@@ -0,0 +1,156 @@
+package packet
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+)
+
+func testNetlinkAddr(prefix string) netlink.Addr {
+	p := netip.MustParsePrefix(prefix)
+	return netlink.Addr{IPNet: &net.IPNet{IP: p.Addr().AsSlice(), Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen())}}
+}
+
+func Test_nicInfoFromNetlink(t *testing.T) {
+	hostLLA := netip.MustParsePrefix("fe80::10/64")
+	hostGUA := netip.MustParsePrefix("2001:db8::10/64")
+	current := NICInfo{
+		IFI:         &net.Interface{Index: 2, Name: "eth0", HardwareAddr: hostMAC},
+		HomeLAN4:    homeLAN,
+		HostAddr4:   Addr{MAC: hostMAC, IP: hostIP4},
+		RouterAddr4: Addr{MAC: routerMAC, IP: routerIP4},
+		HostLLA:     hostLLA,
+	}
+	attrs := &netlink.LinkAttrs{Index: 2, Name: "eth0", HardwareAddr: hostMAC, MTU: 1500}
+	defaultRoute := func(gw netip.Addr) netlink.Route { return netlink.Route{LinkIndex: 2, Gw: gw.AsSlice()} }
+	metricRoute := func(gw netip.Addr, priority int) netlink.Route {
+		return netlink.Route{LinkIndex: 2, Gw: gw.AsSlice(), Priority: priority}
+	}
+
+	tests := []struct {
+		name       string
+		addrs      []netlink.Addr
+		routes     []netlink.Route
+		neighs     []netlink.Neigh
+		wantHost   netip.Addr
+		wantLAN    netip.Prefix
+		wantRouter Addr
+		wantGUA    netip.Prefix
+		wantPrefix net.IP
+	}{
+		{name: "no change", addrs: []netlink.Addr{testNetlinkAddr("192.168.0.129/24"), testNetlinkAddr("fe80::10/64")},
+			routes: []netlink.Route{defaultRoute(routerIP4)}, wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: routerMAC, IP: routerIP4}},
+		{name: "keep primary", addrs: []netlink.Addr{testNetlinkAddr("192.168.0.200/24"), testNetlinkAddr("192.168.0.129/24")},
+			routes: []netlink.Route{defaultRoute(routerIP4)}, wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: routerMAC, IP: routerIP4}},
+		{name: "renumber", addrs: []netlink.Addr{testNetlinkAddr("10.0.0.5/16")},
+			routes:   []netlink.Route{defaultRoute(netip.MustParseAddr("10.0.0.1"))},
+			neighs:   []netlink.Neigh{{IP: net.ParseIP("10.0.0.1"), HardwareAddr: mac1}},
+			wantHost: netip.MustParseAddr("10.0.0.5"), wantLAN: netip.MustParsePrefix("10.0.0.0/16"),
+			wantRouter: Addr{MAC: mac1, IP: netip.MustParseAddr("10.0.0.1")}},
+		{name: "router replaced", addrs: []netlink.Addr{testNetlinkAddr("192.168.0.129/24")},
+			routes: []netlink.Route{defaultRoute(routerIP4)}, neighs: []netlink.Neigh{{IP: routerIP4.AsSlice(), HardwareAddr: mac2}},
+			wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: mac2, IP: routerIP4}},
+		{name: "keep current router", addrs: []netlink.Addr{testNetlinkAddr("192.168.0.129/24")},
+			routes:   []netlink.Route{metricRoute(ip1, 50), metricRoute(routerIP4, 100), metricRoute(ip2, 10)},
+			wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: routerMAC, IP: routerIP4}},
+		{name: "lowest metric", addrs: []netlink.Addr{testNetlinkAddr("192.168.0.129/24")},
+			routes:   []netlink.Route{metricRoute(ip1, 600), metricRoute(ip2, 100), metricRoute(ip3, 300)},
+			neighs:   []netlink.Neigh{{IP: ip2.AsSlice(), HardwareAddr: mac2}},
+			wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: mac2, IP: ip2}},
+		{name: "address removed", wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: routerMAC, IP: routerIP4}},
+		{name: "new prefix", addrs: []netlink.Addr{testNetlinkAddr("192.168.0.129/24"), testNetlinkAddr("2001:db8::10/64")},
+			routes: []netlink.Route{defaultRoute(routerIP4)}, wantHost: hostIP4, wantLAN: homeLAN, wantRouter: Addr{MAC: routerMAC, IP: routerIP4},
+			wantGUA: hostGUA, wantPrefix: net.ParseIP("2001:db8::")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nicInfoFromNetlink(current, attrs, tt.addrs, tt.routes, tt.neighs)
+			if got.HostAddr4.IP != tt.wantHost || got.HomeLAN4 != tt.wantLAN || !bytes.Equal(got.HostAddr4.MAC, hostMAC) {
+				t.Errorf("invalid host got=%s %s want=%s %s", got.HostAddr4, got.HomeLAN4, tt.wantHost, tt.wantLAN)
+			}
+			if got.RouterAddr4.IP != tt.wantRouter.IP || !bytes.Equal(got.RouterAddr4.MAC, tt.wantRouter.MAC) {
+				t.Errorf("invalid router got=%s want=%s", got.RouterAddr4, tt.wantRouter)
+			}
+			if got.HostGUA != tt.wantGUA || !got.RouterPrefix.Equal(tt.wantPrefix) {
+				t.Errorf("invalid ipv6 got=%s %s want=%s %s", got.HostGUA, got.RouterPrefix, tt.wantGUA, tt.wantPrefix)
+			}
+			if got.IFI == current.IFI || got.IFI.MTU != 1500 {
+				t.Errorf("invalid interface %+v", got.IFI)
+			}
+		})
+	}
+}
+
+func Test_routerNeighChanged(t *testing.T) {
+	neigh := func(t uint16, index int, ip netip.Addr, mac net.HardwareAddr) netlink.NeighUpdate {
+		return netlink.NeighUpdate{Type: t, Neigh: netlink.Neigh{LinkIndex: index, IP: ip.AsSlice(), HardwareAddr: mac}}
+	}
+	tests := []struct {
+		name   string
+		update netlink.NeighUpdate
+		router Addr
+		want   bool
+	}{
+		{name: "resolved", update: neigh(syscall.RTM_NEWNEIGH, 2, routerIP4, routerMAC), router: Addr{IP: routerIP4}, want: true},
+		{name: "mac changed", update: neigh(syscall.RTM_NEWNEIGH, 2, routerIP4, mac1), router: Addr{MAC: routerMAC, IP: routerIP4}, want: true},
+		{name: "same mac", update: neigh(syscall.RTM_NEWNEIGH, 2, routerIP4, routerMAC), router: Addr{MAC: routerMAC, IP: routerIP4}},
+		{name: "other ip", update: neigh(syscall.RTM_NEWNEIGH, 2, ip1, mac1), router: Addr{IP: routerIP4}},
+		{name: "other nic", update: neigh(syscall.RTM_NEWNEIGH, 3, routerIP4, routerMAC), router: Addr{IP: routerIP4}},
+		{name: "incomplete", update: neigh(syscall.RTM_NEWNEIGH, 2, routerIP4, nil), router: Addr{IP: routerIP4}},
+		{name: "deleted", update: neigh(syscall.RTM_DELNEIGH, 2, routerIP4, routerMAC), router: Addr{IP: routerIP4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routerNeighChanged(tt.update, 2, tt.router); got != tt.want {
+				t.Errorf("routerNeighChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_SetNICInfo(t *testing.T) {
+	session, _ := testSession()
+	defer session.Close()
+	sub := session.Subscribe(0, EventTypes(EventNICChanged, EventRouterChanged))
+
+	previous := session.NICInfo()
+	info := *session.NICInfo()
+	session.SetNICInfo(info)
+	if session.NICInfo() != previous || len(sub.C) != 0 {
+		t.Fatal("nic info must not change")
+	}
+
+	newIP := netip.MustParseAddr("192.168.0.130")
+	info.HostAddr4 = Addr{MAC: hostMAC, IP: newIP}
+	info.RouterAddr4 = Addr{MAC: mac1, IP: ip1}
+	session.SetNICInfo(info)
+	if session.NICInfo() == previous || previous.HostAddr4.IP != hostIP4 {
+		t.Error("nic info must be replaced not modified")
+	}
+	if session.NICInfo().HostAddr4.IP != newIP || session.NICInfo().RouterAddr4.IP != ip1 {
+		t.Errorf("invalid nic info %s", session.NICInfo())
+	}
+	if e := session.FindMACEntry(mac1); e == nil || !e.IsRouter {
+		t.Error("new router not flagged")
+	}
+	if e := session.FindMACEntry(routerMAC); e == nil || e.IsRouter {
+		t.Error("previous router still flagged")
+	}
+	if host := session.FindIP(newIP); host == nil || !host.Online {
+		t.Error("host entry not created")
+	}
+
+	for _, want := range []EventType{EventNICChanged, EventRouterChanged} {
+		e := <-sub.C
+		if e.Type != want {
+			t.Errorf("invalid event got=%s want=%s", e.Type, want)
+		}
+		if data, ok := e.Data.(NICChangeEvent); ok && (data.Previous.HostAddr4.IP != hostIP4 || data.Current.HostAddr4.IP != newIP) {
+			t.Errorf("invalid event data %+v", data)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package packet
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/deeGraYve/packet/fastlog"
 )
 
@@ -13,6 +16,8 @@ type Notification struct {
 	SSDPName     NameEntry
 	LLMNRName    NameEntry
 	NBNSName     NameEntry
+	WSDName      NameEntry
+	DeviceName   NameEntry
 	IsRouter     bool
 }
 
@@ -33,6 +38,8 @@ func (n Notification) FastLog(l *fastlog.Line) *fastlog.Line {
 	l.Struct(n.SSDPName)
 	l.Struct(n.LLMNRName)
 	l.Struct(n.NBNSName)
+	l.Struct(n.WSDName)
+	l.Struct(n.DeviceName)
 	l.Bool("router", n.IsRouter)
 	return l
 }
@@ -41,14 +48,165 @@ func toNotification(host *Host) Notification {
 	// send the MACEntry name as there can be many IPv6 hosts, some with name entries not populated yet
 	return Notification{Addr: host.Addr, Online: host.Online, Manufacturer: host.MACEntry.Manufacturer,
 		DHCP4Name: host.MACEntry.DHCP4Name, MDNSName: host.MACEntry.MDNSName, SSDPName: host.MACEntry.SSDPName,
-		LLMNRName: host.LLMNRName, NBNSName: host.MACEntry.NBNSName,
-		IsRouter: host.MACEntry.IsRouter}
+		LLMNRName: host.LLMNRName, NBNSName: host.MACEntry.NBNSName, WSDName: host.MACEntry.WSDName,
+		DeviceName: host.MACEntry.DeviceName,
+		IsRouter:   host.MACEntry.IsRouter}
 }
 
+// sendNotification sends the notification to the deprecated Session.C channel unless the
+// application uses Subscribe.
 func (h *Session) sendNotification(notification Notification) {
-	if len(h.C) < cap(h.C) {
-		h.C <- notification
+	h.closeMutex.RLock()
+	defer h.closeMutex.RUnlock()
+	if h.closed || h.bus.subscribed.Load() {
+		return
+	}
+	select {
+	case h.C <- notification:
+	default:
+		if Logger.IsDebug() {
+			Logger.Msg("notification channel is full").Int("len", len(h.C)).Struct(notification).Write()
+		}
+	}
+}
+
+// EventType identifies the type of a session event
+type EventType int
+
+// Session event types
+const (
+	EventSTPRootChange              EventType = 1  // spanning tree root bridge changed
+	EventSTPTopologyChange          EventType = 2  // spanning tree topology change notification
+	EventRRCPLoopDetected           EventType = 3  // realtek loop detection frame seen more than once
+	EventICMPDestinationUnreachable EventType = 4  // icmp destination unreachable for a flow
+	EventICMPFragmentationNeeded    EventType = 5  // icmp fragmentation needed; possible pmtu blackhole
+	EventLatencyDegraded            EventType = 6  // host round trip time increased or host is dropping echo requests
+	EventLatencyRecovered           EventType = 7  // host latency is back to normal
+	EventIPConflict                 EventType = 8  // two macs claiming the same ip address
+	EventGatewayImpersonation       EventType = 9  // arp or neighbour advertisement claiming the router ip from another mac
+	EventMACFlapping                EventType = 10 // mac for an ip changing back and forth
+	EventRogueDHCP4Server           EventType = 11 // dhcp offer or ack from an untrusted server
+	EventDHCP4OptionsChanged        EventType = 12 // dhcp server handing out a different router or dns
+	EventRogueRouterAdvertisement   EventType = 13 // ipv6 router advertisement from a router not in the ra guard policy
+	EventRALifetimeFlapping         EventType = 14 // router lifetime alternating between zero and non zero
+	EventRAOptionsChanged           EventType = 15 // unexpected prefix or a router changing its prefixes or rdnss
+	EventDeviceMACChanged           EventType = 16 // known device seen with a new mac address
+	EventHostOnline                 EventType = 17 // host ip is online; sent to subscribers only
+	EventHostOffline                EventType = 18 // host ip is offline; sent to subscribers only
+	EventNewDevice                  EventType = 19 // first frame from a mac not in the mac table; sent to subscribers only
+	EventIPChanged                  EventType = 20 // mac using a new ipv4 or ipv6 global address; sent to subscribers only
+	EventNameChanged                EventType = 21 // host name updated by a naming protocol; sent to subscribers only
+	EventCaptured                   EventType = 22 // mac set to capture mode; sent to subscribers only
+	EventReleased                   EventType = 23 // mac released from capture mode; sent to subscribers only
+	EventRouterChanged              EventType = 24 // default router changed; sent to subscribers only
+	EventNICStopped                 EventType = 25 // nic is not receiving ip packets; switch port may be disabled
+	EventNICRecovered               EventType = 26 // nic is receiving ip packets again
+	EventNICChanged                 EventType = 27 // nic addresses or routers changed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSTPRootChange:
+		return "stp_root_change"
+	case EventSTPTopologyChange:
+		return "stp_topology_change"
+	case EventRRCPLoopDetected:
+		return "rrcp_loop_detected"
+	case EventICMPDestinationUnreachable:
+		return "icmp_destination_unreachable"
+	case EventICMPFragmentationNeeded:
+		return "icmp_fragmentation_needed"
+	case EventLatencyDegraded:
+		return "latency_degraded"
+	case EventLatencyRecovered:
+		return "latency_recovered"
+	case EventIPConflict:
+		return "ip_conflict"
+	case EventGatewayImpersonation:
+		return "gateway_impersonation"
+	case EventMACFlapping:
+		return "mac_flapping"
+	case EventRogueDHCP4Server:
+		return "rogue_dhcp4_server"
+	case EventDHCP4OptionsChanged:
+		return "dhcp4_options_changed"
+	case EventRogueRouterAdvertisement:
+		return "rogue_router_advertisement"
+	case EventRALifetimeFlapping:
+		return "ra_lifetime_flapping"
+	case EventRAOptionsChanged:
+		return "ra_options_changed"
+	case EventDeviceMACChanged:
+		return "device_mac_changed"
+	case EventHostOnline:
+		return "host_online"
+	case EventHostOffline:
+		return "host_offline"
+	case EventNewDevice:
+		return "new_device"
+	case EventIPChanged:
+		return "ip_changed"
+	case EventNameChanged:
+		return "name_changed"
+	case EventCaptured:
+		return "captured"
+	case EventReleased:
+		return "released"
+	case EventRouterChanged:
+		return "router_changed"
+	case EventNICStopped:
+		return "nic_stopped"
+	case EventNICRecovered:
+		return "nic_recovered"
+	case EventNICChanged:
+		return "nic_changed"
+	}
+	return "event(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Event describes a network event detected by the session that is not
+// tied to a host online or offline transition.
+//
+// Data holds the event specific details and the caller can type assert it
+// based on the event type.
+type Event struct {
+	Type EventType
+	Time time.Time
+	Addr Addr            // source of the frame that triggered the event
+	Data fastlog.FastLog // event details
+}
+
+func (e Event) String() string {
+	line := Logger.Msg("")
+	e.FastLog(line)
+	return line.ToString()
+}
+
+func (e Event) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.String("event", e.Type.String())
+	l.Struct(e.Addr)
+	l.Struct(e.Data)
+	return l
+}
+
+func (h *Session) sendEvent(event Event) {
+	h.closeMutex.RLock()
+	defer h.closeMutex.RUnlock()
+	if h.closed {
 		return
 	}
-	Logger.Msg("notification channel is full").Int("len", len(h.C)).Struct(notification).Write()
+	if Logger.IsInfo() {
+		Logger.Msg("event").Struct(event).Write()
+	}
+	h.bus.publish(event)
+	if h.bus.subscribed.Load() {
+		return // the deprecated channel is not drained by subscribers
+	}
+	select {
+	case h.Events <- event:
+	default:
+		if Logger.IsDebug() {
+			Logger.Msg("event channel is full").Int("len", len(h.Events)).Struct(event).Write()
+		}
+	}
 }
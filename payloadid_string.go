@@ -37,11 +37,13 @@ func _() {
 	_ = x[PayloadIEEE1905-27]
 	_ = x[PayloadSonos-28]
 	_ = x[Payload880a-29]
+	_ = x[PayloadSTP-30]
+	_ = x[PayloadCDP-31]
 }
 
-const _PayloadID_name = "PayloadEtherPayload8023PayloadARPPayloadIP4PayloadIP6PayloadICMP4PayloadICMP6PayloadUDPPayloadTCPPayloadDHCP4PayloadDHCP6PayloadDNSPayloadMDNSPayloadSSLPayloadNTPPayloadSSDPPayloadWSDPPayloadNBNSPayloadPlexPayloadUbiquitiPayloadLLMNRPayloadIGMPPayloadEthernetPausePayloadRRCPPayloadLLDPPayload802_11rPayloadIEEE1905PayloadSonosPayload880a"
+const _PayloadID_name = "PayloadEtherPayload8023PayloadARPPayloadIP4PayloadIP6PayloadICMP4PayloadICMP6PayloadUDPPayloadTCPPayloadDHCP4PayloadDHCP6PayloadDNSPayloadMDNSPayloadSSLPayloadNTPPayloadSSDPPayloadWSDPPayloadNBNSPayloadPlexPayloadUbiquitiPayloadLLMNRPayloadIGMPPayloadEthernetPausePayloadRRCPPayloadLLDPPayload802_11rPayloadIEEE1905PayloadSonosPayload880aPayloadSTPPayloadCDP"
 
-var _PayloadID_index = [...]uint16{0, 12, 23, 33, 43, 53, 65, 77, 87, 97, 109, 121, 131, 142, 152, 162, 173, 184, 195, 206, 221, 233, 244, 264, 275, 286, 300, 315, 327, 338}
+var _PayloadID_index = [...]uint16{0, 12, 23, 33, 43, 53, 65, 77, 87, 97, 109, 121, 131, 142, 152, 162, 173, 184, 195, 206, 221, 233, 244, 264, 275, 286, 300, 315, 327, 338, 348, 358}
 
 func (i PayloadID) String() string {
 	i -= 1
@@ -0,0 +1,239 @@
+package packet
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// PingOptions configures a ping.
+type PingOptions struct {
+	Count    int           // number of echo requests; default 1
+	Interval time.Duration // time between echo requests; default 1 second
+	Timeout  time.Duration // wait time for each reply; default 2 seconds
+	Size     int           // payload size in bytes; default 15
+	Src      Addr          // source address; default to the host address for the dst ip version
+}
+
+// PingStats holds the result of a ping.
+type PingStats struct {
+	Sent     int
+	Received int
+	Loss     float64         // fraction of lost echo requests between 0 and 1
+	Min      time.Duration   // minimum round trip time
+	Avg      time.Duration   // average round trip time
+	Max      time.Duration   // maximum round trip time
+	Jitter   time.Duration   // mean difference between consecutive round trip times
+	RTT      []time.Duration // round trip time for each echo request; zero if lost
+}
+
+func (s PingStats) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Int("sent", s.Sent)
+	l.Int("recv", s.Received)
+	l.Sprintf("loss", s.Loss)
+	l.Duration("min", s.Min)
+	l.Duration("avg", s.Avg)
+	l.Duration("max", s.Max)
+	l.Duration("jitter", s.Jitter)
+	return l
+}
+
+// Pinger sends icmp echo requests and matches the replies received by the session.
+// Each session owns a Pinger so sessions do not share the echo id space.
+type Pinger struct {
+	session *Session
+	mutex   sync.Mutex
+	table   map[uint32]chan time.Time // key is echo id and seq
+}
+
+func newPinger(session *Session) *Pinger {
+	return &Pinger{session: session, table: make(map[uint32]chan time.Time)}
+}
+
+func echoKey(id uint16, seq uint16) uint32 { return uint32(id)<<16 | uint32(seq) }
+
+// nextEchoID returns a new icmp echo id. Pinger and Traceroute share the id space because
+// Parse passes every echo reply to both.
+func (h *Session) nextEchoID() uint16 { return uint16(atomic.AddUint32(&h.echoID, 1)) }
+
+// notify wakes up the ping waiting for the echo reply
+func (p *Pinger) notify(id uint16, seq uint16) {
+	now := time.Now()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if c, found := p.table[echoKey(id, seq)]; found {
+		c <- now // buffered channel
+		delete(p.table, echoKey(id, seq))
+	}
+}
+
+// Ping sends opts.Count echo requests to dst and returns the round trip statistics.
+// dst can be IPv4 or IPv6 and dst.MAC must be set.
+//
+// Ping returns ErrTimeout if no reply is received and the context error if ctx is done
+// before all requests are sent; the statistics include the requests sent so far.
+func (p *Pinger) Ping(ctx context.Context, dst Addr, opts PingOptions) (stats PingStats, err error) {
+	if opts.Count <= 0 {
+		opts.Count = 1
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second * 2
+	}
+	if opts.Size <= 0 {
+		opts.Size = len("HELLO-NETFILTER")
+	}
+	if !opts.Src.IP.IsValid() {
+		opts.Src = p.srcAddr(dst.IP)
+	}
+	if !dst.IP.IsValid() || opts.Src.IP.Is4() != dst.IP.Is4() {
+		return stats, ErrInvalidIP
+	}
+	data := make([]byte, opts.Size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	id := p.session.nextEchoID()
+
+	for seq := 1; seq <= opts.Count; seq++ {
+		start := time.Now()
+		rtt, err := p.probe(ctx, opts.Src, dst, id, uint16(seq), data, opts.Timeout)
+		if err != nil {
+			return stats.compute(), err
+		}
+		stats.Sent++
+		stats.RTT = append(stats.RTT, rtt)
+		if seq == opts.Count {
+			break
+		}
+		select {
+		case <-time.After(time.Until(start.Add(opts.Interval))):
+		case <-ctx.Done():
+			return stats.compute(), ctx.Err()
+		}
+	}
+	stats = stats.compute()
+	if Logger.IsDebug() {
+		Logger.Msg("ping").Struct(dst).Struct(stats).Write()
+	}
+	if stats.Received == 0 {
+		return stats, ErrTimeout
+	}
+	return stats, nil
+}
+
+// probe sends a single echo request and returns the round trip time or zero on timeout.
+func (p *Pinger) probe(ctx context.Context, src Addr, dst Addr, id uint16, seq uint16, data []byte, timeout time.Duration) (time.Duration, error) {
+	c := make(chan time.Time, 1)
+	p.mutex.Lock()
+	p.table[echoKey(id, seq)] = c
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		delete(p.table, echoKey(id, seq))
+		p.mutex.Unlock()
+	}()
+
+	start := time.Now()
+	if err := p.session.sendEchoRequest(src, dst, id, seq, data); err != nil {
+		return 0, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case t := <-c:
+		return t.Sub(start), nil
+	case <-timer.C:
+		return 0, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// srcAddr returns the host address for the ip version; GUA is used for global destinations.
+func (p *Pinger) srcAddr(dst netip.Addr) Addr {
+	nic := p.session.NICInfo()
+	switch {
+	case dst.Is4():
+		return nic.HostAddr4
+	case !dst.IsLinkLocalUnicast() && !dst.IsMulticast() && nic.HostGUA.Addr().IsValid():
+		return Addr{MAC: nic.HostAddr4.MAC, IP: nic.HostGUA.Addr()}
+	}
+	return Addr{MAC: nic.HostAddr4.MAC, IP: nic.HostLLA.Addr()}
+}
+
+func (s PingStats) compute() PingStats {
+	var total, diff time.Duration
+	var prev time.Duration
+	s.Received, s.Min, s.Max = 0, 0, 0
+	for _, v := range s.RTT {
+		if v == 0 {
+			continue
+		}
+		if s.Received == 0 || v < s.Min {
+			s.Min = v
+		}
+		if v > s.Max {
+			s.Max = v
+		}
+		if s.Received > 0 {
+			if v > prev {
+				diff = diff + v - prev
+			} else {
+				diff = diff + prev - v
+			}
+		}
+		prev = v
+		total = total + v
+		s.Received++
+	}
+	if s.Received > 0 {
+		s.Avg = total / time.Duration(s.Received)
+	}
+	if s.Received > 1 {
+		s.Jitter = diff / time.Duration(s.Received-1)
+	}
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) / float64(s.Sent)
+	}
+	return s
+}
+
+// sendEchoRequest transmits an icmp4 or icmp6 echo request with data as payload
+func (h *Session) sendEchoRequest(srcAddr Addr, dstAddr Addr, id uint16, seq uint16, data []byte) error {
+	p := make([]byte, 8+len(data))
+	if dstAddr.IP.Is4() {
+		EncodeICMPEcho(p, ICMP4TypeEchoRequest, 0, id, seq, data)
+		return h.icmp4SendPacket(srcAddr, dstAddr, p)
+	}
+	EncodeICMPEcho(p, ICMP6TypeEchoRequest, 0, id, seq, data)
+	return h.icmp6SendPacket(srcAddr, dstAddr, p)
+}
+
+// Ping6 send a ping request and wait for a reply
+func (h *Session) Ping6(srcAddr Addr, dstAddr Addr, timeout time.Duration) (err error) {
+	if !srcAddr.IP.Is6() || !dstAddr.IP.Is6() {
+		return ErrInvalidIP
+	}
+	return h.ping(srcAddr, dstAddr, timeout)
+}
+
+// Ping send a ping request and wait for a reply
+func (h *Session) Ping(dstAddr Addr, timeout time.Duration) (err error) {
+	return h.ping(h.NICInfo().HostAddr4, dstAddr, timeout)
+}
+
+func (h *Session) ping(srcAddr Addr, dstAddr Addr, timeout time.Duration) (err error) {
+	if timeout <= 0 || timeout > time.Second*10 {
+		timeout = time.Second * 2
+	}
+	_, err = h.Pinger.Ping(context.Background(), dstAddr, PingOptions{Timeout: timeout, Src: srcAddr})
+	return err
+}
@@ -0,0 +1,127 @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// echoResponder replies to icmp4 and icmp6 echo requests except for seq 2.
+func echoResponder(session *Session, conn net.PacketConn) {
+	buf := make([]byte, EthMaxSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		ether := Ether(buf[:n])
+		out := EncodeEther(make([]byte, EthMaxSize), ether.EtherType(), ether.Dst(), ether.Src())
+		switch ether.EtherType() {
+		case syscall.ETH_P_IP:
+			ip4 := IP4(ether.Payload())
+			echo := ICMPEcho(ip4.Payload())
+			if echo.EchoSeq() == 2 {
+				continue
+			}
+			reply := EncodeIP4(out.Payload(), 64, ip4.Dst(), ip4.Src())
+			e := EncodeICMPEcho(reply.Payload(), ICMP4TypeEchoReply, 0, echo.EchoID(), echo.EchoSeq(), echo.EchoData())
+			reply = reply.SetPayload(e, syscall.IPPROTO_ICMP)
+			out, _ = out.SetPayload(reply)
+		case syscall.ETH_P_IPV6:
+			ip6 := IP6(ether.Payload())
+			echo := ICMPEcho(ip6.Payload())
+			if echo.EchoSeq() == 2 {
+				continue
+			}
+			reply := EncodeIP6(out.Payload(), 64, ip6.Dst(), ip6.Src())
+			e := EncodeICMPEcho(make([]byte, 8+len(echo.EchoData())), ICMP6TypeEchoReply, 0, echo.EchoID(), echo.EchoSeq(), echo.EchoData())
+			reply, _ = reply.AppendPayload(e, syscall.IPPROTO_ICMPV6)
+			out, _ = out.SetPayload(reply)
+		default:
+			continue
+		}
+		session.Parse(out)
+	}
+}
+
+func TestPinger_Ping(t *testing.T) {
+	session, client := testSession()
+	updateTestNICInfo(session, func(info *NICInfo) { info.HostLLA = netip.PrefixFrom(netip.MustParseAddr("fe80::10"), 64) })
+	go echoResponder(session, client)
+
+	tests := []struct {
+		name string
+		dst  Addr
+	}{
+		{name: "ipv4", dst: Addr{MAC: mac1, IP: ip1}},
+		{name: "ipv6", dst: Addr{MAC: mac1, IP: netip.MustParseAddr("fe80::1")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := session.Pinger.Ping(context.Background(), tt.dst, PingOptions{Count: 4, Interval: time.Millisecond * 10, Timeout: time.Millisecond * 50, Size: 64})
+			if err != nil {
+				t.Fatal("unexpected error", err)
+			}
+			if stats.Sent != 4 || stats.Received != 3 || stats.Loss != 0.25 || len(stats.RTT) != 4 || stats.RTT[1] != 0 {
+				t.Errorf("invalid stats %+v", stats)
+			}
+			if stats.Min <= 0 || stats.Min > stats.Avg || stats.Avg > stats.Max {
+				t.Errorf("invalid rtt %+v", stats)
+			}
+		})
+	}
+
+	// mismatched ip version
+	if _, err := session.Pinger.Ping(context.Background(), Addr{MAC: mac1, IP: ip1}, PingOptions{Src: Addr{IP: netip.MustParseAddr("fe80::10")}}); err != ErrInvalidIP {
+		t.Errorf("expected invalid ip error got %v", err)
+	}
+}
+
+func TestPinger_PingCancel(t *testing.T) {
+	session, client := testSession()
+	go TestReadAndDiscardLoop(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	stats, err := session.Pinger.Ping(ctx, Addr{MAC: mac1, IP: ip1}, PingOptions{Count: 10, Interval: time.Millisecond * 10, Timeout: time.Millisecond * 5})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error got %v", err)
+	}
+	if stats.Sent == 0 || stats.Sent == 10 || stats.Received != 0 || stats.Loss != 1 {
+		t.Errorf("invalid stats %+v", stats)
+	}
+}
+
+func TestPingStats_compute(t *testing.T) {
+	ms := time.Millisecond
+	s := PingStats{Sent: 5, RTT: []time.Duration{10 * ms, 0, 20 * ms, 30 * ms, 10 * ms}}.compute()
+	if s.Received != 4 || s.Min != 10*ms || s.Max != 30*ms || s.Avg != 17500*time.Microsecond || s.Loss != 0.2 {
+		t.Errorf("invalid stats %+v", s)
+	}
+	// jitter = (10 + 10 + 20) / 3
+	if s.Jitter != 40*ms/3 {
+		t.Errorf("invalid jitter %v", s.Jitter)
+	}
+}
+
+func TestSession_nextEchoID(t *testing.T) {
+	session, _ := testSession()
+	defer session.Close()
+
+	ids := make(chan uint16, 100)
+	for i := 0; i < cap(ids); i++ {
+		go func() { ids <- session.nextEchoID() }()
+	}
+	seen := make(map[uint16]bool)
+	for i := 0; i < cap(ids); i++ {
+		id := <-ids
+		if seen[id] {
+			t.Fatalf("duplicated echo id %d", id)
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,233 @@
+package packet
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// RAGuardPolicy lists the routers and prefixes allowed in ipv6 router advertisements.
+//
+// A router is allowed if its mac or link local address is in the policy. When both
+// lists are empty the router in NICInfo is allowed. Any prefix is allowed if Prefixes is empty.
+type RAGuardPolicy struct {
+	RouterMACs []net.HardwareAddr
+	RouterLLAs []netip.Addr
+	Prefixes   []netip.Prefix
+}
+
+// RAGuardEvent is the Data of router advertisement events.
+type RAGuardEvent struct {
+	Router           Addr // router mac and link local address
+	Reason           string
+	Lifetime         time.Duration // router lifetime; zero means the router is not a default router
+	Prefixes         []netip.Prefix
+	RDNSS            []netip.Addr
+	PreviousPrefixes []netip.Prefix // prefixes in the previous advertisement for option changes
+	PreviousRDNSS    []netip.Addr   // rdnss in the previous advertisement for option changes
+}
+
+func (e RAGuardEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Struct(e.Router)
+	l.String("reason", e.Reason)
+	l.Duration("lifetime", e.Lifetime)
+	for _, v := range e.Prefixes {
+		l.String("prefix", v.String())
+	}
+	for _, v := range e.RDNSS {
+		l.IP("rdnss", v)
+	}
+	for _, v := range e.PreviousPrefixes {
+		l.String("previousPrefix", v.String())
+	}
+	for _, v := range e.PreviousRDNSS {
+		l.IP("previousRDNSS", v)
+	}
+	return l
+}
+
+// maxRARouters limits the number of routers tracked for option and lifetime changes;
+// forged advertisements from random addresses must not grow the table without limit.
+const maxRARouters = 256
+
+type raRouter struct {
+	prefixes        []netip.Prefix
+	rdnss           []netip.Addr
+	lifetimeZero    bool
+	lifetimeChanges []time.Time
+	lastSeen        time.Time
+}
+
+// raGuardEventKey is the event type, reason and router ip. EventRAOptionsChanged is sent for
+// two reasons that are rate limited independently.
+type raGuardEventKey struct {
+	event  EventType
+	reason string
+	ip     netip.Addr
+}
+
+type raGuardState struct {
+	sync.Mutex
+	routers map[netip.Addr]*raRouter
+	events  eventLimiter[raGuardEventKey]
+}
+
+// IsAllowedRouter returns true if the router mac or link local address is allowed by Session.RAGuard.
+func (h *Session) IsAllowedRouter(mac net.HardwareAddr, ip netip.Addr) bool {
+	macs, llas := h.RAGuard.RouterMACs, h.RAGuard.RouterLLAs
+	if len(macs) == 0 && len(llas) == 0 {
+		if len(h.NICInfo().RouterAddr4.MAC) == 0 && !h.NICInfo().RouterLLA.IsValid() {
+			return true // nothing to compare against
+		}
+		macs = []net.HardwareAddr{h.NICInfo().RouterAddr4.MAC}
+		llas = []netip.Addr{h.NICInfo().RouterLLA.Addr()}
+	}
+	for _, v := range macs {
+		if bytes.Equal(v, mac) {
+			return true
+		}
+	}
+	for _, v := range llas {
+		if v == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedPrefix returns true if prefix is within one of the policy prefixes.
+func (h *Session) isAllowedPrefix(prefix netip.Prefix) bool {
+	if len(h.RAGuard.Prefixes) == 0 {
+		return true
+	}
+	for _, v := range h.RAGuard.Prefixes {
+		if v.Bits() <= prefix.Bits() && v.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// processRAGuard checks a router advertisement against Session.RAGuard.
+//
+// It sends an EventRogueRouterAdvertisement for routers not in the policy, an EventRAOptionsChanged
+// for prefixes not in the policy or when a router changes its prefixes or rdnss, and an
+// EventRALifetimeFlapping when the router lifetime alternates between zero and non zero
+// DefaultFlapCount times within DefaultFlapWindow. Routers may split options across
+// advertisements so options are only compared when present. Events are sent at most once per
+// minute for each router and reason. Routers are tracked up to maxRARouters and deleted when not seen for
+// PurgeDeadline.
+func (h *Session) processRAGuard(srcAddr Addr, p ICMP6RouterAdvertisement, now time.Time) {
+	options, err := p.Options()
+	if err != nil {
+		return
+	}
+	router := Addr{MAC: srcAddr.MAC, IP: srcAddr.IP}
+	if len(options.SourceLLA.MAC) == EthAddrLen {
+		router.MAC = options.SourceLLA.MAC
+	}
+	var prefixes []netip.Prefix
+	for _, v := range options.Prefixes {
+		if ip, ok := netip.AddrFromSlice(v.Prefix); ok {
+			prefixes = append(prefixes, netip.PrefixFrom(ip.Unmap(), int(v.PrefixLength)))
+		}
+	}
+	var rdnss []netip.Addr
+	for _, v := range options.RDNSS.Servers {
+		if ip, ok := netip.AddrFromSlice(v); ok {
+			rdnss = append(rdnss, ip)
+		}
+	}
+	lifetime := time.Duration(p.Lifetime()) * time.Second
+	newEvent := func(t EventType, reason string) Event {
+		addr := Addr{MAC: CopyMAC(router.MAC), IP: router.IP}
+		return Event{Type: t, Time: now, Addr: addr, Data: RAGuardEvent{Router: addr, Reason: reason, Lifetime: lifetime, Prefixes: prefixes, RDNSS: rdnss}}
+	}
+	var events []Event
+
+	h.raGuard.Lock()
+	if h.raGuard.routers == nil {
+		h.raGuard.routers = make(map[netip.Addr]*raRouter)
+	}
+	allow := func(t EventType, reason string) bool {
+		return h.raGuard.events.allow(raGuardEventKey{event: t, reason: reason, ip: router.IP}, now)
+	}
+
+	if !h.IsAllowedRouter(router.MAC, router.IP) && allow(EventRogueRouterAdvertisement, "router") {
+		events = append(events, newEvent(EventRogueRouterAdvertisement, "router"))
+	}
+	for _, v := range prefixes {
+		if !h.isAllowedPrefix(v) && allow(EventRAOptionsChanged, "prefix") {
+			events = append(events, newEvent(EventRAOptionsChanged, "prefix"))
+			break
+		}
+	}
+
+	r := h.raGuard.routers[router.IP]
+	if r == nil {
+		r = &raRouter{prefixes: prefixes, rdnss: rdnss, lifetimeZero: lifetime == 0}
+		if len(h.raGuard.routers) < maxRARouters { // an untracked router is only compared with itself
+			h.raGuard.routers[router.IP] = r
+		}
+	}
+	r.lastSeen = now
+	changed := (len(prefixes) > 0 && !equalPrefixList(r.prefixes, prefixes)) || (len(rdnss) > 0 && !equalAddrList(r.rdnss, rdnss))
+	if changed && allow(EventRAOptionsChanged, "changed") {
+		e := newEvent(EventRAOptionsChanged, "changed")
+		data := e.Data.(RAGuardEvent)
+		data.PreviousPrefixes, data.PreviousRDNSS = r.prefixes, r.rdnss
+		e.Data = data
+		events = append(events, e)
+	}
+	if len(prefixes) > 0 {
+		r.prefixes = prefixes
+	}
+	if len(rdnss) > 0 {
+		r.rdnss = rdnss
+	}
+
+	if zero := lifetime == 0; zero != r.lifetimeZero {
+		r.lifetimeZero = zero
+		r.lifetimeChanges = append(r.lifetimeChanges, now)
+		for len(r.lifetimeChanges) > 0 && now.Sub(r.lifetimeChanges[0]) >= DefaultFlapWindow {
+			r.lifetimeChanges = r.lifetimeChanges[1:]
+		}
+		if len(r.lifetimeChanges) >= DefaultFlapCount && allow(EventRALifetimeFlapping, "lifetime") {
+			events = append(events, newEvent(EventRALifetimeFlapping, "lifetime"))
+			r.lifetimeChanges = nil
+		}
+	}
+	h.raGuard.Unlock()
+
+	for _, e := range events {
+		h.sendEvent(e)
+	}
+}
+
+// purgeRAGuard deletes routers not seen for PurgeDeadline and event times older than a minute.
+func (h *Session) purgeRAGuard(now time.Time) {
+	h.raGuard.Lock()
+	defer h.raGuard.Unlock()
+	for k, v := range h.raGuard.routers {
+		if now.Sub(v.lastSeen) >= h.PurgeDeadline {
+			delete(h.raGuard.routers, k)
+		}
+	}
+	h.raGuard.events.purge(now)
+}
+
+func equalPrefixList(a []netip.Prefix, b []netip.Prefix) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
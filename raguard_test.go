@@ -0,0 +1,135 @@
+package packet
+
+import (
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv6"
+)
+
+func newRAFrame(src Addr, lifetime time.Duration, prefix netip.Prefix, rdnss ...netip.Addr) Ether {
+	var options []Option
+	if prefix.IsValid() {
+		options = append(options, &PrefixInformation{PrefixLength: uint8(prefix.Bits()), OnLink: true, AutonomousAddressConfiguration: true,
+			ValidLifetime: 2 * time.Hour, PreferredLifetime: 30 * time.Minute, Prefix: prefix.Addr().AsSlice()})
+	}
+	if len(rdnss) > 0 {
+		dns := &RecursiveDNSServer{Lifetime: time.Minute * 10}
+		for _, v := range rdnss {
+			dns.Servers = append(dns.Servers, v.AsSlice())
+		}
+		options = append(options, dns)
+	}
+	options = append(options, &LinkLayerAddress{Direction: Source, MAC: src.MAC})
+	ra := &RouterAdvertisement{CurrentHopLimit: 64, RouterLifetime: lifetime, Options: options}
+	b, err := ra.marshal()
+	if err != nil {
+		panic(err)
+	}
+	ether := newEtherPacket(syscall.ETH_P_IPV6, src.MAC, EthernetBroadcast)
+	ip6 := EncodeIP6(ether.Payload(), 255, src.IP, IP6AllNodesMulticast)
+	ip6, _ = ip6.AppendPayload(append([]byte{byte(ipv6.ICMPTypeRouterAdvertisement), 0, 0, 0}, b...), syscall.IPPROTO_ICMPV6)
+	ether, _ = ether.SetPayload(ip6)
+	return ether
+}
+
+func TestSession_RAGuard(t *testing.T) {
+	session, _ := testSession()
+	routerLLA := netip.MustParseAddr("fe80::1")
+	rogueLLA := netip.MustParseAddr("fe80::2")
+	updateTestNICInfo(session, func(info *NICInfo) { info.RouterLLA = netip.PrefixFrom(routerLLA, 64) })
+	session.RAGuard.Prefixes = []netip.Prefix{netip.MustParsePrefix("2001:db8::/48")}
+	router := Addr{MAC: routerMAC, IP: routerLLA}
+	rogue := Addr{MAC: net.HardwareAddr{0x00, 0x02, 0x03, 0x04, 0x05, 0x09}, IP: rogueLLA}
+	prefix1 := netip.MustParsePrefix("2001:db8:0:1::/64")
+	prefix2 := netip.MustParsePrefix("2001:db8:0:2::/64")
+	dns1 := netip.MustParseAddr("2001:db8::53")
+	dns2 := netip.MustParseAddr("2606:4700:4700::1111")
+
+	tests := []struct {
+		name  string
+		frame Ether
+		want  []EventType
+	}{
+		{name: "router", frame: newRAFrame(router, 30*time.Minute, prefix1, dns1)},
+		{name: "router no options", frame: newRAFrame(router, 30*time.Minute, netip.Prefix{})},
+		{name: "rdnss changed", frame: newRAFrame(router, 30*time.Minute, prefix1, dns2), want: []EventType{EventRAOptionsChanged}},
+		{name: "our ra", frame: newRAFrame(Addr{MAC: hostMAC, IP: netip.MustParseAddr("fe80::3")}, 30*time.Minute, netip.MustParsePrefix("2001:db9::/64"))},
+		{name: "rogue", frame: newRAFrame(rogue, 30*time.Minute, prefix2), want: []EventType{EventRogueRouterAdvertisement}},
+		{name: "rogue rate limited", frame: newRAFrame(rogue, 30*time.Minute, prefix2)},
+		{name: "flap 1", frame: newRAFrame(router, 0, netip.Prefix{})},
+		{name: "flap 2", frame: newRAFrame(router, 30*time.Minute, netip.Prefix{})},
+		{name: "flap 3", frame: newRAFrame(router, 0, netip.Prefix{}), want: []EventType{EventRALifetimeFlapping}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := session.Parse(tt.frame); err != nil {
+				t.Fatal("unexpected parse error", err)
+			}
+			var events []Event
+			for len(session.Events) > 0 {
+				events = append(events, <-session.Events)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("invalid events want=%v got=%v", tt.want, events)
+			}
+			for i, e := range events {
+				if _, ok := e.Data.(RAGuardEvent); e.Type != tt.want[i] || !ok {
+					t.Errorf("invalid event want=%s got=%s", tt.want[i], e)
+				}
+			}
+		})
+	}
+
+	session.purgeRAGuard(time.Now().Add(time.Minute))
+	if n, m := session.raGuard.events.len(), len(session.raGuard.routers); n != 0 || m == 0 {
+		t.Errorf("entries not purged len=%d routers=%d", n, m)
+	}
+	session.purgeRAGuard(time.Now().Add(session.PurgeDeadline))
+	if n := len(session.raGuard.routers); n != 0 {
+		t.Errorf("routers not purged len=%d", n)
+	}
+}
+
+func TestSession_RAGuardMaxRouters(t *testing.T) {
+	session, _ := testSession()
+	for i := 0; i < maxRARouters+10; i++ {
+		src := Addr{MAC: mac1, IP: netip.AddrFrom16([16]byte{0xfe, 0x80, 14: byte(i >> 8), 15: byte(i)})}
+		session.Parse(newRAFrame(src, 30*time.Minute, netip.Prefix{}))
+	}
+	if n := len(session.raGuard.routers); n != maxRARouters {
+		t.Errorf("invalid routers len=%d", n)
+	}
+}
+
+func TestSession_RAGuardPrefix(t *testing.T) {
+	session, _ := testSession()
+	session.RAGuard.RouterMACs = []net.HardwareAddr{mac1}
+	session.RAGuard.Prefixes = []netip.Prefix{netip.MustParsePrefix("2001:db8::/48")}
+	src := Addr{MAC: mac1, IP: netip.MustParseAddr("fe80::1")}
+
+	session.Parse(newRAFrame(src, 30*time.Minute, netip.MustParsePrefix("fd00:bad::/64")))
+	if len(session.Events) != 1 {
+		t.Fatalf("expected one event got %d", len(session.Events))
+	}
+	e := <-session.Events
+	if data, ok := e.Data.(RAGuardEvent); e.Type != EventRAOptionsChanged || !ok || data.Reason != "prefix" {
+		t.Errorf("invalid event %s", e)
+	}
+
+	// the prefix event must not suppress the options changed event
+	session.Parse(newRAFrame(src, 30*time.Minute, netip.MustParsePrefix("fd00:bad:1::/64")))
+	if len(session.Events) != 1 {
+		t.Fatalf("expected one event got %d", len(session.Events))
+	}
+	e = <-session.Events
+	if data, ok := e.Data.(RAGuardEvent); e.Type != EventRAOptionsChanged || !ok || data.Reason != "changed" {
+		t.Errorf("invalid event %s", e)
+	}
+	if !session.IsAllowedRouter(mac1, netip.MustParseAddr("fe80::9")) || session.IsAllowedRouter(routerMAC, netip.MustParseAddr("fe80::1")) {
+		t.Error("invalid router policy")
+	}
+}
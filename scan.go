@@ -0,0 +1,220 @@
+package packet
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// ScanOptions configures a network scan.
+type ScanOptions struct {
+	IPv4      bool           // scan ipv4; both ipv4 and ipv6 are scanned if neither is set
+	IPv6      bool           // scan ipv6
+	Prefix4   netip.Prefix   // ipv4 subnet to sweep; default to the home lan
+	Prefixes6 []netip.Prefix // ipv6 prefixes to probe; default to the host LLA and GUA prefixes
+	Rate      int            // packets per second up to maxScanRate; default 100
+	Wait      time.Duration  // time to wait for late responses after the last request; default 1 second
+}
+
+// maxScan4Bits is the largest ipv4 subnet we are willing to sweep (/16)
+const maxScan4Bits = 16
+
+// maxScanRate is the highest request rate in packets per second
+const maxScanRate = 10000
+
+// scan holds the state of a running scan
+type scan struct {
+	targets map[netip.Addr]bool // true if the target responded
+	c       chan Addr
+}
+
+type scanState struct {
+	sync.Mutex
+	scans map[*scan]struct{}
+}
+
+// Scan sweeps the local network and streams each address that responds.
+//
+// IPv4 addresses in the subnet are probed with ARP requests. IPv6 cannot be swept, so for
+// each prefix Scan probes the interface identifiers it already knows about, from the
+// IPv6 addresses in the host table and the EUI-64 identifier of each known MAC, sending a
+// neighbour solicitation to the solicited-node multicast address of each candidate.
+//
+// Requests are sent at opts.Rate packets per second. The returned channel is closed when the
+// scan completes or ctx is done. Each address is sent at most once per scan.
+func (h *Session) Scan(ctx context.Context, opts ScanOptions) (<-chan Addr, error) {
+	if !opts.IPv4 && !opts.IPv6 {
+		opts.IPv4 = true
+		opts.IPv6 = h.NICInfo().HostLLA.Addr().Is6() // skip ipv6 if the host does not have ipv6
+	}
+	if opts.IPv6 && !h.NICInfo().HostLLA.Addr().Is6() {
+		return nil, ErrInvalidIP6LLA
+	}
+	if opts.Rate <= 0 {
+		opts.Rate = 100
+	}
+	if opts.Rate > maxScanRate {
+		return nil, ErrInvalidParam
+	}
+	if opts.Wait <= 0 {
+		opts.Wait = time.Second
+	}
+	if !opts.Prefix4.IsValid() {
+		opts.Prefix4 = h.NICInfo().HomeLAN4
+	}
+	if opts.Prefixes6 == nil {
+		for _, p := range []netip.Prefix{h.NICInfo().HostLLA, h.NICInfo().HostGUA} {
+			if p.IsValid() {
+				opts.Prefixes6 = append(opts.Prefixes6, p.Masked())
+			}
+		}
+	}
+
+	var targets []netip.Addr
+	if opts.IPv4 {
+		if !opts.Prefix4.Addr().Is4() || opts.Prefix4.Bits() < maxScan4Bits {
+			return nil, ErrInvalidParam
+		}
+		targets = append(targets, h.scanTargets4(opts.Prefix4)...)
+	}
+	if opts.IPv6 {
+		targets = append(targets, h.scanTargets6(opts.Prefixes6)...)
+	}
+
+	s := &scan{targets: make(map[netip.Addr]bool, len(targets)), c: make(chan Addr, len(targets))}
+	for _, ip := range targets {
+		s.targets[ip] = false
+	}
+	h.scan.Lock()
+	if h.scan.scans == nil {
+		h.scan.scans = make(map[*scan]struct{})
+	}
+	h.scan.scans[s] = struct{}{}
+	h.scan.Unlock()
+
+	go func() {
+		defer func() {
+			h.scan.Lock()
+			delete(h.scan.scans, s)
+			close(s.c)
+			h.scan.Unlock()
+		}()
+		if err := h.scanSend(ctx, targets, opts.Rate); err != nil {
+			if ctx.Err() == nil {
+				Logger.Msg("scan error").Error(err).Write()
+			}
+			return
+		}
+		select {
+		case <-time.After(opts.Wait):
+		case <-ctx.Done():
+		case <-h.ctx.Done():
+		}
+	}()
+	return s.c, nil
+}
+
+// scanTargets4 returns all ipv4 host addresses in the subnet except the router and our host.
+func (h *Session) scanTargets4(prefix netip.Prefix) (list []netip.Addr) {
+	prefix = prefix.Masked()
+	n := uint32(1)<<(32-prefix.Bits()) - 1 // exclude network and broadcast addresses
+	ip := prefix.Addr()
+	for i := uint32(1); i < n; i++ {
+		ip = ip.Next()
+		if ip == h.NICInfo().RouterAddr4.IP || ip == h.NICInfo().HostAddr4.IP {
+			continue
+		}
+		list = append(list, ip)
+	}
+	return list
+}
+
+// scanTargets6 returns the candidate addresses for each prefix using the interface
+// identifiers known to the session.
+func (h *Session) scanTargets6(prefixes []netip.Prefix) (list []netip.Addr) {
+	iids := make(map[[8]byte]bool)
+	h.mutex.RLock()
+	for _, host := range h.HostTable.Table {
+		if host.Addr.IP.Is6() {
+			b := host.Addr.IP.As16()
+			iids[*(*[8]byte)(b[8:])] = true
+		}
+	}
+	for _, entry := range h.MACTable.Table {
+		iids[eui64(entry.MAC)] = true
+	}
+	h.mutex.RUnlock()
+
+	seen := make(map[netip.Addr]bool)
+	for _, prefix := range prefixes {
+		if !prefix.Addr().Is6() || prefix.Bits() > 64 {
+			continue
+		}
+		p := prefix.Masked().Addr().As16()
+		for iid := range iids {
+			copy(p[8:], iid[:])
+			ip := netip.AddrFrom16(p)
+			if seen[ip] || ip == h.NICInfo().HostLLA.Addr() || ip == h.NICInfo().HostGUA.Addr() {
+				continue
+			}
+			seen[ip] = true
+			list = append(list, ip)
+		}
+	}
+	return list
+}
+
+// eui64 returns the modified EUI-64 interface identifier for the mac.
+// see https://www.rfc-editor.org/rfc/rfc4291#appendix-A
+func eui64(mac net.HardwareAddr) (iid [8]byte) {
+	if len(mac) != 6 {
+		return iid
+	}
+	iid = [8]byte{mac[0] ^ 0x02, mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+	return iid
+}
+
+// scanSend sends one request per target at the given rate.
+func (h *Session) scanSend(ctx context.Context, targets []netip.Addr, rate int) error {
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for _, ip := range targets {
+		var err error
+		if ip.Is4() {
+			err = h.arpRequest(EthernetBroadcast, h.NICInfo().HostAddr4, Addr{MAC: EthernetBroadcast, IP: ip})
+		} else {
+			srcAddr := Addr{MAC: h.NICInfo().HostAddr4.MAC, IP: h.NICInfo().HostLLA.Addr()}
+			err = h.ICMP6SendNeighbourSolicitation(srcAddr, IPv6SolicitedNode(ip), ip)
+		}
+		if err != nil {
+			if netErr, ok := err.(net.Error); !ok || !netErr.Temporary() {
+				return err
+			}
+			if Logger.IsDebug() {
+				Logger.Msg("scan write error is temporary - skip").IP("ip", ip).Error(err).Write()
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-h.ctx.Done():
+			return ErrHandlerClosed
+		}
+	}
+	return nil
+}
+
+// scanNotify streams addr to running scans that probed the address.
+func (h *Session) scanNotify(addr Addr) {
+	h.scan.Lock()
+	defer h.scan.Unlock()
+	for s := range h.scan.scans {
+		if responded, found := s.targets[addr.IP]; found && !responded {
+			s.targets[addr.IP] = true
+			s.c <- Addr{MAC: CopyMAC(addr.MAC), IP: addr.IP} // buffered channel with room for all targets
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package packet
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// scanResponder replies to arp requests and neighbour solicitations for the hosts in the table
+func scanResponder(session *Session, conn net.PacketConn, hosts map[netip.Addr]net.HardwareAddr) {
+	buf := make([]byte, EthMaxSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		ether := Ether(buf[:n])
+		switch ether.EtherType() {
+		case syscall.ETH_P_ARP:
+			arp := ARP(ether.Payload())
+			mac, found := hosts[arp.DstIP()]
+			if arp.IsValid() != nil || arp.Operation() != ARPOperationRequest || !found {
+				continue
+			}
+			out := EncodeEther(make([]byte, EthMaxSize), syscall.ETH_P_ARP, mac, arp.SrcMAC())
+			reply := EncodeARP(out.Payload(), ARPOperationReply, Addr{MAC: mac, IP: arp.DstIP()}, Addr{MAC: arp.SrcMAC(), IP: arp.SrcIP()})
+			out, _ = out.SetPayload(reply)
+			session.Parse(out)
+		case syscall.ETH_P_IPV6:
+			ip6 := IP6(ether.Payload())
+			ns := ICMP6NeighborSolicitation(ip6.Payload())
+			if ns.IsValid() != nil {
+				continue
+			}
+			mac, found := hosts[ns.TargetAddress()]
+			if !found {
+				continue
+			}
+			out := EncodeEther(make([]byte, EthMaxSize), syscall.ETH_P_IPV6, mac, ether.Src())
+			reply := EncodeIP6(out.Payload(), 255, ns.TargetAddress(), ip6.Src())
+			reply, _ = reply.AppendPayload(ICMP6NeighborAdvertisementMarshal(false, true, true, Addr{MAC: mac, IP: ns.TargetAddress()}), syscall.IPPROTO_ICMPV6)
+			out, _ = out.SetPayload(reply)
+			session.Parse(out)
+		}
+	}
+}
+
+func TestSession_Scan(t *testing.T) {
+	session, client := testSession()
+	updateTestNICInfo(session, func(info *NICInfo) { info.HostLLA = netip.PrefixFrom(netip.MustParseAddr("fe80::10"), 64) })
+	mac2 := net.HardwareAddr{0x00, 0x02, 0x03, 0x04, 0x05, 0x02}
+	lla2 := netip.AddrFrom16([16]byte{0xfe, 0x80, 8: 0x02, 0x02, 0x03, 0xff, 0xfe, 0x04, 0x05, 0x02}) // eui-64 for mac2
+	newTestHost(session, Addr{MAC: mac2, IP: netip.MustParseAddr("192.168.0.200")})                   // add mac2 to the mac table
+
+	hosts := map[netip.Addr]net.HardwareAddr{
+		netip.MustParseAddr("192.168.0.2"): mac1,
+		netip.MustParseAddr("192.168.0.5"): mac2,
+		lla2:                               mac2,
+	}
+	go scanResponder(session, client, hosts)
+
+	tests := []struct {
+		name string
+		opts ScanOptions
+		want int
+		err  error
+	}{
+		{name: "ipv4", opts: ScanOptions{IPv4: true, Prefix4: netip.MustParsePrefix("192.168.0.0/29")}, want: 2},
+		{name: "ipv6", opts: ScanOptions{IPv6: true}, want: 1},
+		{name: "all", opts: ScanOptions{Prefix4: netip.MustParsePrefix("192.168.0.0/29")}, want: 3},
+		{name: "subnet too big", opts: ScanOptions{IPv4: true, Prefix4: netip.MustParsePrefix("10.0.0.0/8")}, err: ErrInvalidParam},
+		{name: "rate too high", opts: ScanOptions{IPv4: true, Rate: maxScanRate + 1}, err: ErrInvalidParam},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.Rate == 0 {
+				tt.opts.Rate = 1000
+			}
+			tt.opts.Wait = time.Millisecond * 50
+			c, err := session.Scan(context.Background(), tt.opts)
+			if err != tt.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				return
+			}
+			var list []Addr
+			for addr := range c {
+				list = append(list, addr)
+			}
+			if len(list) != tt.want {
+				t.Fatalf("invalid scan result want=%d got=%v", tt.want, list)
+			}
+			for _, addr := range list {
+				if mac := hosts[addr.IP]; mac.String() != addr.MAC.String() {
+					t.Errorf("invalid mac for %s", addr)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_ScanCancel(t *testing.T) {
+	session, client := testSession()
+	go TestReadAndDiscardLoop(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	start := time.Now()
+	c, err := session.Scan(ctx, ScanOptions{IPv4: true, Rate: 10})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	for range c {
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("scan did not stop on cancel %v", d)
+	}
+}
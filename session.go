@@ -1,12 +1,13 @@
 package packet
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"net"
 	"net/netip"
-	"os"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -66,6 +67,7 @@ var (
 	ErrInvalidParam  = errors.New("invalid parameter")
 	ErrMulticastMAC  = errors.New("mac is multicast")
 	ErrHandlerClosed = errors.New("handler is closed")
+	ErrNICStopped    = errors.New("nic stopped receiving ip packets")
 )
 
 // CLoudFlare family
@@ -85,35 +87,68 @@ var (
 
 // Session holds the session context for a given network interface.
 type Session struct {
-	Conn            net.PacketConn    // the underlaying raw connection used for all read and write
-	NICInfo         *NICInfo          // keep interface information
-	ProbeDeadline   time.Duration     // send IP probe if no traffic received for this long
-	OfflineDeadline time.Duration     // mark Host offline if no traffic for this long
-	PurgeDeadline   time.Duration     // delete Host if no traffic for this long
-	HostTable       HostTable         // store MAC/IP list - one for each IP host
-	MACTable        MACTable          // store mac list
-	mutex           sync.RWMutex      // global session mutex
-	Statistics      []ProtoStats      // keep per protocol statistics
-	C               chan Notification // channel for online & offline notifications
-	closeChan       chan bool         // channel to end all go routines
-	closed          bool              // indicate the session is closed
-	ipHeartBeat     uint32            // ipHeartBeat is set to 1 when we receive an IP packet
+	Conn              net.PacketConn          // the underlaying raw connection used for all read and write
+	nicInfo           atomic.Pointer[NICInfo] // interface information; read with NICInfo()
+	ProbeDeadline     time.Duration           // send IP probe if no traffic received for this long
+	OfflineDeadline   time.Duration           // mark Host offline if no traffic for this long
+	PurgeDeadline     time.Duration           // delete Host if no traffic for this long
+	InventoryDeadline time.Duration           // delete mac entries without hosts if not seen for this long
+	TrustedDHCP4      []netip.Addr            // expected dhcp server ids; default to the router
+	RAGuard           RAGuardPolicy           // expected ipv6 routers and prefixes; default to the router
+	HostTable         HostTable               // store MAC/IP list - one for each IP host
+	MACTable          MACTable                // store mac list
+	LLDPTable         LLDPTable               // store lldp and cdp neighbours
+	MeshTable         MeshTable               // store ieee 1905 mesh devices
+	stp               stpState                // spanning tree root and topology change state
+	rrcp              rrcpState               // realtek loop detection signatures
+	icmpErrors        icmpErrorState          // rate limit icmp error events
+	trace             traceState              // traceroute probes waiting for a reply
+	scan              scanState               // running network scans
+	conflicts         conflictState           // recent address probes for conflict detection
+	spoof             spoofState              // gateway impersonation and mac flapping detection
+	dhcp4Servers      dhcp4ServerState        // dhcp servers seen on the lan
+	raGuard           raGuardState            // router advertisement options and lifetime changes
+	devices           deviceState             // devices linked by stable signals across mac changes
+	store             InventoryStore          // persistent mac inventory; nil if not configured
+	Pinger            *Pinger                 // icmp echo requests waiting for a reply
+	echoID            uint32                  // last icmp echo id used by Pinger and Traceroute; atomic access
+	mutex             sync.RWMutex            // global session mutex
+	Statistics        []ProtoStats            // keep per protocol statistics
+	C                 chan Notification       // Deprecated: use Subscribe; online & offline notifications; not filled after Subscribe
+	Events            chan Event              // Deprecated: use Subscribe; network events; not filled after Subscribe
+	bus               eventBus                // event subscriptions
+	dispatcher        dispatcher              // handlers registered for Run
+	workers           int                     // number of Run workers; zero to process frames in the read goroutine
+	ctx               context.Context         // cancelled when the session is closed
+	cancel            context.CancelFunc      // cancel ctx; called by Close
+	wg                sync.WaitGroup          // session goroutines; Close waits for them to end
+	closeOnce         sync.Once               // run Close once
+	closeMutex        sync.RWMutex            // protect closed, C and Events
+	closed            bool                    // indicate the session is closed
+	ipHeartBeat       uint32                  // ipHeartBeat is set to 1 when we receive an IP packet
 }
 
 // Config contains configurable parameters that overide package defaults
 type Config struct {
-	Conn            net.PacketConn // override underlying connection - useful for testing
-	NICInfo         *NICInfo       // override nic information - set to non nil to create a test Handler
-	ProbeDeadline   time.Duration  // override probe deadline
-	OfflineDeadline time.Duration  // override offline deadline
-	PurgeDeadline   time.Duration  // override purge deadline
+	Conn              net.PacketConn // override underlying connection - useful for testing
+	NICInfo           *NICInfo       // override nic information - set to non nil to create a test Handler
+	ProbeDeadline     time.Duration  // override probe deadline
+	OfflineDeadline   time.Duration  // override offline deadline
+	PurgeDeadline     time.Duration  // override purge deadline
+	InventoryDeadline time.Duration  // override inventory deadline
+	TrustedDHCP4      []netip.Addr   // dhcp servers not reported as rogue; default to the router
+	RAGuard           RAGuardPolicy  // routers and prefixes not reported as rogue; default to the router
+	Store             InventoryStore // load and save the mac inventory across restarts; nil to disable
+	StoreInterval     time.Duration  // interval between inventory snapshots; default to DefaultStoreInterval
+	Workers           int            // number of goroutines processing frames in Run; zero to process frames in the read goroutine
 }
 
 // Default dealines
 const (
-	DefaultProbeDeadline   = time.Minute * 2  // probe IP every two minutes
-	DefaultOfflineDeadline = time.Minute * 5  // set offline if not IP not seen for this long
-	DefaultPurgeDeadline   = time.Minute * 61 // purge from table if not seen for this long
+	DefaultProbeDeadline     = time.Minute * 2     // probe IP every two minutes
+	DefaultOfflineDeadline   = time.Minute * 5     // set offline if not IP not seen for this long
+	DefaultPurgeDeadline     = time.Minute * 61    // purge from table if not seen for this long
+	DefaultInventoryDeadline = time.Hour * 24 * 30 // purge mac entries without hosts if not seen for this long
 )
 
 // monitorNICFrequency sets the frequency to check the network card is working properly.
@@ -122,25 +157,39 @@ var monitorNICFrequency = time.Minute * 3
 
 // NewSession returns a session to read and write raw packets from the network interface card.
 func NewSession(nic string) (*Session, error) {
-	return Config{ProbeDeadline: DefaultProbeDeadline, OfflineDeadline: DefaultOfflineDeadline, PurgeDeadline: DefaultPurgeDeadline}.NewSession(nic)
+	return NewSessionContext(context.Background(), nic)
+}
+
+// NewSessionContext is like NewSession but the session is closed when ctx is cancelled.
+func NewSessionContext(ctx context.Context, nic string) (*Session, error) {
+	return Config{ProbeDeadline: DefaultProbeDeadline, OfflineDeadline: DefaultOfflineDeadline, PurgeDeadline: DefaultPurgeDeadline}.NewSessionContext(ctx, nic)
 }
 
 // NewSession accepts a configuration structure and returns a session to read and write raw packets from the network interface card.
 func (config Config) NewSession(nic string) (session *Session, err error) {
+	return config.NewSessionContext(context.Background(), nic)
+}
+
+// NewSessionContext is like Config.NewSession but the session is closed when ctx is cancelled.
+func (config Config) NewSessionContext(ctx context.Context, nic string) (session *Session, err error) {
 	session = new(Session)
 	session.MACTable = newMACTable()
 	session.HostTable = newHostTable()
+	session.LLDPTable = newLLDPTable()
+	session.MeshTable = newMeshTable()
+	session.Pinger = newPinger(session)
 	session.C = make(chan Notification, 128) // plenty of capacity to prevent blocking
-	session.closeChan = make(chan bool)
+	session.Events = make(chan Event, 128)
 
-	if session.NICInfo = config.NICInfo; session.NICInfo == nil {
-		session.NICInfo, err = GetNICInfo(nic)
-		if err != nil {
+	info := config.NICInfo
+	if info == nil {
+		if info, err = GetNICInfo(nic); err != nil {
 			return nil, fmt.Errorf("failed to setup nic=%s: %w", nic, err)
 		}
 	}
+	session.nicInfo.Store(info)
 	if session.Conn = config.Conn; session.Conn == nil {
-		session.Conn, err = NewServerConn(session.NICInfo.IFI, syscall.ETH_P_ALL, SocketConfig{Filter: nil, Promiscuous: true})
+		session.Conn, err = NewServerConn(session.NICInfo().IFI, syscall.ETH_P_ALL, SocketConfig{Filter: nil, Promiscuous: true})
 		if err != nil {
 			return nil, fmt.Errorf("failed to open raw connection: %w", err)
 		}
@@ -168,82 +217,177 @@ func (config Config) NewSession(nic string) (session *Session, err error) {
 	if session.PurgeDeadline = config.PurgeDeadline; session.PurgeDeadline <= 0 || session.PurgeDeadline > time.Hour*24 {
 		return nil, fmt.Errorf("invalid PurgeDeadline=%v: %w", session.PurgeDeadline, ErrInvalidParam)
 	}
+	if session.InventoryDeadline = config.InventoryDeadline; session.InventoryDeadline == 0 {
+		session.InventoryDeadline = DefaultInventoryDeadline
+	}
+	if session.InventoryDeadline < session.PurgeDeadline {
+		return nil, fmt.Errorf("invalid InventoryDeadline=%v: %w", session.InventoryDeadline, ErrInvalidParam)
+	}
+	session.TrustedDHCP4 = config.TrustedDHCP4
+	session.RAGuard = config.RAGuard
+	if session.workers = config.Workers; session.workers < 0 {
+		return nil, fmt.Errorf("invalid Workers=%d: %w", config.Workers, ErrInvalidParam)
+	}
+	var inventory []InventoryEntry
+	if session.store = config.Store; session.store != nil {
+		if inventory, err = session.store.Load(); err != nil {
+			return nil, fmt.Errorf("failed to load inventory: %w", err)
+		}
+	}
+
+	session.ctx, session.cancel = context.WithCancel(ctx)
 
 	// Setup a goroutine to monitor the nic to ensure we receive IP packets frequently.
-	// If the nic stops receiving IP packets, it is likely the switch port is disabled
-	// and our best option is to stop and likely restart.
-	go func(h *Session) {
-		ticker := time.NewTicker(monitorNICFrequency)
-		for {
-			select {
-			case <-ticker.C:
-				if atomic.LoadUint32(&h.ipHeartBeat) == 0 {
-					Logger.Msg("fatal failure to receive ip packets").Duration("duration", monitorNICFrequency).Time("time", time.Now()).Write()
-					// Send sigterm to terminate process
-					syscall.Kill(os.Getpid(), syscall.SIGTERM)
-				}
-				atomic.StoreUint32(&h.ipHeartBeat, 0)
-			case <-session.closeChan:
-				if Logger.IsDebug() {
-					Logger.Msg("nic monitoring goroutine ended").Write()
-				}
-				return
-			}
-		}
-	}(session)
+	// If the nic stops receiving IP packets, it is likely the switch port is disabled;
+	// send an event and let the application decide whether to stop or to wait.
+	session.wg.Add(1)
+	go func(h *Session, frequency time.Duration) {
+		defer h.wg.Done()
+		h.monitorNIC(frequency)
+	}(session, monitorNICFrequency)
 
 	// Start a minute loop goroutine to check for offline transition
+	session.wg.Add(1)
 	go func(h *Session) {
+		defer h.wg.Done()
 		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
 				if Logger.IsDebug() {
 					Logger.Msg("minute check").Write()
 				}
-				go h.purge(time.Now())
+				h.purge(time.Now())
 
-			case <-h.closeChan:
+			case <-h.ctx.Done():
 				Logger.Msg("session minute loop goroutine ended").Write()
 				return
 			}
 		}
 	}(session)
 
-	// create our own Host entry manually because we don't create for host packets
-	host, _ := session.findOrCreateHostWithLock(session.NICInfo.HostAddr4)
-	host.LastSeen = time.Now().Add(time.Hour * 24 * 365) // never expire
-	host.MACEntry.LastSeen = host.LastSeen
-	host.MACEntry.IP4 = host.Addr.IP
-	host.MACEntry.IP6LLA = session.NICInfo.HostLLA.Addr()
-	host.Online = true
-	host.MACEntry.Online = true
+	session.setHostEntry(*session.NICInfo())
 
 	// create the router entry manually and set router flag
-	host, _ = session.findOrCreateHostWithLock(session.NICInfo.RouterAddr4)
+	host, _ := session.findOrCreateHostWithLock(session.NICInfo().RouterAddr4)
 	host.MACEntry.IsRouter = true
 	host.MACEntry.IP4 = host.Addr.IP
 	host.Online = true
 	host.MACEntry.Online = true
 
+	if session.store != nil {
+		session.restoreInventory(inventory)
+		if config.StoreInterval <= 0 {
+			config.StoreInterval = DefaultStoreInterval
+		}
+		session.wg.Add(1)
+		go func(h *Session) {
+			defer h.wg.Done()
+			ticker := time.NewTicker(config.StoreInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					h.saveInventory()
+				case <-h.ctx.Done():
+					return
+				}
+			}
+		}(session)
+	}
+
+	// update NICInfo when the nic addresses, routes or router mac change; skip if the caller set NICInfo
+	if config.NICInfo == nil {
+		if err := session.monitorNetlink(); err != nil {
+			Logger.Msg("failed to subscribe to netlink updates").Error(err).Write()
+		}
+	}
+
+	// close the session when the parent context is cancelled
+	go func(h *Session) {
+		<-h.ctx.Done()
+		h.Close()
+	}(session)
+
 	return session, nil
 }
 
-// Close stop all session goroutines and close notification channel and the underlaying raw connection.
+// monitorNIC sends EventNICStopped if no ip packets are received for frequency and
+// EventNICRecovered when ip packets arrive again.
+func (h *Session) monitorNIC(frequency time.Duration) {
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
+	stopped := false
+	for {
+		select {
+		case <-ticker.C:
+			switch heartBeat := atomic.SwapUint32(&h.ipHeartBeat, 0) == 1; {
+			case !heartBeat && !stopped:
+				stopped = true
+				Logger.Msg("failure to receive ip packets").Duration("duration", frequency).Time("time", time.Now()).Write()
+				h.sendEvent(Event{Type: EventNICStopped, Time: time.Now(), Addr: h.NICInfo().HostAddr4,
+					Data: NICHealthEvent{Duration: frequency, Err: ErrNICStopped}})
+			case heartBeat && stopped:
+				stopped = false
+				h.sendEvent(Event{Type: EventNICRecovered, Time: time.Now(), Addr: h.NICInfo().HostAddr4,
+					Data: NICHealthEvent{Duration: frequency}})
+			}
+		case <-h.ctx.Done():
+			if Logger.IsDebug() {
+				Logger.Msg("nic monitoring goroutine ended").Write()
+			}
+			return
+		}
+	}
+}
+
+// NICHealthEvent is the Data of EventNICStopped and EventNICRecovered events.
+type NICHealthEvent struct {
+	Duration time.Duration // interval without ip packets that triggers EventNICStopped
+	Err      error         // ErrNICStopped if the nic is not receiving ip packets; nil otherwise
+}
+
+func (e NICHealthEvent) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Duration("duration", e.Duration)
+	if e.Err != nil {
+		l.Error(e.Err)
+	}
+	return l
+}
+
+// Close stops all session goroutines, waits for them to end and closes the notification channels
+// and the underlaying raw connection. Close is safe to call more than once and from many goroutines;
+// every call returns after the session is closed.
 // The session is no longer valid after calling Close().
 func (h *Session) Close() {
-	if h.closed {
-		return
-	}
-	h.closed = true
-	close(h.closeChan)
-	close(h.C)
-	h.Conn.Close()
-	time.Sleep(time.Second) // give time for goroutines to end
+	h.closeOnce.Do(func() {
+		h.cancel()
+		h.wg.Wait()
+		h.saveInventory()
+		h.closeMutex.Lock()
+		h.closed = true
+		close(h.C)
+		close(h.Events)
+		h.closeMutex.Unlock()
+		h.bus.close()
+		h.Conn.Close()
+	})
+}
+
+// NICInfo returns the current nic information. The returned value must not be modified;
+// SetNICInfo and SetRouter replace it with a new copy when the nic changes.
+func (h *Session) NICInfo() *NICInfo {
+	return h.nicInfo.Load()
+}
+
+// Done returns a channel that is closed when the session is closing.
+func (h *Session) Done() <-chan struct{} {
+	return h.ctx.Done()
 }
 
 func (h *Session) EnableIP4Forwarding() error {
-	return EnableIP4Forwarding(h.NICInfo.IFI.Name)
+	return EnableIP4Forwarding(h.NICInfo().IFI.Name)
 }
 
 // PrintTable logs the table to standard out.
@@ -269,7 +413,7 @@ func (h *Session) ReadFrom(b []byte) (int, net.Addr, error) {
 			}
 			continue
 		}
-		if h.closed {
+		if h.ctx.Err() != nil {
 			return n, addr, ErrHandlerClosed
 		}
 		return n, addr, err
@@ -309,25 +453,32 @@ func (h *Session) purge(now time.Time) error {
 			offline = append(offline, e)
 		}
 		e.MACEntry.Row.RUnlock()
+
+		// Remove multicast groups not reported recently
+		e.MACEntry.Row.Lock()
+		e.expireMulticastGroups(now)
+		e.MACEntry.Row.Unlock()
 	}
 
 	// run probe addr in goroutine as it may take time to return
 	if len(probe) > 0 {
+		h.wg.Add(1)
 		go func() {
+			defer h.wg.Done()
 			for _, addr := range probe {
 				if addr.IP.Is4() {
 					if Logger.IsDebug() {
 						Logger.Msg("send arp request - who is").IP("ip", addr.IP).Write()
 					}
-					if err := h.arpRequest(EthernetBroadcast, h.NICInfo.HostAddr4, Addr{MAC: EthernetBroadcast, IP: addr.IP}); err != nil {
+					if err := h.arpRequest(EthernetBroadcast, h.NICInfo().HostAddr4, Addr{MAC: EthernetBroadcast, IP: addr.IP}); err != nil {
 						Logger.Msg("failed to probe ipv4").IP("ip", addr.IP).Error(err).Write()
 					}
 				} else {
-					if !h.NICInfo.HostLLA.Addr().Is6() { // in case host does not have IPv6 - this should never happen
-						Logger.Msg("failed to probe ipv6 missing host ipv6").IP("ip", h.NICInfo.HostLLA.Addr()).Write()
+					if !h.NICInfo().HostLLA.Addr().Is6() { // in case host does not have IPv6 - this should never happen
+						Logger.Msg("failed to probe ipv6 missing host ipv6").IP("ip", h.NICInfo().HostLLA.Addr()).Write()
 						continue
 					}
-					srcAddr := Addr{MAC: h.NICInfo.HostAddr4.MAC, IP: h.NICInfo.HostLLA.Addr()}
+					srcAddr := Addr{MAC: h.NICInfo().HostAddr4.MAC, IP: h.NICInfo().HostLLA.Addr()}
 					if addr.IP.IsLinkLocalUnicast() {
 						// Use Neigbour solicitation if link local address as NS almost always result in a response from host if online unless
 						// host is on battery saving mode.
@@ -348,6 +499,17 @@ func (h *Session) purge(now time.Time) error {
 		h.makeOffline(host) // will lock/unlock row
 	}
 
+	h.purgeLLDP(now)
+	h.purgeRRCP(now)
+	h.purgeICMPErrors(now)
+	h.purgeConflicts(now)
+	h.purgeMesh(now)
+	h.purgeImpersonation(now)
+	h.purgeRAGuard(now)
+	h.purgeDHCP4Servers(now)
+	h.processDeviceNames(now)
+	h.purgeDevices(now)
+
 	// delete after loop because this will change the table
 	if len(purge) > 0 {
 		h.mutex.Lock()
@@ -356,6 +518,7 @@ func (h *Session) purge(now time.Time) error {
 		}
 		h.mutex.Unlock()
 	}
+	h.purgeMACEntries(now.Add(h.InventoryDeadline * -1))
 	return nil
 }
 
@@ -363,14 +526,14 @@ func (h *Session) purge(now time.Time) error {
 func (h *Session) arpRequest(dst net.HardwareAddr, sender Addr, target Addr) (err error) {
 	b := EtherBufferPool.Get().(*[EthMaxSize]byte)
 	defer EtherBufferPool.Put(b)
-	ether := Ether(b[0 : EthHeaderLen+28])                                      // arp length - 28 bytes
-	ether = EncodeEther(ether, syscall.ETH_P_ARP, h.NICInfo.HostAddr4.MAC, dst) // ether src set to host but arp packet set to target
+	ether := Ether(b[0 : EthHeaderLen+28])                                        // arp length - 28 bytes
+	ether = EncodeEther(ether, syscall.ETH_P_ARP, h.NICInfo().HostAddr4.MAC, dst) // ether src set to host but arp packet set to target
 
 	arp := ether.Payload()
 	binary.BigEndian.PutUint16(arp[0:2], 1)                // Hardware Type - Ethernet is 1
 	binary.BigEndian.PutUint16(arp[2:4], syscall.ETH_P_IP) // Protocol type - IPv4 0x0800
-	b[4] = 6                                               // mac len - fixed
-	b[5] = 4                                               // ipv4 len - fixed
+	arp[4] = 6                                             // mac len - fixed
+	arp[5] = 4                                             // ipv4 len - fixed
 	binary.BigEndian.PutUint16(arp[6:8], 0x01)             // operation - 1 request, 2 reply
 	copy(arp[8:8+6], sender.MAC[:6])
 	copy(arp[14:14+4], sender.IP.AsSlice())
@@ -438,6 +601,11 @@ func (h *Session) notify(frame Frame) {
 	frame.Host.MACEntry.Row.Unlock()
 
 	h.sendNotification(notification)
+	if frame.onlineTransition() {
+		h.publishHost(EventHostOnline, notification.Addr, notification)
+	} else {
+		h.publishHost(EventNameChanged, notification.Addr, notification)
+	}
 }
 
 func (h *Session) makeOffline(host *Host) {
@@ -464,6 +632,7 @@ func (h *Session) makeOffline(host *Host) {
 	if len(h.C) < cap(h.C) {
 		h.sendNotification(notification)
 	}
+	h.publishHost(EventHostOffline, notification.Addr, notification)
 }
 
 // DHCPv4Update updates the mac and host entry with dhcp details.
@@ -544,6 +713,7 @@ func (h *Session) Capture(mac net.HardwareAddr) error {
 		Logger.Msg("captured").MAC("mac", mac).Write()
 	}
 	macEntry.Captured = true
+	h.publishHost(EventCaptured, Addr{MAC: macEntry.MAC}, CaptureEvent{MAC: macEntry.MAC, Captured: true})
 	return nil
 }
 
@@ -553,14 +723,39 @@ func (h *Session) Release(mac net.HardwareAddr) error {
 	defer h.mutex.Unlock()
 	macEntry, _ := h.MACTable.findMAC(mac)
 	if macEntry != nil {
+		captured := macEntry.Captured
 		macEntry.Captured = false
 		if Logger.IsInfo() {
 			Logger.Msg("release").MAC("mac", mac).Write()
 		}
+		if captured {
+			h.publishHost(EventReleased, Addr{MAC: macEntry.MAC}, CaptureEvent{MAC: macEntry.MAC, Captured: false})
+		}
 	}
 	return nil
 }
 
+// SetRouter changes the default ipv4 router and sends an EventRouterChanged to subscribers.
+// The previous router mac entry is no longer flagged as a router.
+func (h *Session) SetRouter(router Addr) {
+	h.mutex.Lock()
+	info := *h.NICInfo()
+	previous := info.RouterAddr4
+	if previous.IP == router.IP && bytes.Equal(previous.MAC, router.MAC) {
+		h.mutex.Unlock()
+		return
+	}
+	router = h.setRouter(previous, router)
+	info.RouterAddr4 = router
+	h.nicInfo.Store(&info)
+	h.mutex.Unlock()
+
+	if Logger.IsInfo() {
+		Logger.Msg("router changed").Struct(router).IP("previous", previous.IP).Write()
+	}
+	h.publishHost(EventRouterChanged, router, RouterChangeEvent{Router: router, Previous: previous})
+}
+
 // IPAddrs retun the array of hosts for the mac.
 func (h *Session) IPAddrs(mac net.HardwareAddr) []Addr {
 	h.mutex.RLock()
@@ -2,11 +2,13 @@ package packet
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
-	"os"
-	"os/signal"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -34,6 +36,14 @@ func testSession() (*Session, net.PacketConn) {
 	return session, outConn
 }
 
+// updateTestNICInfo replaces the session nic info with a copy changed by update.
+// It does not send events or update the host table.
+func updateTestNICInfo(session *Session, update func(info *NICInfo)) {
+	info := *session.NICInfo()
+	update(&info)
+	session.nicInfo.Store(&info)
+}
+
 func TestSession_Notify(t *testing.T) {
 	session, _ := testSession()
 	// first host
@@ -108,8 +118,7 @@ func TestSession_Notify(t *testing.T) {
 
 }
 
-func TestHandler_SignalNICStopped(t *testing.T) {
-	Logger.SetLevel(fastlog.LevelDebug)
+func TestSession_monitorNIC(t *testing.T) {
 	nicInfo := NICInfo{
 		RouterAddr4: Addr{MAC: routerMAC, IP: routerIP4},
 		HostAddr4:   Addr{MAC: hostMAC, IP: hostIP4},
@@ -117,25 +126,89 @@ func TestHandler_SignalNICStopped(t *testing.T) {
 	}
 	inConn, _ := TestNewBufferedConn()
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c)
-
 	keep := monitorNICFrequency
 	defer func() { monitorNICFrequency = keep }()
-	monitorNICFrequency = time.Millisecond * 3
+	monitorNICFrequency = time.Millisecond * 10
 
 	session, err := Config{Conn: inConn, NICInfo: &nicInfo}.NewSession("")
 	if err != nil {
-		t.Fatal("engine did not stop as expected", err)
+		t.Fatal("failed to create session", err)
+	}
+	defer session.Close()
+	sub := session.Subscribe(0, EventTypes(EventNICStopped, EventNICRecovered))
+
+	select {
+	case e := <-sub.C:
+		if data, ok := e.Data.(NICHealthEvent); e.Type != EventNICStopped || !ok || !errors.Is(data.Err, ErrNICStopped) {
+			t.Errorf("invalid nic event %v", e)
+		}
+	case <-time.After(time.Millisecond * 200):
+		t.Fatal("nic stopped event not sent")
+	}
+
+	// keep the heartbeat alive until the recovered event arrives
+	for timeout := time.After(time.Millisecond * 200); ; {
+		atomic.StoreUint32(&session.ipHeartBeat, 1)
+		select {
+		case e := <-sub.C:
+			if e.Type != EventNICRecovered {
+				t.Errorf("invalid nic event %v", e)
+			}
+			return
+		case <-timeout:
+			t.Fatal("nic recovered event not sent")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestSession_Close(t *testing.T) {
+	session, _ := testSession()
+	sub := session.Subscribe(0, nil)
+
+	// concurrent calls must not panic and must all return after the session is closed
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session.Close()
+			select {
+			case <-session.Done():
+			default:
+				t.Error("close returned before the session is done")
+			}
+		}()
 	}
+	wg.Wait()
+	session.Close()
 
+	if _, ok := <-sub.C; ok {
+		t.Error("subscription not closed")
+	}
+	if _, ok := <-session.C; ok {
+		t.Error("notification channel not closed")
+	}
+	session.sendEvent(Event{Type: EventIPConflict}) // must not panic after close
+}
+
+func TestSession_context(t *testing.T) {
+	inConn, _ := TestNewBufferedConn()
+	ctx, cancel := context.WithCancel(context.Background())
+	session, err := Config{Conn: inConn, NICInfo: &NICInfo{HomeLAN4: homeLAN, HostAddr4: Addr{MAC: hostMAC, IP: hostIP4},
+		RouterAddr4: Addr{MAC: routerMAC, IP: routerIP4}}}.NewSessionContext(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := session.Subscribe(0, nil)
+	cancel()
 	select {
-	case <-time.After(time.Millisecond * 5):
-		t.Error("engine did not stop as expected")
-		fmt.Println(session.NICInfo)
-	case <-c:
-		fmt.Printf("got signal")
-		fmt.Println(session.NICInfo)
+	case _, ok := <-sub.C:
+		if ok {
+			t.Error("unexpected event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("session not closed when the context is cancelled")
 	}
 }
 
@@ -0,0 +1,220 @@
+package packet
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/deeGraYve/packet/fastlog"
+)
+
+// TracerouteOptions configures a traceroute.
+type TracerouteOptions struct {
+	MaxHops int           // maximum ttl; default 30
+	Probes  int           // number of probes per hop; default 3
+	Timeout time.Duration // wait time for each probe; default 2 seconds
+	UDP     bool          // send UDP probes to port 33434 and above instead of ICMP echo requests
+}
+
+// TracerouteHop holds the result for a single ttl. Addr is invalid if no probe
+// received a reply. RTT contains one entry per probe and zero for a lost probe.
+type TracerouteHop struct {
+	TTL     int
+	Addr    netip.Addr
+	RTT     []time.Duration
+	Reached bool // the reply came from the destination
+}
+
+func (e TracerouteHop) FastLog(l *fastlog.Line) *fastlog.Line {
+	l.Int("ttl", e.TTL)
+	l.IP("ip", e.Addr)
+	for _, v := range e.RTT {
+		l.Duration("rtt", v)
+	}
+	l.Bool("reached", e.Reached)
+	return l
+}
+
+// traceroute udp probes use the traditional base port
+const tracerouteUDPPort = 33434
+
+type traceKey struct {
+	proto uint8
+	id    uint16 // icmp echo id or udp source port
+	seq   uint16 // icmp echo seq or udp destination port
+}
+
+type traceReply struct {
+	from    netip.Addr
+	reached bool
+	time    time.Time
+}
+
+type traceState struct {
+	sync.Mutex
+	probes map[traceKey]chan traceReply
+	seq    uint16
+}
+
+// traceNotify wakes up the traceroute waiting on the probe key and returns true
+// if the frame was a reply to one of our probes.
+func (h *Session) traceNotify(key traceKey, from netip.Addr, reached bool) bool {
+	h.trace.Lock()
+	defer h.trace.Unlock()
+	c, found := h.trace.probes[key]
+	if !found {
+		return false
+	}
+	delete(h.trace.probes, key)
+	c <- traceReply{from: from, reached: reached, time: time.Now()} // buffered channel
+	return true
+}
+
+// traceNotifyICMP4Error matches the original datagram in a time exceeded or destination
+// unreachable message to a traceroute probe.
+func (h *Session) traceNotifyICMP4Error(from netip.Addr, p ICMP4Error) bool {
+	l4 := p.OriginalL4()
+	if len(l4) < 8 || p.Type() == ICMP4TypeParameterProblem {
+		return false
+	}
+	var key traceKey
+	switch p.OriginalIP().Protocol() {
+	case syscall.IPPROTO_ICMP:
+		if l4[0] != ICMP4TypeEchoRequest {
+			return false
+		}
+		key = traceKey{proto: syscall.IPPROTO_ICMP, id: binary.BigEndian.Uint16(l4[4:6]), seq: binary.BigEndian.Uint16(l4[6:8])}
+	case syscall.IPPROTO_UDP:
+		key = traceKey{proto: syscall.IPPROTO_UDP, id: binary.BigEndian.Uint16(l4[0:2]), seq: binary.BigEndian.Uint16(l4[2:4])}
+	default:
+		return false
+	}
+	return h.traceNotify(key, from, p.Type() == ICMP4TypeDestinationUnreachable)
+}
+
+// Traceroute sends TTL limited probes to dst and returns the list of hops.
+//
+// Probes are ICMP echo requests unless opts.UDP is set. Hops reply with ICMP time exceeded;
+// the destination replies with an echo reply or a port unreachable for UDP probes.
+// Traceroute returns when the destination is reached, after MaxHops or when ctx is done; in the
+// last case the hops found so far are returned with the context error.
+func (h *Session) Traceroute(ctx context.Context, dst netip.Addr, opts TracerouteOptions) (hops []TracerouteHop, err error) {
+	if !dst.Is4() {
+		return nil, ErrInvalidIP
+	}
+	if opts.MaxHops <= 0 || opts.MaxHops > 255 {
+		opts.MaxHops = 30
+	}
+	if opts.Probes <= 0 {
+		opts.Probes = 3
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second * 2
+	}
+
+	// off lan destinations are sent to the router
+	dstMAC := h.NICInfo().RouterAddr4.MAC
+	if h.NICInfo().HomeLAN4.Contains(dst) {
+		if host := h.FindIP(dst); host != nil {
+			dstMAC = host.MACEntry.MAC
+		}
+	}
+
+	h.trace.Lock()
+	if h.trace.probes == nil {
+		h.trace.probes = make(map[traceKey]chan traceReply)
+	}
+	h.trace.Unlock()
+	id := h.nextEchoID()
+
+	for ttl := 1; ttl <= opts.MaxHops; ttl++ {
+		hop := TracerouteHop{TTL: ttl, RTT: make([]time.Duration, opts.Probes)}
+		for i := 0; i < opts.Probes; i++ {
+			reply, rtt, err := h.traceProbe(ctx, dstMAC, dst, uint8(ttl), id, opts)
+			if err != nil {
+				hops = append(hops, hop)
+				return hops, err
+			}
+			if !reply.from.IsValid() {
+				continue
+			}
+			hop.Addr = reply.from
+			hop.RTT[i] = rtt
+			hop.Reached = hop.Reached || reply.reached || reply.from == dst
+		}
+		if Logger.IsDebug() {
+			Logger.Msg("traceroute hop").IP("dst", dst).Struct(hop).Write()
+		}
+		hops = append(hops, hop)
+		if hop.Reached {
+			break
+		}
+	}
+	return hops, nil
+}
+
+// traceProbe sends a single probe and waits for the reply. It returns an empty reply on timeout.
+func (h *Session) traceProbe(ctx context.Context, dstMAC net.HardwareAddr, dst netip.Addr, ttl uint8, id uint16, opts TracerouteOptions) (traceReply, time.Duration, error) {
+	h.trace.Lock()
+	h.trace.seq++
+	key := traceKey{proto: syscall.IPPROTO_ICMP, id: id, seq: h.trace.seq}
+	if opts.UDP {
+		key = traceKey{proto: syscall.IPPROTO_UDP, id: id | 0x8000, seq: tracerouteUDPPort + h.trace.seq%1024}
+	}
+	c := make(chan traceReply, 1)
+	h.trace.probes[key] = c
+	h.trace.Unlock()
+
+	defer func() {
+		h.trace.Lock()
+		delete(h.trace.probes, key)
+		h.trace.Unlock()
+	}()
+
+	start := time.Now()
+	if err := h.traceSend(dstMAC, dst, ttl, key); err != nil {
+		return traceReply{}, 0, err
+	}
+
+	timer := time.NewTimer(opts.Timeout)
+	defer timer.Stop()
+	select {
+	case reply := <-c:
+		return reply, reply.time.Sub(start), nil
+	case <-timer.C:
+		return traceReply{}, 0, nil
+	case <-ctx.Done():
+		return traceReply{}, 0, ctx.Err()
+	}
+}
+
+func (h *Session) traceSend(dstMAC net.HardwareAddr, dst netip.Addr, ttl uint8, key traceKey) (err error) {
+	buf := EtherBufferPool.Get().(*[EthMaxSize]byte)
+	defer EtherBufferPool.Put(buf)
+	ether := EncodeEther(buf[:], syscall.ETH_P_IP, h.NICInfo().HostAddr4.MAC, dstMAC)
+	ip4 := EncodeIP4(ether.Payload(), ttl, h.NICInfo().HostAddr4.IP, dst)
+	switch key.proto {
+	case syscall.IPPROTO_UDP:
+		udp := EncodeUDP(ip4.Payload(), key.id, key.seq)
+		if udp, err = udp.AppendPayload([]byte("TRACEROUTE")); err != nil {
+			return err
+		}
+		ip4 = ip4.SetPayload(udp, syscall.IPPROTO_UDP)
+	default:
+		p := EncodeICMPEcho(ip4.Payload(), ICMP4TypeEchoRequest, 0, key.id, key.seq, []byte("TRACEROUTE"))
+		ICMP(p).SetChecksum(Checksum(p))
+		ip4 = ip4.SetPayload(p, syscall.IPPROTO_ICMP)
+	}
+	if ether, err = ether.SetPayload(ip4); err != nil {
+		return err
+	}
+	if _, err := h.Conn.WriteTo(ether, &Addr{MAC: dstMAC, IP: dst}); err != nil {
+		return fmt.Errorf("traceroute write: %w", err)
+	}
+	return nil
+}
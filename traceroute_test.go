@@ -0,0 +1,93 @@
+package packet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// traceResponder replies to traceroute probes: hops 1 and 2 send time exceeded and
+// the destination replies at ttl 3.
+func traceResponder(session *Session, conn net.PacketConn, dst netip.Addr) {
+	buf := make([]byte, EthMaxSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		probe := IP4(Ether(buf[:n]).Payload())
+		out := EncodeEther(make([]byte, EthMaxSize), syscall.ETH_P_IP, routerMAC, hostMAC)
+		switch {
+		case probe.TTL() < 3:
+			router := netip.AddrFrom4([4]byte{10, 0, 0, byte(probe.TTL())})
+			icmp := append(make([]byte, 8), probe[:probe.IHL()+8]...)
+			icmp[0] = ICMP4TypeTimeExceeded
+			ip4 := EncodeIP4(out.Payload(), 64, router, hostIP4)
+			ip4, _ = ip4.AppendPayload(icmp, syscall.IPPROTO_ICMP)
+			out, _ = out.SetPayload(ip4)
+		case probe.Protocol() == syscall.IPPROTO_UDP:
+			icmp := append(make([]byte, 8), probe[:probe.IHL()+8]...)
+			icmp[0] = ICMP4TypeDestinationUnreachable
+			icmp[1] = ICMP4CodePortUnreachable
+			ip4 := EncodeIP4(out.Payload(), 64, dst, hostIP4)
+			ip4, _ = ip4.AppendPayload(icmp, syscall.IPPROTO_ICMP)
+			out, _ = out.SetPayload(ip4)
+		default:
+			echo := ICMPEcho(probe.Payload())
+			ip4 := EncodeIP4(out.Payload(), 64, dst, hostIP4)
+			reply := EncodeICMPEcho(ip4.Payload(), ICMP4TypeEchoReply, 0, echo.EchoID(), echo.EchoSeq(), echo.EchoData())
+			ip4 = ip4.SetPayload(reply, syscall.IPPROTO_ICMP)
+			out, _ = out.SetPayload(ip4)
+		}
+		session.Parse(out)
+	}
+}
+
+func TestSession_Traceroute(t *testing.T) {
+	session, client := testSession()
+	dst := netip.MustParseAddr("8.8.8.8")
+	go traceResponder(session, client, dst)
+
+	for _, udp := range []bool{false, true} {
+		hops, err := session.Traceroute(context.Background(), dst, TracerouteOptions{Probes: 2, Timeout: time.Millisecond * 200, UDP: udp})
+		if err != nil {
+			t.Fatalf("udp=%v unexpected error %v", udp, err)
+		}
+		if len(hops) != 3 {
+			t.Fatalf("udp=%v invalid hops %v", udp, hops)
+		}
+		for i, hop := range hops[:2] {
+			if hop.TTL != i+1 || hop.Addr != netip.AddrFrom4([4]byte{10, 0, 0, byte(i + 1)}) || hop.Reached || hop.RTT[0] == 0 || hop.RTT[1] == 0 {
+				t.Errorf("udp=%v invalid hop %+v", udp, hop)
+			}
+		}
+		if hops[2].Addr != dst || !hops[2].Reached {
+			t.Errorf("udp=%v invalid last hop %+v", udp, hops[2])
+		}
+	}
+	if len(session.Events) != 0 {
+		t.Errorf("unexpected icmp error event %s", <-session.Events)
+	}
+	if _, err := session.Traceroute(context.Background(), netip.MustParseAddr("2001:db8::1"), TracerouteOptions{}); err != ErrInvalidIP {
+		t.Errorf("expected invalid ip error got %v", err)
+	}
+}
+
+func TestSession_TracerouteCancel(t *testing.T) {
+	session, client := testSession()
+	go TestReadAndDiscardLoop(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	hops, err := session.Traceroute(ctx, netip.MustParseAddr("8.8.8.8"), TracerouteOptions{Timeout: time.Second})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error got %v", err)
+	}
+	if len(hops) != 1 || hops[0].Addr.IsValid() {
+		t.Errorf("invalid hops %+v", hops)
+	}
+}